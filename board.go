@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// defaultBoardInterval 是未配置 discord.board.interval 时的更新间隔
+const defaultBoardInterval = 5 * time.Minute
+
+// boardState 是保存在磁盘上的看板状态，重启后继续编辑同一条消息
+type boardState struct {
+	ChannelID string    `json:"channel_id"`
+	MessageID string    `json:"message_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LiveBoard 维护频道中的一条实时看板消息：第一次发送后记录消息 ID，之后每次编辑这条消息
+// 看板消息被删除（编辑返回 404）时重新发送一条
+type LiveBoard struct {
+	sender    *DiscordSender
+	channelID string
+	statePath string
+	pin       bool
+	now       func() time.Time
+}
+
+// NewLiveBoard 创建实时看板，sender 需要是发送到 channelID 的 Bot 发送器
+func NewLiveBoard(sender *DiscordSender, channelID, statePath string, pin bool) *LiveBoard {
+	return &LiveBoard{
+		sender:    sender,
+		channelID: channelID,
+		statePath: statePath,
+		pin:       pin,
+		now:       time.Now,
+	}
+}
+
+// Update 将看板更新为 embeds（一条消息的内容），返回看板消息的 ID
+func (b *LiveBoard) Update(embeds []DiscordEmbed) (string, error) {
+	state, err := b.loadState()
+	if err != nil {
+		log.Printf("读取看板状态失败，将重新创建看板: %v", err)
+	}
+
+	// 状态文件记录的是其他频道的看板时（修改过 channel_id），在新频道重新创建
+	if state.MessageID != "" && state.ChannelID == b.channelID {
+		err := b.sender.EditEmbeds(state.MessageID, embeds)
+		if err == nil {
+			state.UpdatedAt = b.now()
+			return state.MessageID, b.saveState(state)
+		}
+		if !isDiscordNotFound(err) {
+			return "", err
+		}
+		log.Printf("看板消息 %s 不存在（可能已被删除），将重新创建看板: %v", state.MessageID, err)
+	}
+
+	messageID, err := b.sender.PostEmbeds(embeds)
+	if err != nil {
+		return "", err
+	}
+	if messageID == "" {
+		return "", fmt.Errorf("Discord 没有返回看板消息的 ID")
+	}
+	if b.pin {
+		if err := b.sender.PinMessage(messageID); err != nil {
+			log.Printf("置顶看板消息失败: %v", err)
+		}
+	}
+	state = boardState{ChannelID: b.channelID, MessageID: messageID, UpdatedAt: b.now()}
+	return messageID, b.saveState(state)
+}
+
+// boardEmbeds 生成看板消息的内容，看板只有一条消息
+// 内容超过一条消息的长度限制时只显示能放下的前几个币种，并在页脚注明未显示的数量
+func boardEmbeds(gen *ReportGenerator, coins []CoinPrice) []DiscordEmbed {
+	messages := paginateEmbed(gen.GenerateDiscordEmbed(coins))
+	if len(messages) == 1 {
+		return messages[0]
+	}
+
+	truncated := func(n int) [][]DiscordEmbed {
+		embed := gen.GenerateDiscordEmbed(coins[:n])
+		note := gen.Locale().T("board.truncated", len(coins)-n)
+		if embed.Footer != nil && embed.Footer.Text != "" {
+			note = embed.Footer.Text + " | " + note
+		}
+		embed.Footer = &EmbedFooter{Text: note}
+		return paginateEmbed(embed)
+	}
+	// 找到第一个放不下的数量，前一个就是能显示的最多币种数
+	n := sort.Search(len(coins), func(n int) bool { return n > 0 && len(truncated(n)) > 1 }) - 1
+	if n < 1 {
+		n = 1
+	}
+	log.Printf("看板内容超过一条消息的长度限制，只显示前 %d 个币种（共 %d 个），可以减少币种或使用 compact 样式", n, len(coins))
+	return truncated(n)[0]
+}
+
+// loadState 读取看板状态，文件不存在时返回空状态
+func (b *LiveBoard) loadState() (boardState, error) {
+	var state boardState
+	data, err := os.ReadFile(b.statePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read board state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return boardState{}, fmt.Errorf("failed to parse board state: %w", err)
+	}
+	return state, nil
+}
+
+// saveState 保存看板状态
+func (b *LiveBoard) saveState(state boardState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(b.statePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write board state: %w", err)
+	}
+	return nil
+}
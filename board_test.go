@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDiscordChannel 模拟一个频道的消息接口，记录每个请求
+type fakeDiscordChannel struct {
+	mu       sync.Mutex
+	messages map[string]string // 消息 ID -> 最新的标题
+	pinned   []string
+	requests []string
+	nextID   int
+}
+
+func (c *fakeDiscordChannel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, r.Method+" "+r.URL.Path)
+
+	var body struct {
+		Embeds []DiscordEmbed `json:"embeds"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	title := ""
+	if len(body.Embeds) > 0 {
+		title = body.Embeds[0].Title
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 3:
+		c.nextID++
+		id := fmt.Sprintf("%d", 1000+c.nextID)
+		c.messages[id] = title
+		fmt.Fprintf(w, `{"id": %q}`, id)
+	case r.Method == http.MethodPatch && len(parts) == 4:
+		if _, ok := c.messages[parts[3]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Unknown Message", "code": 10008}`))
+			return
+		}
+		c.messages[parts[3]] = title
+		fmt.Fprintf(w, `{"id": %q}`, parts[3])
+	case r.Method == http.MethodPut && len(parts) == 4 && parts[2] == "pins":
+		c.pinned = append(c.pinned, parts[3])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// newTestLiveBoard 创建连接到 fakeDiscordChannel 的看板
+func newTestLiveBoard(t *testing.T, channel *fakeDiscordChannel, channelID, statePath string) *LiveBoard {
+	server := httptest.NewServer(channel)
+	t.Cleanup(server.Close)
+	sender := NewDiscordSender("test-token", channelID, false, "")
+	sender.apiBaseURL = server.URL
+	return NewLiveBoard(sender, channelID, statePath, true)
+}
+
+// TestLiveBoardUpdate 测试看板第一次创建并置顶，之后编辑同一条消息，重启后继续使用保存的消息 ID
+func TestLiveBoardUpdate(t *testing.T) {
+	channel := &fakeDiscordChannel{messages: map[string]string{}}
+	statePath := filepath.Join(t.TempDir(), "board.json")
+
+	board := newTestLiveBoard(t, channel, "123", statePath)
+	first, err := board.Update([]DiscordEmbed{{Title: "第一次"}})
+	if err != nil {
+		t.Fatalf("创建看板失败: %v", err)
+	}
+	if len(channel.pinned) != 1 || channel.pinned[0] != first {
+		t.Errorf("新建的看板消息应该被置顶，实际置顶 %v", channel.pinned)
+	}
+
+	// 模拟重启：新的 LiveBoard 从状态文件读取消息 ID
+	board = newTestLiveBoard(t, channel, "123", statePath)
+	second, err := board.Update([]DiscordEmbed{{Title: "第二次"}})
+	if err != nil {
+		t.Fatalf("更新看板失败: %v", err)
+	}
+	if second != first {
+		t.Errorf("重启后应该编辑原来的消息 %s，实际为 %s", first, second)
+	}
+	if len(channel.messages) != 1 || channel.messages[first] != "第二次" {
+		t.Errorf("频道中应该只有一条已更新的看板消息，实际为 %v", channel.messages)
+	}
+	if len(channel.pinned) != 1 {
+		t.Errorf("编辑看板时不应该再次置顶，实际置顶 %v", channel.pinned)
+	}
+}
+
+// TestLiveBoardRecreate 测试看板消息被删除后重新创建，并保存新的消息 ID
+func TestLiveBoardRecreate(t *testing.T) {
+	channel := &fakeDiscordChannel{messages: map[string]string{}}
+	statePath := filepath.Join(t.TempDir(), "board.json")
+	board := newTestLiveBoard(t, channel, "123", statePath)
+
+	first, err := board.Update([]DiscordEmbed{{Title: "第一次"}})
+	if err != nil {
+		t.Fatalf("创建看板失败: %v", err)
+	}
+	delete(channel.messages, first)
+	err = board.sender.EditEmbeds(first, []DiscordEmbed{{Title: "编辑"}})
+	if !isDiscordNotFound(err) || !strings.Contains(err.Error(), "消息不存在") || strings.Contains(err.Error(), "频道") {
+		t.Errorf("编辑已删除的消息应该返回消息不存在的错误，实际为 %v", err)
+	}
+	channel.requests = nil
+
+	second, err := board.Update([]DiscordEmbed{{Title: "重新创建"}})
+	if err != nil {
+		t.Fatalf("重新创建看板失败: %v", err)
+	}
+	if second == first || channel.messages[second] != "重新创建" {
+		t.Errorf("看板消息被删除后应该发送新消息，实际消息 %s，频道 %v", second, channel.messages)
+	}
+	patches := 0
+	for _, request := range channel.requests {
+		if strings.HasPrefix(request, http.MethodPatch) {
+			patches++
+		}
+	}
+	if patches != 1 {
+		t.Errorf("编辑返回 404 时不应该重试，实际编辑 %d 次", patches)
+	}
+
+	data, err := os.ReadFile(statePath)
+	if err != nil || !strings.Contains(string(data), second) {
+		t.Errorf("状态文件应该记录新的消息 ID %s，实际为 %s (%v)", second, data, err)
+	}
+}
+
+// TestLiveBoardChannelChanged 测试修改频道后不编辑旧频道中的看板，而是在新频道创建
+func TestLiveBoardChannelChanged(t *testing.T) {
+	channel := &fakeDiscordChannel{messages: map[string]string{}}
+	statePath := filepath.Join(t.TempDir(), "board.json")
+
+	if _, err := newTestLiveBoard(t, channel, "123", statePath).Update([]DiscordEmbed{{Title: "旧频道"}}); err != nil {
+		t.Fatalf("创建看板失败: %v", err)
+	}
+	if _, err := newTestLiveBoard(t, channel, "456", statePath).Update([]DiscordEmbed{{Title: "新频道"}}); err != nil {
+		t.Fatalf("创建看板失败: %v", err)
+	}
+	last := channel.requests[len(channel.requests)-2]
+	if last != "POST /channels/456/messages" {
+		t.Errorf("应该在新频道发送看板消息，实际请求为 %v", channel.requests)
+	}
+}
+
+// TestBoardEmbedsTruncated 测试看板内容超过一条消息时只显示部分币种，并在页脚注明未显示的数量
+func TestBoardEmbedsTruncated(t *testing.T) {
+	gen := NewReportGenerator().ForLocale("en").WithDiscordStyle(discordStyleFull)
+
+	small := boardEmbeds(gen, manyTestCoins(3))
+	if footer := small[len(small)-1].Footer; footer == nil || strings.Contains(footer.Text, "not shown") {
+		t.Errorf("放得下时不应该注明未显示的币种: %+v", footer)
+	}
+
+	coins := manyTestCoins(300)
+	embeds := boardEmbeds(gen, coins)
+	if len(embeds) > maxEmbedsPerMessage || embedsLength(embeds) > maxEmbedTotalLength {
+		t.Errorf("看板内容应该放得下一条消息，实际 %d 个 Embed，%d 个字符", len(embeds), embedsLength(embeds))
+	}
+	footer := embeds[len(embeds)-1].Footer
+	if footer == nil || !strings.Contains(footer.Text, "Data source") {
+		t.Fatalf("页脚应该保留数据来源，实际为 %+v", footer)
+	}
+	var hidden int
+	if _, err := fmt.Sscanf(footer.Text[strings.LastIndex(footer.Text, "+"):], "+%d more coins not shown", &hidden); err != nil || hidden <= 0 || hidden >= len(coins) {
+		t.Fatalf("页脚应该注明未显示的币种数量，实际为 %q", footer.Text)
+	}
+
+	// 显示的是前 len(coins)-hidden 个币种
+	shown := len(coins) - hidden
+	data, _ := json.Marshal(embeds)
+	if !strings.Contains(string(data), coins[shown-1].Name+" (") || strings.Contains(string(data), coins[shown].Name+" (") {
+		t.Errorf("看板应该显示前 %d 个币种", shown)
+	}
+}
+
+// embedsLength 计算一条消息中所有 Embed 的字符数
+func embedsLength(embeds []DiscordEmbed) int {
+	length := 0
+	for _, embed := range embeds {
+		length += calculateEmbedLength(&embed)
+	}
+	return length
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strings"
+)
+
+// 24h 涨跌幅图表的尺寸（像素）
+const (
+	chartWidth      = 600
+	chartBarHeight  = 18
+	chartBarSpacing = 6
+	chartPadding    = 12
+)
+
+// 图表颜色，与 HTML 报表中的 .positive、.negative 一致
+var (
+	chartBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	chartAxis       = color.RGBA{0x95, 0xa5, 0xa6, 0xff}
+	chartText       = color.RGBA{0x2c, 0x3e, 0x50, 0xff}
+	chartPositive   = color.RGBA{0x27, 0xae, 0x60, 0xff}
+	chartNegative   = color.RGBA{0xe7, 0x4c, 0x3c, 0xff}
+)
+
+// ReportChart 是内嵌在 HTML 邮件中的图表，模板中通过 {{cid .ContentID}} 引用
+type ReportChart struct {
+	ContentID string
+	Title     string
+}
+
+// RenderChangeChart 生成 24h 涨跌幅的水平柱状图（PNG），每个币种一行，顺序与 coins 一致
+// 以中间的竖线为零点，上涨向右、下跌向左，长度按最大涨跌幅缩放
+func RenderChangeChart(coins []CoinPrice) ([]byte, error) {
+	if len(coins) == 0 {
+		return nil, fmt.Errorf("no coins to chart")
+	}
+
+	height := 2*chartPadding + len(coins)*chartBarHeight + (len(coins)-1)*chartBarSpacing
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{chartBackground}, image.Point{}, draw.Src)
+
+	maxChange := 0.0
+	for _, coin := range coins {
+		maxChange = math.Max(maxChange, math.Abs(coin.PriceChangePerc24h))
+	}
+
+	center := chartWidth / 2
+	halfWidth := float64(center - chartPadding)
+	for i, coin := range coins {
+		top := chartPadding + i*(chartBarHeight+chartBarSpacing)
+		length := 0
+		if maxChange > 0 {
+			length = int(math.Round(math.Abs(coin.PriceChangePerc24h) / maxChange * halfWidth))
+		}
+		bar := image.Rect(center, top, center+length, top+chartBarHeight)
+		fill := chartPositive
+		if coin.PriceChangePerc24h < 0 {
+			bar = image.Rect(center-length, top, center, top+chartBarHeight)
+			fill = chartNegative
+		}
+		draw.Draw(img, bar, &image.Uniform{fill}, image.Point{}, draw.Src)
+
+		// 币种符号写在零点另一侧，不与柱子重叠
+		label := strings.ToUpper(coin.Symbol)
+		labelTop := top + (chartBarHeight-chartGlyphHeight*chartGlyphScale)/2
+		if coin.PriceChangePerc24h < 0 {
+			drawLabel(img, label, center+chartBarSpacing, labelTop, chartText)
+		} else {
+			drawLabel(img, label, center-chartBarSpacing-labelWidth(label), labelTop, chartText)
+		}
+	}
+
+	axis := image.Rect(center, chartPadding/2, center+1, height-chartPadding/2)
+	draw.Draw(img, axis, &image.Uniform{chartAxis}, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode chart: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// 标签使用内置的 3x5 点阵字体，放大 chartGlyphScale 倍绘制，只包含币种符号常用的字符
+const (
+	chartGlyphWidth  = 3
+	chartGlyphHeight = 5
+	chartGlyphScale  = 2
+)
+
+// chartGlyphs 每个字符 5 行，每行低 3 位表示从左到右的像素
+var chartGlyphs = map[rune][chartGlyphHeight]uint8{
+	'A': {2, 5, 7, 5, 5}, 'B': {6, 5, 6, 5, 6}, 'C': {3, 4, 4, 4, 3}, 'D': {6, 5, 5, 5, 6},
+	'E': {7, 4, 6, 4, 7}, 'F': {7, 4, 6, 4, 4}, 'G': {3, 4, 5, 5, 3}, 'H': {5, 5, 7, 5, 5},
+	'I': {7, 2, 2, 2, 7}, 'J': {1, 1, 1, 5, 2}, 'K': {5, 5, 6, 5, 5}, 'L': {4, 4, 4, 4, 7},
+	'M': {5, 7, 7, 5, 5}, 'N': {6, 5, 5, 5, 5}, 'O': {2, 5, 5, 5, 2}, 'P': {6, 5, 6, 4, 4},
+	'Q': {2, 5, 5, 6, 3}, 'R': {6, 5, 6, 5, 5}, 'S': {3, 4, 2, 1, 6}, 'T': {7, 2, 2, 2, 2},
+	'U': {5, 5, 5, 5, 7}, 'V': {5, 5, 5, 5, 2}, 'W': {5, 5, 7, 7, 5}, 'X': {5, 5, 2, 5, 5},
+	'Y': {5, 5, 2, 2, 2}, 'Z': {7, 1, 2, 4, 7},
+	'0': {7, 5, 5, 5, 7}, '1': {2, 6, 2, 2, 7}, '2': {6, 1, 2, 4, 7}, '3': {6, 1, 2, 1, 6},
+	'4': {5, 5, 7, 1, 1}, '5': {7, 4, 6, 1, 6}, '6': {3, 4, 7, 5, 7}, '7': {7, 1, 2, 2, 2},
+	'8': {7, 5, 7, 5, 7}, '9': {7, 5, 7, 1, 6}, '-': {0, 0, 7, 0, 0}, '.': {0, 0, 0, 0, 2},
+}
+
+// labelWidth 返回标签绘制后的宽度（像素），字符之间留 1 个点的间距
+func labelWidth(label string) int {
+	n := len([]rune(label))
+	if n == 0 {
+		return 0
+	}
+	return (n*(chartGlyphWidth+1) - 1) * chartGlyphScale
+}
+
+// drawLabel 在 (x, y) 处绘制标签，不支持的字符留空
+func drawLabel(img *image.RGBA, label string, x, y int, c color.Color) {
+	for _, r := range label {
+		glyph := chartGlyphs[r]
+		for row, bits := range glyph {
+			for col := 0; col < chartGlyphWidth; col++ {
+				if bits&(1<<(chartGlyphWidth-1-col)) == 0 {
+					continue
+				}
+				px := x + col*chartGlyphScale
+				py := y + row*chartGlyphScale
+				draw.Draw(img, image.Rect(px, py, px+chartGlyphScale, py+chartGlyphScale), &image.Uniform{c}, image.Point{}, draw.Src)
+			}
+		}
+		x += (chartGlyphWidth + 1) * chartGlyphScale
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// TestRenderChangeChart 测试图表尺寸以及上涨、下跌柱子的位置和颜色
+func TestRenderChangeChart(t *testing.T) {
+	coins := []CoinPrice{
+		{Symbol: "btc", PriceChangePerc24h: 4},
+		{Symbol: "eth", PriceChangePerc24h: -2},
+		{Symbol: "usdt", PriceChangePerc24h: 0},
+	}
+	data, err := RenderChangeChart(coins)
+	if err != nil {
+		t.Fatalf("生成图表失败: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("图表应该是 PNG: %v", err)
+	}
+
+	bounds := img.Bounds()
+	wantHeight := 2*chartPadding + 3*chartBarHeight + 2*chartBarSpacing
+	if bounds.Dx() != chartWidth || bounds.Dy() != wantHeight {
+		t.Fatalf("图表尺寸 = %dx%d, 期望 %dx%d", bounds.Dx(), bounds.Dy(), chartWidth, wantHeight)
+	}
+
+	center := chartWidth / 2
+	halfWidth := center - chartPadding
+	rowMiddle := func(i int) int {
+		return chartPadding + i*(chartBarHeight+chartBarSpacing) + chartBarHeight/2
+	}
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"最大涨幅的柱子伸到右边缘", center + halfWidth - 1, rowMiddle(0), chartPositive},
+		{"上涨柱子不在零点左侧", center - halfWidth, rowMiddle(0), chartBackground},
+		{"下跌柱子按比例缩放到一半长度", center - halfWidth/2 + 1, rowMiddle(1), chartNegative},
+		{"下跌柱子不超过比例长度", center - halfWidth/2 - 2, rowMiddle(1), chartBackground},
+		{"零涨跌幅没有柱子", center + 10, rowMiddle(2), chartBackground},
+		{"零点轴线", center, rowMiddle(2), chartAxis},
+	}
+	for _, tt := range tests {
+		r, g, b, a := img.At(tt.x, tt.y).RGBA()
+		got := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		if got != tt.want {
+			t.Errorf("%s: (%d, %d) 的颜色 = %v, 期望 %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+// TestRenderChangeChartEmpty 测试没有币种时返回错误
+func TestRenderChangeChartEmpty(t *testing.T) {
+	if _, err := RenderChangeChart(nil); err == nil {
+		t.Error("没有币种时应该返回错误")
+	}
+}
@@ -5,21 +5,23 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 )
 
 type CoinPrice struct {
-	ID                string  `json:"id"`
-	Symbol            string  `json:"symbol"`
-	Name              string  `json:"name"`
-	CurrentPrice      float64 `json:"current_price"`
-	MarketCap         float64 `json:"market_cap"`
-	PriceChange24h    float64 `json:"price_change_24h"`
+	ID                 string  `json:"id"`
+	Symbol             string  `json:"symbol"`
+	Name               string  `json:"name"`
+	CurrentPrice       float64 `json:"current_price"`
+	MarketCap          float64 `json:"market_cap"`
+	PriceChange24h     float64 `json:"price_change_24h"`
 	PriceChangePerc24h float64 `json:"price_change_percentage_24h"`
-	Volume24h         float64 `json:"total_volume"`
-	LastUpdated       string  `json:"last_updated"`
+	Volume24h          float64 `json:"total_volume"`
+	High24h            float64 `json:"high_24h"`
+	Low24h             float64 `json:"low_24h"`
+	ATH                float64 `json:"ath"`
+	LastUpdated        string  `json:"last_updated"`
 }
 
 type CoinGeckoClient struct {
@@ -29,19 +31,8 @@ type CoinGeckoClient struct {
 }
 
 func NewCoinGeckoClient(apiKey string, proxyEnabled bool, proxyURL string) *CoinGeckoClient {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	// 如果启用了代理，配置 HTTP Transport
-	if proxyEnabled && proxyURL != "" {
-		parsedProxyURL, err := url.Parse(proxyURL)
-		if err == nil {
-			client.Transport = &http.Transport{
-				Proxy: http.ProxyURL(parsedProxyURL),
-			}
-		}
-	}
+	// 启用代理时通过 HTTP、HTTPS 或 SOCKS5 代理连接
+	client := newProxyHTTPClient(proxyEnabled, proxyURL, 30*time.Second)
 
 	return &CoinGeckoClient{
 		baseURL: "https://api.coingecko.com/api/v3",
@@ -106,4 +97,4 @@ func (c *CoinGeckoClient) doRequest(url string) ([]CoinPrice, error) {
 	}
 
 	return coins, nil
-}
\ No newline at end of file
+}
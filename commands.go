@@ -0,0 +1,283 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 斜杠命令名称
+const (
+	commandPrice     = "price"
+	commandReport    = "report"
+	commandWatchlist = "watchlist"
+)
+
+// manageGuildPermission 是 /watchlist 默认需要的权限，服务器管理员可以在设置中调整
+var manageGuildPermission int64 = discordgo.PermissionManageGuild
+
+// slashCommands 是注册到 Discord 的斜杠命令
+var slashCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:        commandPrice,
+		Description: "Show the current price of a coin",
+		DescriptionLocalizations: &map[discordgo.Locale]string{
+			discordgo.ChineseCN: "查询单个币种的当前价格",
+		},
+		Options: []*discordgo.ApplicationCommandOption{
+			coinOption("CoinGecko ID, e.g. bitcoin", "CoinGecko ID，如 bitcoin"),
+		},
+	},
+	{
+		Name:        commandReport,
+		Description: "Post the current price report",
+		DescriptionLocalizations: &map[discordgo.Locale]string{
+			discordgo.ChineseCN: "发送当前的价格报表",
+		},
+	},
+	{
+		Name:        commandWatchlist,
+		Description: "Edit this server's watchlist used by /report",
+		DescriptionLocalizations: &map[discordgo.Locale]string{
+			discordgo.ChineseCN: "编辑本服务器 /report 使用的关注列表",
+		},
+		DefaultMemberPermissions: &manageGuildPermission,
+		Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Add a coin to the watchlist",
+				DescriptionLocalizations: map[discordgo.Locale]string{
+					discordgo.ChineseCN: "将币种加入关注列表",
+				},
+				Options: []*discordgo.ApplicationCommandOption{coinOption("CoinGecko ID to add", "要加入的 CoinGecko ID")},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove a coin from the watchlist",
+				DescriptionLocalizations: map[discordgo.Locale]string{
+					discordgo.ChineseCN: "将币种移出关注列表",
+				},
+				Options: []*discordgo.ApplicationCommandOption{coinOption("CoinGecko ID to remove", "要移出的 CoinGecko ID")},
+			},
+		},
+	},
+}
+
+// coinOption 返回必填的 coin 参数
+func coinOption(description, descriptionZH string) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "coin",
+		Description: description,
+		DescriptionLocalizations: map[discordgo.Locale]string{
+			discordgo.ChineseCN: descriptionZH,
+		},
+		Required: true,
+	}
+}
+
+// CommandResponse 是斜杠命令的回复：一段文字，或者按消息拆分好的 Embed
+type CommandResponse struct {
+	Content  string
+	Messages [][]DiscordEmbed
+}
+
+// CommandHandler 执行斜杠命令，与接收命令的方式（Gateway 或 HTTP）无关
+// 价格通过 PriceCache 获取，报表使用与定时报表相同的模板
+type CommandHandler struct {
+	config     *Config
+	prices     *PriceCache
+	reportGen  *ReportGenerator
+	watchlists *WatchlistStore
+	history    *HistoryStore
+}
+
+// NewCommandHandler 创建斜杠命令处理器
+func NewCommandHandler(config *Config, coinClient *CoinGeckoClient, reportGen *ReportGenerator) (*CommandHandler, error) {
+	watchlists, err := NewWatchlistStore(watchlistPath(config))
+	if err != nil {
+		return nil, err
+	}
+	return &CommandHandler{
+		config:     config,
+		prices:     NewPriceCache(coinClient, config.Discord.Commands.CacheTTL),
+		reportGen:  reportGen,
+		watchlists: watchlists,
+	}, nil
+}
+
+// SetHistory 设置 /report 使用的历史快照存储，与定时报表共享同一个存储，每次执行命令时读取最新的快照
+func (h *CommandHandler) SetHistory(history *HistoryStore) {
+	h.history = history
+}
+
+// Handle 执行一个斜杠命令，guildID 在私信中为空，locale 为用户的 Discord 语言
+func (h *CommandHandler) Handle(guildID string, locale discordgo.Locale, data discordgo.ApplicationCommandInteractionData) CommandResponse {
+	gen := h.reportGen.ForLocale(h.commandLocale(locale))
+	switch data.Name {
+	case commandPrice:
+		return h.price(gen, commandOption(data.Options, "coin"))
+	case commandReport:
+		return h.report(gen, guildID)
+	case commandWatchlist:
+		if guildID == "" {
+			return CommandResponse{Content: gen.Locale().T("command.guild_only")}
+		}
+		if len(data.Options) == 0 {
+			return CommandResponse{}
+		}
+		sub := data.Options[0]
+		return h.watchlist(gen, guildID, sub.Name, commandOption(sub.Options, "coin"))
+	}
+	return CommandResponse{}
+}
+
+// price 处理 /price
+func (h *CommandHandler) price(gen *ReportGenerator, coinID string) CommandResponse {
+	if !isCoinID(coinID) {
+		return CommandResponse{Content: gen.Locale().T("command.invalid_coin")}
+	}
+	coins, err := h.prices.Prices([]string{coinID})
+	if err != nil {
+		log.Printf("斜杠命令获取价格失败: %v", err)
+		return CommandResponse{Content: gen.Locale().T("command.failed")}
+	}
+	if len(coins) == 0 {
+		return CommandResponse{Content: gen.Locale().T("command.not_found", coinID)}
+	}
+	return CommandResponse{Messages: paginateEmbed(gen.GenerateDiscordPriceEmbed(coins[0]))}
+}
+
+// report 处理 /report，服务器设置了关注列表时使用关注列表，否则使用 coins 配置
+func (h *CommandHandler) report(gen *ReportGenerator, guildID string) CommandResponse {
+	ids := h.config.Coins
+	if guildID != "" {
+		if watchlist := h.watchlists.Coins(guildID); len(watchlist) > 0 {
+			ids = watchlist
+		}
+	}
+	coins, err := h.prices.Prices(ids)
+	if err != nil {
+		log.Printf("斜杠命令获取价格失败: %v", err)
+	}
+	if len(coins) == 0 {
+		return CommandResponse{Content: gen.Locale().T("command.failed")}
+	}
+	if h.history != nil {
+		history, err := h.history.Load()
+		if err != nil {
+			log.Printf("读取历史快照失败: %v", err)
+		}
+		gen.SetHistory(history)
+	}
+	return CommandResponse{Messages: paginateEmbed(gen.GenerateDiscordEmbed(coins))}
+}
+
+// watchlist 处理 /watchlist add 和 /watchlist remove，加入的币种需要能在 CoinGecko 查到
+func (h *CommandHandler) watchlist(gen *ReportGenerator, guildID, action, coinID string) CommandResponse {
+	locale := gen.Locale()
+	var message string
+	switch action {
+	case "add":
+		if !isCoinID(coinID) {
+			return CommandResponse{Content: locale.T("command.invalid_coin")}
+		}
+		coins, err := h.prices.Prices([]string{coinID})
+		if err != nil {
+			log.Printf("斜杠命令获取价格失败: %v", err)
+			return CommandResponse{Content: locale.T("command.failed")}
+		}
+		if len(coins) == 0 {
+			return CommandResponse{Content: locale.T("command.not_found", coinID)}
+		}
+		added, err := h.watchlists.Add(guildID, coinID)
+		if err != nil {
+			log.Printf("保存关注列表失败: %v", err)
+			return CommandResponse{Content: locale.T("watchlist.save_failed")}
+		}
+		message = locale.T("watchlist.exists", coinID)
+		if added {
+			message = locale.T("watchlist.added", coinID)
+		}
+	case "remove":
+		removed, err := h.watchlists.Remove(guildID, coinID)
+		if err != nil {
+			log.Printf("保存关注列表失败: %v", err)
+			return CommandResponse{Content: locale.T("watchlist.save_failed")}
+		}
+		message = locale.T("watchlist.missing", coinID)
+		if removed {
+			message = locale.T("watchlist.removed", coinID)
+		}
+	default:
+		return CommandResponse{}
+	}
+
+	current := locale.T("watchlist.empty")
+	if coins := h.watchlists.Coins(guildID); len(coins) > 0 {
+		current = locale.T("watchlist.current", "`"+strings.Join(coins, "`, `")+"`")
+	}
+	return CommandResponse{Content: message + "\n" + current}
+}
+
+// commandLocale 将用户的 Discord 语言映射为报表语言区域，不支持的语言使用 discord.locale
+func (h *CommandHandler) commandLocale(locale discordgo.Locale) string {
+	switch {
+	case strings.HasPrefix(string(locale), "zh"):
+		return "zh-CN"
+	case strings.HasPrefix(string(locale), "en"):
+		return "en"
+	}
+	return discordLocale(h.config)
+}
+
+// commandOption 返回字符串参数的值，统一为小写并去掉首尾空白（CoinGecko ID 都是小写）
+func commandOption(options []*discordgo.ApplicationCommandInteractionDataOption, name string) string {
+	for _, option := range options {
+		if option.Name == name && option.Type == discordgo.ApplicationCommandOptionString {
+			value, _ := option.Value.(string)
+			return strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+	return ""
+}
+
+// isCoinID 判断是否像一个 CoinGecko ID（小写字母、数字和 -）
+// 其他输入不需要请求 API，也不会原样出现在回复中
+func isCoinID(id string) bool {
+	if id == "" || len(id) > 100 {
+		return false
+	}
+	for _, c := range id {
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// toMessageEmbeds 将报表 Embed 转换为 discordgo 的消息 Embed
+func toMessageEmbeds(embeds []DiscordEmbed) []*discordgo.MessageEmbed {
+	converted := make([]*discordgo.MessageEmbed, len(embeds))
+	for i, embed := range embeds {
+		fields := make([]*discordgo.MessageEmbedField, len(embed.Fields))
+		for j, field := range embed.Fields {
+			fields[j] = &discordgo.MessageEmbedField{Name: field.Name, Value: field.Value, Inline: field.Inline}
+		}
+		converted[i] = &discordgo.MessageEmbed{
+			Title:       embed.Title,
+			Description: embed.Description,
+			Color:       embed.Color,
+			Fields:      fields,
+			Timestamp:   embed.Timestamp,
+		}
+		if embed.Footer != nil {
+			converted[i].Footer = &discordgo.MessageEmbedFooter{Text: embed.Footer.Text}
+		}
+	}
+	return converted
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTestCommandHandler 创建使用测试 CoinGecko 服务器的命令处理器
+func newTestCommandHandler(t *testing.T) (*CommandHandler, func() []string) {
+	t.Helper()
+	client, requests := fakeCoinGecko(t, append(sampleCoins, CoinPrice{ID: "solana", Symbol: "sol", Name: "Solana", CurrentPrice: 100}))
+	config := &Config{Coins: []string{"bitcoin", "ethereum"}, DataDir: t.TempDir()}
+	handler, err := NewCommandHandler(config, client, NewReportGenerator())
+	if err != nil {
+		t.Fatalf("创建命令处理器失败: %v", err)
+	}
+	return handler, requests
+}
+
+// commandData 构建斜杠命令数据，coin 非空时带上 coin 参数，sub 非空时作为子命令
+func commandData(name, sub, coin string) discordgo.ApplicationCommandInteractionData {
+	var options []*discordgo.ApplicationCommandInteractionDataOption
+	if coin != "" {
+		options = append(options, &discordgo.ApplicationCommandInteractionDataOption{
+			Name: "coin", Type: discordgo.ApplicationCommandOptionString, Value: coin,
+		})
+	}
+	if sub != "" {
+		options = []*discordgo.ApplicationCommandInteractionDataOption{{
+			Name: sub, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options,
+		}}
+	}
+	return discordgo.ApplicationCommandInteractionData{Name: name, Options: options}
+}
+
+// TestCommandPrice 测试 /price 返回单个币种的 Embed，并使用用户的语言
+func TestCommandPrice(t *testing.T) {
+	handler, _ := newTestCommandHandler(t)
+
+	response := handler.Handle("", discordgo.EnglishUS, commandData(commandPrice, "", " Bitcoin "))
+	if len(response.Messages) != 1 || len(response.Messages[0]) != 1 {
+		t.Fatalf("/price 应该返回一个 Embed: %+v", response)
+	}
+	embed := response.Messages[0][0]
+	if embed.Title != "Bitcoin (BTC)" || !strings.Contains(embed.Description, "$45,000.00") || !strings.Contains(embed.Description, "Market Cap") {
+		t.Errorf("/price 的 Embed 内容错误: %+v", embed)
+	}
+
+	if response := handler.Handle("", discordgo.ChineseCN, commandData(commandPrice, "", "dogecoin")); !strings.Contains(response.Content, "未找到币种 `dogecoin`") {
+		t.Errorf("不存在的币种应该返回提示: %q", response.Content)
+	}
+	if response := handler.Handle("", discordgo.ChineseCN, commandData(commandPrice, "", "@everyone")); response.Content != locales["zh-CN"].T("command.invalid_coin") {
+		t.Errorf("无效的币种 ID 不应该原样返回: %q", response.Content)
+	}
+}
+
+// TestCommandWatchlistAndReport 测试 /watchlist 修改服务器的关注列表，/report 使用关注列表
+func TestCommandWatchlistAndReport(t *testing.T) {
+	handler, requests := newTestCommandHandler(t)
+
+	if response := handler.Handle("guild", discordgo.EnglishUS, commandData(commandReport, "", "")); len(response.Messages) == 0 || len(response.Messages[0][0].Fields) != 2 {
+		t.Fatalf("未设置关注列表时 /report 应该使用 coins 配置: %+v", response)
+	}
+
+	response := handler.Handle("guild", discordgo.EnglishUS, commandData(commandWatchlist, "add", "solana"))
+	if !strings.Contains(response.Content, "Added `solana`") || !strings.Contains(response.Content, "`solana`") {
+		t.Errorf("/watchlist add 的回复错误: %q", response.Content)
+	}
+	if response := handler.Handle("guild", discordgo.EnglishUS, commandData(commandWatchlist, "add", "dogecoin")); !strings.Contains(response.Content, "not found") {
+		t.Errorf("不存在的币种不应该加入关注列表: %q", response.Content)
+	}
+
+	report := handler.Handle("guild", discordgo.EnglishUS, commandData(commandReport, "", ""))
+	if fields := report.Messages[0][0].Fields; len(fields) != 1 || !strings.Contains(fields[0].Name, "Solana") {
+		t.Errorf("/report 应该使用服务器的关注列表: %+v", fields)
+	}
+	// 其他服务器和私信不受影响
+	if other := handler.Handle("other", discordgo.EnglishUS, commandData(commandReport, "", "")); len(other.Messages[0][0].Fields) != 2 {
+		t.Error("其他服务器应该使用 coins 配置")
+	}
+
+	response = handler.Handle("guild", discordgo.EnglishUS, commandData(commandWatchlist, "remove", "solana"))
+	if !strings.Contains(response.Content, "Removed `solana`") || !strings.Contains(response.Content, "empty") {
+		t.Errorf("/watchlist remove 的回复错误: %q", response.Content)
+	}
+	if response := handler.Handle("", discordgo.EnglishUS, commandData(commandWatchlist, "add", "solana")); response.Content != locales["en"].T("command.guild_only") {
+		t.Errorf("私信中不能修改关注列表: %q", response.Content)
+	}
+
+	// 价格在缓存有效期内只请求一次
+	for _, ids := range requests() {
+		if strings.Contains(ids, "bitcoin") && ids != "bitcoin,ethereum" {
+			t.Errorf("重复请求了已缓存的币种: %v", requests())
+		}
+	}
+}
+
+// TestCommandReportHistory 测试 /report 读取与定时报表共享的历史快照，包括命令处理器创建之后记录的快照
+func TestCommandReportHistory(t *testing.T) {
+	handler, _ := newTestCommandHandler(t)
+	store := NewHistoryStore(filepath.Join(t.TempDir(), "history.json"), 7)
+	handler.SetHistory(store)
+
+	previous := []CoinPrice{{ID: "bitcoin", Symbol: "btc", Name: "Bitcoin", Volume24h: 1e9}}
+	if err := store.Record(previous, time.Now().AddDate(0, 0, -1)); err != nil {
+		t.Fatalf("保存历史快照失败: %v", err)
+	}
+
+	response := handler.Handle("guild", discordgo.EnglishUS, commandData(commandReport, "", ""))
+	if len(response.Messages) == 0 {
+		t.Fatalf("/report 应该返回报表: %+v", response)
+	}
+	data, _ := json.Marshal(response.Messages)
+	if !strings.Contains(string(data), "Bitcoin (BTC) volume is 25.0x the previous report") {
+		t.Errorf("/report 应该根据历史快照显示放量事件: %s", data)
+	}
+}
+
+// TestCommandWatchlistSaveFailed 测试关注列表写入失败时回复错误，且修改不生效
+func TestCommandWatchlistSaveFailed(t *testing.T) {
+	handler, _ := newTestCommandHandler(t)
+	handler.Handle("guild", discordgo.EnglishUS, commandData(commandWatchlist, "add", "bitcoin"))
+
+	// 将文件路径放在一个普通文件下面，使写入失败
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	handler.watchlists.path = filepath.Join(blocker, "watchlist.json")
+
+	failed := locales["en"].T("watchlist.save_failed")
+	if response := handler.Handle("guild", discordgo.EnglishUS, commandData(commandWatchlist, "add", "solana")); response.Content != failed {
+		t.Errorf("写入失败时 /watchlist add 应该回复错误，实际为 %q", response.Content)
+	}
+	if response := handler.Handle("guild", discordgo.EnglishUS, commandData(commandWatchlist, "remove", "bitcoin")); response.Content != failed {
+		t.Errorf("写入失败时 /watchlist remove 应该回复错误，实际为 %q", response.Content)
+	}
+	if coins := handler.watchlists.Coins("guild"); len(coins) != 1 || coins[0] != "bitcoin" {
+		t.Errorf("写入失败时关注列表不应该改变，实际为 %v", coins)
+	}
+}
+
+// TestToMessageEmbeds 测试报表 Embed 转换为 discordgo 的 Embed
+func TestToMessageEmbeds(t *testing.T) {
+	embeds := toMessageEmbeds([]DiscordEmbed{{
+		Title:  "报表",
+		Color:  0x27AE60,
+		Fields: []EmbedField{{Name: "BTC", Value: "$45,000.00", Inline: true}},
+		Footer: &EmbedFooter{Text: "footer"},
+	}})
+	if len(embeds) != 1 || embeds[0].Title != "报表" || embeds[0].Color != 0x27AE60 || embeds[0].Footer.Text != "footer" {
+		t.Fatalf("转换结果错误: %+v", embeds)
+	}
+	if field := embeds[0].Fields[0]; field.Name != "BTC" || !field.Inline {
+		t.Errorf("字段转换错误: %+v", field)
+	}
+}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"fmt"
+	"net/mail"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -18,17 +23,150 @@ type Config struct {
 		Username   string   `yaml:"username"`
 		Password   string   `yaml:"password"`
 		To         []string `yaml:"to"`
+
+		// 投递方式：smtp（默认）、ses（AWS SES v2 API）、mailgun、http（通用 JSON 接口）、
+		// sendmail（本机 MTA）或 maildir（写入本地目录，不发送）
+		// 不使用 SMTP 时不需要 SMTP 相关配置，发件人使用 from
+		Transport string `yaml:"transport"`
+		SES       struct {
+			Region           string `yaml:"region"`
+			AccessKeyID      string `yaml:"access_key_id"`
+			SecretAccessKey  string `yaml:"secret_access_key"`
+			SessionToken     string `yaml:"session_token"`
+			ConfigurationSet string `yaml:"configuration_set"`
+			Endpoint         string `yaml:"endpoint"` // 可选，覆盖默认的 API 地址
+		} `yaml:"ses"`
+		Mailgun struct {
+			Domain   string `yaml:"domain"`
+			APIKey   string `yaml:"api_key"`
+			Region   string `yaml:"region"`   // us（默认）或 eu
+			Endpoint string `yaml:"endpoint"` // 可选，覆盖区域对应的 API 地址
+		} `yaml:"mailgun"`
+		HTTP struct {
+			URL     string            `yaml:"url"`
+			Headers map[string]string `yaml:"headers"` // 附加的请求头，如 Authorization
+		} `yaml:"http"`
+		Sendmail struct {
+			Path string   `yaml:"path"` // 默认 /usr/sbin/sendmail
+			Args []string `yaml:"args"` // 默认 ["-t", "-i"]
+		} `yaml:"sendmail"`
+		Maildir struct {
+			Path   string `yaml:"path"`   // 默认 data_dir/maildir
+			Format string `yaml:"format"` // maildir（默认）或 eml
+		} `yaml:"maildir"`
+
+		// 认证方式：plain（默认，使用 password）或 xoauth2（OAuth2 访问令牌）
+		Auth   string `yaml:"auth"`
+		OAuth2 struct {
+			// 服务商：google 或 microsoft，用于推导 token_url 和 scope
+			Provider string `yaml:"provider"`
+			// Microsoft 365 的租户 ID（可选，默认 common）
+			Tenant       string `yaml:"tenant"`
+			TokenURL     string `yaml:"token_url"`
+			ClientID     string `yaml:"client_id"`
+			ClientSecret string `yaml:"client_secret"`
+			RefreshToken string `yaml:"refresh_token"`
+			Scope        string `yaml:"scope"`
+		} `yaml:"oauth2"`
+
+		// 发件人地址（可选，默认使用 username）和显示名称
+		From     string `yaml:"from"`
+		FromName string `yaml:"from_name"`
+		// 回复地址和 List-Id 邮件头（可选）
+		ReplyTo string `yaml:"reply_to"`
+		ListID  string `yaml:"list_id"`
+
+		// 正文传输编码：quoted-printable（默认）或 base64
+		TransferEncoding string `yaml:"transfer_encoding"`
+
+		// TLS 模式：starttls、implicit（SMTPS）或 none，默认 465 端口为 implicit，其余为 starttls
+		TLS string `yaml:"tls"`
+		// 自定义 CA 证书、最低 TLS 版本以及是否跳过证书校验（可选）
+		TLSCAFile             string `yaml:"tls_ca_file"`
+		TLSMinVersion         string `yaml:"tls_min_version"`
+		TLSInsecureSkipVerify bool   `yaml:"tls_insecure_skip_verify"`
+
+		// 附件（可选）：币种数据导出文件和内嵌在正文中的图表
+		Attachments struct {
+			Data  string `yaml:"data"`  // csv 或 xlsx，为空时不附加数据文件
+			Chart bool   `yaml:"chart"` // 在 HTML 正文中内嵌 24h 涨跌幅图表
+		} `yaml:"attachments"`
+
+		// DKIM 签名（可选），签名算法由私钥类型决定：RSA 使用 rsa-sha256，Ed25519 使用 ed25519-sha256
+		DKIM struct {
+			Domain         string `yaml:"domain"`
+			Selector       string `yaml:"selector"`
+			PrivateKeyFile string `yaml:"private_key_file"`
+		} `yaml:"dkim"`
+
+		// 发送失败后的重试策略（可选），失败的邮件保存在 data_dir/outbox 中
+		Retry struct {
+			MaxAttempts    int           `yaml:"max_attempts"`
+			InitialBackoff time.Duration `yaml:"initial_backoff"`
+			MaxBackoff     time.Duration `yaml:"max_backoff"`
+		} `yaml:"retry"`
+
+		// 发送方式：to（默认，所有收件人写入同一个 To 邮件头）、individual（每个收件人单独发送）
+		// 或 bcc（密送，收件人互相不可见）
+		Delivery string `yaml:"delivery"`
+
+		// 邮件报表的语言区域（可选，默认使用全局 locale）
+		Locale string `yaml:"locale"`
+		// 需要单独设置语言区域、称呼或币种的收件人（可选）
+		Recipients []EmailRecipient `yaml:"recipients"`
 	} `yaml:"email"`
 
 	// Discord 配置（可选）
 	Discord struct {
 		BotToken  string `yaml:"bot_token"`
 		ChannelID string `yaml:"channel_id"`
+		// 通过 webhook 发送（可选），配置后报表使用 webhook 发送，不需要 bot_token 和 channel_id
+		WebhookURL string `yaml:"webhook_url"`
+		Username   string `yaml:"username"`   // webhook 消息的显示名称（可选）
+		AvatarURL  string `yaml:"avatar_url"` // webhook 消息的头像（可选）
+		ThreadID   string `yaml:"thread_id"`  // 发送到 webhook 所在频道中的指定子区（可选）
+		Locale     string `yaml:"locale"`
+		// 报表的发送方式：message（默认，直接发送）、thread（以报表消息创建子区）或 forum（在论坛频道中发帖）
+		// thread 和 forum 方式每天的子区以日期命名，thread 方式需要 bot_token + channel_id
+		PostMode    string        `yaml:"post_mode"`
+		ForumTags   []string      `yaml:"forum_tags"`   // 论坛帖子的标签名称或 ID（webhook 方式只支持 ID）
+		AutoArchive time.Duration `yaml:"auto_archive"` // 子区无人发言后自动归档的时间：1h、24h、72h 或 168h（默认使用频道设置）
+		// 多个发送目标（可选），每个目标可以单独设置频道或 webhook、币种、样式和发送时间
+		// 上面的 channel_id 或 webhook_url 作为第一个目标，使用全局的币种和发送时间
+		Targets []DiscordTarget `yaml:"targets"`
+
+		// 实时看板（可选）：在频道中保留一条消息，每隔 interval 编辑为最新价格，需要 bot_token
+		Board struct {
+			Enabled   bool          `yaml:"enabled"`
+			ChannelID string        `yaml:"channel_id"` // 看板所在的频道（默认使用 discord.channel_id）
+			Interval  time.Duration `yaml:"interval"`   // 更新间隔（默认 5m，最短 1m）
+			Coins     []string      `yaml:"coins"`      // 看板中的币种（可选，默认使用 coins 配置）
+			Style     string        `yaml:"style"`      // full 或 compact（默认）
+			Pin       bool          `yaml:"pin"`        // 创建看板消息后置顶，需要“管理消息”权限
+		} `yaml:"board"`
+
+		// 斜杠命令（可选），启用后注册 /price、/report、/watchlist，需要 bot_token
+		Commands struct {
+			Enabled  bool          `yaml:"enabled"`
+			GuildID  string        `yaml:"guild_id"`  // 只在该服务器注册命令，立即生效（可选，默认全局注册）
+			CacheTTL time.Duration `yaml:"cache_ttl"` // 价格缓存时间（默认 5m）
+			// 接收方式：gateway（默认，保持 Gateway 长连接）或 http（Discord 将命令 POST 到 interactions 地址）
+			Mode      string `yaml:"mode"`
+			Listen    string `yaml:"listen"`     // http 模式的监听地址（默认 :8080）
+			Path      string `yaml:"path"`       // http 模式的路径（默认 /interactions）
+			PublicKey string `yaml:"public_key"` // 应用的 Public Key，用于校验请求签名（http 模式必填）
+		} `yaml:"commands"`
 	} `yaml:"discord"`
 
+	// 代理配置，URL 支持 http://、https:// 和 socks5://，可以包含 user:pass 认证信息
 	Proxy struct {
 		Enabled bool   `yaml:"enabled"`
 		URL     string `yaml:"url"`
+
+		// 单个服务的代理（可选）：为空时使用上面的全局代理，为 "direct" 时该服务直连
+		CoinGecko string `yaml:"coingecko"`
+		Discord   string `yaml:"discord"`
+		Email     string `yaml:"email"`
 	} `yaml:"proxy"`
 
 	Coins []string `yaml:"coins"`
@@ -37,8 +175,90 @@ type Config struct {
 		Hour   int `yaml:"hour"`
 		Minute int `yaml:"minute"`
 	} `yaml:"schedule"`
+
+	// 报表布局：显示的列、排序方式和币种分组（可选）
+	Report struct {
+		Columns []string    `yaml:"columns"`
+		Sort    string      `yaml:"sort"`
+		Order   string      `yaml:"order"`
+		Groups  []CoinGroup `yaml:"groups"`
+	} `yaml:"report"`
+
+	// 自定义报表模板（可选，未配置时使用内置模板）
+	Templates TemplateConfig `yaml:"templates"`
+
+	// 历史快照（可选，启用后模板可以访问最近若干天的数据）
+	History struct {
+		Enabled bool `yaml:"enabled"`
+		Days    int  `yaml:"days"`
+	} `yaml:"history"`
+
+	// 运行时数据目录，用于保存历史快照等状态文件
+	DataDir string `yaml:"data_dir"`
+
+	// 报表默认语言区域（zh-CN 或 en，默认 zh-CN）
+	Locale string `yaml:"locale"`
+}
+
+// TemplateConfig 表示自定义模板文件的路径，为空时使用内置模板
+type TemplateConfig struct {
+	EmailHTML    string `yaml:"email_html"`
+	EmailText    string `yaml:"email_text"`
+	EmailSubject string `yaml:"email_subject"`
+	Discord      string `yaml:"discord"`
+}
+
+// EmailRecipient 表示一个邮件收件人及其偏好设置
+type EmailRecipient struct {
+	Address string   `yaml:"address"`
+	Locale  string   `yaml:"locale"`
+	Name    string   `yaml:"name"`  // 收件人称呼，individual 模式下用于 To 邮件头和报表问候语
+	Coins   []string `yaml:"coins"` // 收件人关注的币种（可选，默认使用 coins 配置）
+}
+
+// DiscordTarget 表示一个 Discord 发送目标，channel_id 和 webhook_url 二选一
+type DiscordTarget struct {
+	Name       string        `yaml:"name"`        // 用于日志（可选）
+	ChannelID  string        `yaml:"channel_id"`  // 使用 discord.bot_token 发送到该频道
+	WebhookURL string        `yaml:"webhook_url"` // 通过 webhook 发送
+	Username   string        `yaml:"username"`    // webhook 消息的显示名称（可选）
+	AvatarURL  string        `yaml:"avatar_url"`  // webhook 消息的头像（可选）
+	ThreadID   string        `yaml:"thread_id"`   // webhook 所在频道中的子区（可选）
+	Coins      []string      `yaml:"coins"`       // 报表包含的币种（可选，默认使用 coins 配置）
+	Style      string        `yaml:"style"`       // full（默认，每个币种一个字段）或 compact（每个币种一行）
+	Locale     string        `yaml:"locale"`      // 语言区域（可选，默认使用 discord.locale）
+	Schedule   *ScheduleTime `yaml:"schedule"`    // 发送时间（可选，默认使用 schedule 配置）
+	// 发送方式、论坛标签和自动归档时间（可选，默认使用 discord 中的配置）
+	PostMode    string        `yaml:"post_mode"`
+	ForumTags   []string      `yaml:"forum_tags"`
+	AutoArchive time.Duration `yaml:"auto_archive"`
 }
 
+// ScheduleTime 表示每天的发送时间
+type ScheduleTime struct {
+	Hour   int `yaml:"hour"`
+	Minute int `yaml:"minute"`
+}
+
+// label 返回目标在日志中显示的名称
+func (t DiscordTarget) label() string {
+	switch {
+	case t.Name != "":
+		return t.Name
+	case t.ChannelID != "":
+		return "channel " + t.ChannelID
+	default:
+		return "webhook"
+	}
+}
+
+// 邮件发送方式
+const (
+	deliveryTo         = "to"
+	deliveryIndividual = "individual"
+	deliveryBcc        = "bcc"
+)
+
 func LoadConfig(configPath string) (*Config, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -51,6 +271,8 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	applyDefaults(&config)
+
 	if err := validateConfig(&config); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
@@ -58,6 +280,19 @@ func LoadConfig(configPath string) (*Config, error) {
 	return &config, nil
 }
 
+// applyDefaults 为未填写的可选配置项设置默认值
+func applyDefaults(config *Config) {
+	if config.DataDir == "" {
+		config.DataDir = "data"
+	}
+	if config.History.Days == 0 {
+		config.History.Days = 30
+	}
+	if config.Locale == "" {
+		config.Locale = defaultLocale
+	}
+}
+
 func validateConfig(config *Config) error {
 	if config.CoinGecko.APIKey == "" {
 		return fmt.Errorf("coingecko.api_key is required")
@@ -73,29 +308,136 @@ func validateConfig(config *Config) error {
 
 	// 如果配置了邮件，验证邮件配置完整性
 	if hasEmail {
-		if config.Email.SMTPPort == 0 {
-			return fmt.Errorf("email.smtp_port is required")
+		if isSMTPTransport(config) {
+			if err := validateSMTPConfig(config); err != nil {
+				return err
+			}
+		} else {
+			if _, err := emailTransport(config, nil); err != nil {
+				return err
+			}
+			if config.Email.From == "" && config.Email.Username == "" {
+				return fmt.Errorf("email.from is required when email.transport is %s", config.Email.Transport)
+			}
 		}
-		if config.Email.Username == "" {
-			return fmt.Errorf("email.username is required")
+		if len(config.Email.To) == 0 && len(config.Email.Recipients) == 0 {
+			return fmt.Errorf("email.to is required (at least one recipient)")
 		}
-		if config.Email.Password == "" {
-			return fmt.Errorf("email.password is required")
+		if config.Email.From != "" {
+			if _, err := mail.ParseAddress(config.Email.From); err != nil {
+				return fmt.Errorf("email.from is not a valid address: %w", err)
+			}
 		}
-		if len(config.Email.To) == 0 {
-			return fmt.Errorf("email.to is required (at least one recipient)")
+		if config.Email.ReplyTo != "" {
+			if _, err := mail.ParseAddress(config.Email.ReplyTo); err != nil {
+				return fmt.Errorf("email.reply_to is not a valid address: %w", err)
+			}
+		}
+		if dkim := config.Email.DKIM; dkim.Domain != "" || dkim.Selector != "" || dkim.PrivateKeyFile != "" {
+			if dkim.PrivateKeyFile == "" {
+				return fmt.Errorf("email.dkim.private_key_file is required")
+			}
+			if _, err := LoadDKIMSigner(dkim.Domain, dkim.Selector, dkim.PrivateKeyFile); err != nil {
+				return fmt.Errorf("email.dkim: %w", err)
+			}
+		}
+		switch config.Email.TransferEncoding {
+		case "", encodingQuotedPrintable, encodingBase64:
+		default:
+			return fmt.Errorf("email.transfer_encoding must be quoted-printable or base64")
+		}
+		switch config.Email.Attachments.Data {
+		case "", exportCSV, exportXLSX:
+		default:
+			return fmt.Errorf("email.attachments.data must be csv or xlsx")
+		}
+		switch config.Email.Delivery {
+		case "", deliveryTo, deliveryIndividual, deliveryBcc:
+		default:
+			return fmt.Errorf("email.delivery must be one of to, individual, bcc")
+		}
+		for i, recipient := range config.Email.Recipients {
+			if recipient.Address == "" {
+				return fmt.Errorf("email.recipients[%d].address is required", i)
+			}
+			if _, err := mail.ParseAddress(recipient.Address); err != nil {
+				return fmt.Errorf("email.recipients[%d].address is not a valid address: %w", i, err)
+			}
+			if _, err := LookupLocale(recipient.Locale); err != nil {
+				return fmt.Errorf("email.recipients[%d].locale: %w", i, err)
+			}
+			for _, coin := range recipient.Coins {
+				if coin == "" {
+					return fmt.Errorf("email.recipients[%d].coins cannot contain empty coin IDs", i)
+				}
+			}
 		}
 	}
 
 	// 如果配置了 Discord，验证 Discord 配置完整性
+	// 只配置 bot_token 时需要有按 channel_id 发送的目标，或者启用了斜杠命令
 	if config.Discord.BotToken != "" || config.Discord.ChannelID != "" {
 		if config.Discord.BotToken == "" {
 			return fmt.Errorf("discord.bot_token is required when discord is configured")
 		}
-		if config.Discord.ChannelID == "" {
+		if config.Discord.ChannelID == "" && !hasDiscordChannelTarget(config) && !config.Discord.Commands.Enabled && config.Discord.Board.ChannelID == "" {
 			return fmt.Errorf("discord.channel_id is required when discord is configured")
 		}
 	}
+	if config.Discord.WebhookURL != "" {
+		if u, err := url.Parse(config.Discord.WebhookURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("discord.webhook_url must be an http:// or https:// URL")
+		}
+	} else if config.Discord.Username != "" || config.Discord.AvatarURL != "" || config.Discord.ThreadID != "" {
+		return fmt.Errorf("discord.username, avatar_url and thread_id require discord.webhook_url")
+	}
+	if err := validateDiscordPost(config.Discord.PostMode, config.Discord.ForumTags, config.Discord.AutoArchive, config.Discord.WebhookURL != "", config.Discord.ThreadID); err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+	for i, target := range config.Discord.Targets {
+		if err := validateDiscordTarget(config, target); err != nil {
+			return fmt.Errorf("discord.targets[%d]: %w", i, err)
+		}
+	}
+	if board := config.Discord.Board; board.Enabled {
+		if config.Discord.BotToken == "" {
+			return fmt.Errorf("discord.board requires discord.bot_token")
+		}
+		if board.ChannelID == "" && config.Discord.ChannelID == "" {
+			return fmt.Errorf("discord.board.channel_id is required when discord.channel_id is not set")
+		}
+		if board.Interval != 0 && board.Interval < time.Minute {
+			return fmt.Errorf("discord.board.interval must be at least 1m")
+		}
+		switch board.Style {
+		case "", discordStyleFull, discordStyleCompact:
+		default:
+			return fmt.Errorf("discord.board.style must be full or compact")
+		}
+		for _, coin := range board.Coins {
+			if coin == "" {
+				return fmt.Errorf("discord.board.coins cannot contain empty coin IDs")
+			}
+		}
+	}
+	if config.Discord.Commands.Enabled && config.Discord.BotToken == "" {
+		return fmt.Errorf("discord.commands requires discord.bot_token")
+	}
+	if config.Discord.Commands.CacheTTL < 0 {
+		return fmt.Errorf("discord.commands.cache_ttl cannot be negative")
+	}
+	switch config.Discord.Commands.Mode {
+	case "", commandModeGateway:
+	case commandModeHTTP:
+		if _, err := parseInteractionPublicKey(config.Discord.Commands.PublicKey); err != nil {
+			return fmt.Errorf("discord.commands.%w", err)
+		}
+		if path := config.Discord.Commands.Path; path != "" && !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("discord.commands.path must start with /")
+		}
+	default:
+		return fmt.Errorf("discord.commands.mode must be gateway or http")
+	}
 
 	if len(config.Coins) == 0 {
 		return fmt.Errorf("at least one coin must be specified")
@@ -106,16 +448,391 @@ func validateConfig(config *Config) error {
 	if config.Schedule.Minute < 0 || config.Schedule.Minute > 59 {
 		return fmt.Errorf("schedule.minute must be between 0 and 59")
 	}
+	if _, err := LookupLocale(config.Locale); err != nil {
+		return fmt.Errorf("locale: %w", err)
+	}
+	if _, err := LookupLocale(config.Email.Locale); err != nil {
+		return fmt.Errorf("email.locale: %w", err)
+	}
+	if _, err := LookupLocale(config.Discord.Locale); err != nil {
+		return fmt.Errorf("discord.locale: %w", err)
+	}
+	if err := validateReportLayout(config); err != nil {
+		return err
+	}
+	if config.Proxy.Enabled {
+		if _, err := NewProxyDialer(config.Proxy.URL); err != nil {
+			return fmt.Errorf("proxy.url: %w", err)
+		}
+	}
+	for _, override := range []struct{ name, value string }{
+		{"proxy.coingecko", config.Proxy.CoinGecko},
+		{"proxy.discord", config.Proxy.Discord},
+		{"proxy.email", config.Proxy.Email},
+	} {
+		if override.value == directProxy {
+			continue
+		}
+		if _, err := NewProxyDialer(override.value); err != nil {
+			return fmt.Errorf("%s: %w", override.name, err)
+		}
+	}
+	if config.Email.Retry.MaxAttempts < 0 || config.Email.Retry.InitialBackoff < 0 || config.Email.Retry.MaxBackoff < 0 {
+		return fmt.Errorf("email.retry values must not be negative")
+	}
+	if config.History.Days < 0 {
+		return fmt.Errorf("history.days must not be negative")
+	}
+
+	if _, err := NewReportGeneratorFromConfig(config); err != nil {
+		return fmt.Errorf("templates: %w", err)
+	}
 
 	return nil
 }
 
 // isEmailConfigured 检查邮件配置是否存在
 func isEmailConfigured(config *Config) bool {
-	return config.Email.SMTPServer != ""
+	return config.Email.SMTPServer != "" || !isSMTPTransport(config)
+}
+
+// isSMTPTransport 判断邮件是否通过 SMTP 服务器投递
+func isSMTPTransport(config *Config) bool {
+	return config.Email.Transport == "" || config.Email.Transport == transportSMTP
+}
+
+// validateSMTPConfig 校验 SMTP 服务器、认证和 TLS 配置
+func validateSMTPConfig(config *Config) error {
+	if config.Email.SMTPPort == 0 {
+		return fmt.Errorf("email.smtp_port is required")
+	}
+	if config.Email.Username == "" {
+		return fmt.Errorf("email.username is required")
+	}
+	switch config.Email.Auth {
+	case "", authPlain:
+		if config.Email.Password == "" {
+			return fmt.Errorf("email.password is required")
+		}
+	case authXOAuth2:
+		if _, err := emailOAuth2Config(config); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("email.auth must be plain or xoauth2")
+	}
+	switch config.Email.TLS {
+	case "", tlsModeSTARTTLS, tlsModeImplicit, tlsModeNone:
+	default:
+		return fmt.Errorf("email.tls must be one of starttls, implicit, none")
+	}
+	if _, err := newTLSConfig(config.Email.SMTPServer, config.Email.TLSCAFile, config.Email.TLSMinVersion, false); err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	return nil
+}
+
+// emailOAuth2Config 返回 XOAUTH2 认证的令牌配置，未填写的 token_url 和 scope 按服务商推导
+func emailOAuth2Config(config *Config) (OAuth2Config, error) {
+	oauth := config.Email.OAuth2
+	tokenURL, scope, err := oauth2Defaults(oauth.Provider, oauth.Tenant)
+	if err != nil {
+		return OAuth2Config{}, fmt.Errorf("email.oauth2.provider: %w", err)
+	}
+
+	result := OAuth2Config{
+		TokenURL:     firstNonEmpty(oauth.TokenURL, tokenURL),
+		ClientID:     oauth.ClientID,
+		ClientSecret: oauth.ClientSecret,
+		RefreshToken: oauth.RefreshToken,
+		Scope:        firstNonEmpty(oauth.Scope, scope),
+	}
+	switch {
+	case result.TokenURL == "":
+		return OAuth2Config{}, fmt.Errorf("email.oauth2.token_url or email.oauth2.provider is required")
+	case result.ClientID == "":
+		return OAuth2Config{}, fmt.Errorf("email.oauth2.client_id is required")
+	case result.RefreshToken == "":
+		return OAuth2Config{}, fmt.Errorf("email.oauth2.refresh_token is required")
+	}
+	if _, err := url.ParseRequestURI(result.TokenURL); err != nil {
+		return OAuth2Config{}, fmt.Errorf("email.oauth2.token_url is invalid: %w", err)
+	}
+	return result, nil
+}
+
+// emailRecipientGroup 表示收到同一份报表的一组收件人
+type emailRecipientGroup struct {
+	Locale string
+	Coins  []string // 报表包含的币种，为空时使用 coins 配置
+	Name   string   // 收件人称呼，只在 individual 模式下设置
+	To     []string
+}
+
+// emailRecipientGroups 按语言区域和币种对收件人分组，保持配置中的顺序
+// 语言区域优先级：收件人 locale > email.locale > 全局 locale
+// individual 模式下每个收件人单独成组
+func emailRecipientGroups(config *Config) []emailRecipientGroup {
+	channelLocale := firstNonEmpty(config.Email.Locale, config.Locale, defaultLocale)
+	individual := config.Email.Delivery == deliveryIndividual
+
+	var groups []emailRecipientGroup
+	add := func(recipient EmailRecipient) {
+		locale := firstNonEmpty(recipient.Locale, channelLocale)
+		if individual {
+			groups = append(groups, emailRecipientGroup{
+				Locale: locale,
+				Coins:  recipient.Coins,
+				Name:   recipient.Name,
+				To:     []string{recipient.Address},
+			})
+			return
+		}
+		for i := range groups {
+			if groups[i].Locale == locale && sameStrings(groups[i].Coins, recipient.Coins) {
+				groups[i].To = append(groups[i].To, recipient.Address)
+				return
+			}
+		}
+		groups = append(groups, emailRecipientGroup{Locale: locale, Coins: recipient.Coins, To: []string{recipient.Address}})
+	}
+
+	for _, address := range config.Email.To {
+		add(EmailRecipient{Address: address})
+	}
+	for _, recipient := range config.Email.Recipients {
+		add(recipient)
+	}
+	return groups
+}
+
+// reportCoinIDs 返回需要获取价格的全部币种：coins 配置加上收件人和 Discord 目标单独关注的币种
+func reportCoinIDs(config *Config) []string {
+	ids := append([]string(nil), config.Coins...)
+	add := func(coins []string) {
+		for _, id := range coins {
+			if !containsString(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	for _, recipient := range config.Email.Recipients {
+		add(recipient.Coins)
+	}
+	for _, target := range config.Discord.Targets {
+		add(target.Coins)
+	}
+	return ids
+}
+
+// sameStrings 判断两个字符串列表是否相同
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// emailAddresses 返回所有收件人地址
+func emailAddresses(config *Config) []string {
+	var addresses []string
+	for _, group := range emailRecipientGroups(config) {
+		addresses = append(addresses, group.To...)
+	}
+	return addresses
+}
+
+// discordLocale 返回 Discord 报表使用的语言区域
+func discordLocale(config *Config) string {
+	return firstNonEmpty(config.Discord.Locale, config.Locale, defaultLocale)
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// historyPath 返回历史快照文件路径
+func historyPath(config *Config) string {
+	return filepath.Join(config.DataDir, "history.json")
+}
+
+// watchlistPath 返回斜杠命令维护的各服务器关注列表文件
+func watchlistPath(config *Config) string {
+	return filepath.Join(config.DataDir, "watchlists.json")
+}
+
+// boardChannelID 返回实时看板所在的频道，未单独配置时使用 discord.channel_id
+func boardChannelID(config *Config) string {
+	return firstNonEmpty(config.Discord.Board.ChannelID, config.Discord.ChannelID)
+}
+
+// boardStatePath 返回实时看板状态文件，记录看板消息的 ID
+func boardStatePath(config *Config) string {
+	return filepath.Join(config.DataDir, "board.json")
+}
+
+// outboxPath 返回发件箱目录
+func outboxPath(config *Config) string {
+	return filepath.Join(config.DataDir, "outbox")
+}
+
+// newEmailOutbox 根据配置创建发件箱
+func newEmailOutbox(config *Config) *Outbox {
+	return NewOutbox(outboxPath(config), RetryPolicy{
+		MaxAttempts:    config.Email.Retry.MaxAttempts,
+		InitialBackoff: config.Email.Retry.InitialBackoff,
+		MaxBackoff:     config.Email.Retry.MaxBackoff,
+	})
 }
 
 // isDiscordConfigured 检查 Discord 配置是否完整
 func isDiscordConfigured(config *Config) bool {
-	return config.Discord.BotToken != "" && config.Discord.ChannelID != ""
-}
\ No newline at end of file
+	return config.Discord.WebhookURL != "" || (config.Discord.BotToken != "" && config.Discord.ChannelID != "") ||
+		len(config.Discord.Targets) > 0
+}
+
+// 单个 Discord 目标的报表样式
+const (
+	discordStyleFull    = "full"
+	discordStyleCompact = "compact"
+)
+
+// validateDiscordTarget 校验单个 Discord 目标
+func validateDiscordTarget(config *Config, target DiscordTarget) error {
+	switch {
+	case target.ChannelID != "" && target.WebhookURL != "":
+		return fmt.Errorf("channel_id and webhook_url cannot both be set")
+	case target.ChannelID != "":
+		if config.Discord.BotToken == "" {
+			return fmt.Errorf("discord.bot_token is required for channel_id targets")
+		}
+		if target.Username != "" || target.AvatarURL != "" || target.ThreadID != "" {
+			return fmt.Errorf("username, avatar_url and thread_id require webhook_url")
+		}
+	case target.WebhookURL != "":
+		if u, err := url.Parse(target.WebhookURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("webhook_url must be an http:// or https:// URL")
+		}
+	default:
+		return fmt.Errorf("channel_id or webhook_url is required")
+	}
+
+	switch target.Style {
+	case "", discordStyleFull, discordStyleCompact:
+	default:
+		return fmt.Errorf("style must be full or compact")
+	}
+	if _, err := LookupLocale(target.Locale); err != nil {
+		return fmt.Errorf("locale: %w", err)
+	}
+	for _, coin := range target.Coins {
+		if coin == "" {
+			return fmt.Errorf("coins cannot contain empty coin IDs")
+		}
+	}
+	if schedule := target.Schedule; schedule != nil {
+		if schedule.Hour < 0 || schedule.Hour > 23 || schedule.Minute < 0 || schedule.Minute > 59 {
+			return fmt.Errorf("schedule must be a valid time (hour 0-23, minute 0-59)")
+		}
+	}
+	post := discordPostOptions(config, target)
+	return validateDiscordPost(post.Mode, post.Tags, post.AutoArchive, target.WebhookURL != "", target.ThreadID)
+}
+
+// validateDiscordPost 校验报表的发送方式，webhook 表示是否通过 webhook 发送
+func validateDiscordPost(mode string, tags []string, autoArchive time.Duration, webhook bool, threadID string) error {
+	switch mode {
+	case "", discordPostMessage:
+		return nil
+	case discordPostThread:
+		if webhook {
+			return fmt.Errorf("post_mode thread requires channel_id, webhooks cannot start threads")
+		}
+	case discordPostForum:
+	default:
+		return fmt.Errorf("post_mode must be message, thread or forum")
+	}
+
+	if threadID != "" {
+		return fmt.Errorf("thread_id cannot be used with post_mode %s", mode)
+	}
+	for _, tag := range tags {
+		if tag == "" {
+			return fmt.Errorf("forum_tags cannot contain empty tags")
+		}
+		if webhook && !isDiscordID(tag) {
+			return fmt.Errorf("forum_tags must be tag IDs when using webhook_url, got %q", tag)
+		}
+	}
+	if autoArchive != 0 {
+		valid := false
+		for _, d := range discordAutoArchiveDurations {
+			valid = valid || autoArchive == d
+		}
+		if !valid {
+			return fmt.Errorf("auto_archive must be 1h, 24h, 72h or 168h")
+		}
+	}
+	return nil
+}
+
+// discordPostOptions 返回目标的发送方式，未单独设置的项使用 discord 中的配置
+func discordPostOptions(config *Config, target DiscordTarget) DiscordPostOptions {
+	options := DiscordPostOptions{
+		Mode:        firstNonEmpty(target.PostMode, config.Discord.PostMode, discordPostMessage),
+		Tags:        target.ForumTags,
+		AutoArchive: target.AutoArchive,
+	}
+	if len(options.Tags) == 0 {
+		options.Tags = config.Discord.ForumTags
+	}
+	if options.AutoArchive == 0 {
+		options.AutoArchive = config.Discord.AutoArchive
+	}
+	return options
+}
+
+// hasDiscordChannelTarget 判断 targets 中是否有按 channel_id 发送的目标
+func hasDiscordChannelTarget(config *Config) bool {
+	for _, target := range config.Discord.Targets {
+		if target.ChannelID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// discordTargets 返回全部 Discord 发送目标，顶层的 webhook_url 或 channel_id 作为第一个目标
+// 目标的语言区域优先级：目标 locale > discord.locale > 全局 locale
+func discordTargets(config *Config) []DiscordTarget {
+	var targets []DiscordTarget
+	switch {
+	case config.Discord.WebhookURL != "":
+		targets = append(targets, DiscordTarget{
+			WebhookURL: config.Discord.WebhookURL,
+			Username:   config.Discord.Username,
+			AvatarURL:  config.Discord.AvatarURL,
+			ThreadID:   config.Discord.ThreadID,
+		})
+	case config.Discord.BotToken != "" && config.Discord.ChannelID != "":
+		targets = append(targets, DiscordTarget{ChannelID: config.Discord.ChannelID})
+	}
+	targets = append(targets, config.Discord.Targets...)
+
+	for i := range targets {
+		targets[i].Locale = firstNonEmpty(targets[i].Locale, discordLocale(config))
+		targets[i].Style = firstNonEmpty(targets[i].Style, discordStyleFull)
+	}
+	return targets
+}
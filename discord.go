@@ -3,12 +3,16 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Discord Embed 相关结构体
@@ -37,13 +41,71 @@ type EmbedFooter struct {
 
 // discordMessage 表示发送到 Discord 的消息结构
 type discordMessage struct {
-	Embeds []DiscordEmbed `json:"embeds"`
+	Username  string         `json:"username,omitempty"`   // 仅 webhook 支持
+	AvatarURL string         `json:"avatar_url,omitempty"` // 仅 webhook 支持
+	Embeds    []DiscordEmbed `json:"embeds"`
+	// 以下两项仅用于通过 webhook 在论坛频道中创建帖子
+	ThreadName  string   `json:"thread_name,omitempty"`
+	AppliedTags []string `json:"applied_tags,omitempty"`
 }
 
-// DiscordSender 负责发送 Discord 消息
+// discordMessageResponse 是创建消息后 Discord 返回的消息对象（只解析需要的字段）
+type discordMessageResponse struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+}
+
+// discordThreadRequest 是创建子区或论坛帖子的请求
+type discordThreadRequest struct {
+	Name                string          `json:"name"`
+	AutoArchiveDuration int             `json:"auto_archive_duration,omitempty"` // 单位为分钟
+	AppliedTags         []string        `json:"applied_tags,omitempty"`          // 仅论坛帖子
+	Message             *discordMessage `json:"message,omitempty"`               // 仅论坛帖子，帖子的第一条消息
+}
+
+// discordChannelResponse 是 Discord 返回的频道对象（只解析需要的字段），子区和论坛帖子同样是频道
+type discordChannelResponse struct {
+	ID            string `json:"id"`
+	AvailableTags []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"available_tags"`
+}
+
+// Discord 报表的发送方式
+const (
+	discordPostMessage = "message" // 直接发送到频道（默认）
+	discordPostThread  = "thread"  // 发送到文字频道后以报表消息创建子区，其余部分发送到子区中
+	discordPostForum   = "forum"   // 在论坛频道中创建帖子
+)
+
+// maxThreadNameLength 是子区和论坛帖子标题的最大长度
+const maxThreadNameLength = 100
+
+// discordAutoArchiveDurations 是 Discord 支持的子区自动归档时间
+var discordAutoArchiveDurations = []time.Duration{time.Hour, 24 * time.Hour, 72 * time.Hour, 168 * time.Hour}
+
+// DiscordPostOptions 是报表的发送方式，thread 和 forum 方式每天的报表使用一个以日期命名的子区
+type DiscordPostOptions struct {
+	Mode        string        // message（默认）、thread 或 forum
+	Tags        []string      // 论坛帖子的标签，可以是名称或 ID（webhook 方式只支持 ID）
+	AutoArchive time.Duration // 子区无人发言多久后自动归档（1h、24h、72h 或 168h，默认使用频道设置）
+}
+
+// DiscordWebhook 是 webhook 发送方式的配置
+type DiscordWebhook struct {
+	URL       string // https://discord.com/api/webhooks/{id}/{token}
+	Username  string // 覆盖 webhook 默认的显示名称（可选）
+	AvatarURL string // 覆盖 webhook 默认的头像（可选）
+	ThreadID  string // 发送到 webhook 所在频道中的指定子区（可选）
+}
+
+// DiscordSender 负责发送 Discord 消息，支持 Bot（bot_token + channel_id）和 webhook 两种方式
 type DiscordSender struct {
 	botToken   string
 	channelID  string
+	webhook    DiscordWebhook // URL 非空时通过 webhook 发送
+	post       DiscordPostOptions
 	client     *http.Client
 	apiBaseURL string
 }
@@ -56,19 +118,8 @@ const (
 
 // NewDiscordSender 创建新的 Discord 发送器
 func NewDiscordSender(botToken, channelID string, proxyEnabled bool, proxyURL string) *DiscordSender {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	// 如果启用了代理，配置 HTTP Transport
-	if proxyEnabled && proxyURL != "" {
-		parsedProxyURL, err := url.Parse(proxyURL)
-		if err == nil {
-			client.Transport = &http.Transport{
-				Proxy: http.ProxyURL(parsedProxyURL),
-			}
-		}
-	}
+	// 启用代理时通过 HTTP、HTTPS 或 SOCKS5 代理连接
+	client := newProxyHTTPClient(proxyEnabled, proxyURL, 30*time.Second)
 
 	return &DiscordSender{
 		botToken:   botToken,
@@ -78,90 +129,242 @@ func NewDiscordSender(botToken, channelID string, proxyEnabled bool, proxyURL st
 	}
 }
 
+// NewDiscordWebhookSender 创建通过 webhook 发送的 Discord 发送器，不需要 Bot Token
+func NewDiscordWebhookSender(webhook DiscordWebhook, proxyEnabled bool, proxyURL string) *DiscordSender {
+	return &DiscordSender{
+		webhook: webhook,
+		client:  newProxyHTTPClient(proxyEnabled, proxyURL, 30*time.Second),
+	}
+}
+
+// SetPostOptions 设置报表的发送方式，只影响 SendReport
+func (d *DiscordSender) SetPostOptions(options DiscordPostOptions) {
+	d.post = options
+}
+
 // IsConfigured 检查 Discord 是否已正确配置
 func (d *DiscordSender) IsConfigured() bool {
-	return d.botToken != "" && d.channelID != ""
+	return d.webhook.URL != "" || (d.botToken != "" && d.channelID != "")
 }
 
 // SendEmbed 发送 Discord Embed 消息
 func (d *DiscordSender) SendEmbed(embed *DiscordEmbed) error {
+	_, err := d.PostEmbed(embed)
+	return err
+}
+
+// PostEmbed 发送 Discord Embed 消息并返回消息 ID
+// webhook 方式使用 ?wait=true 等待 Discord 创建消息，因此同样可以拿到消息 ID
+func (d *DiscordSender) PostEmbed(embed *DiscordEmbed) (string, error) {
+	return d.PostEmbeds([]DiscordEmbed{*embed})
+}
+
+// PostEmbeds 在一条消息中发送多个 Embed（最多 10 个）并返回消息 ID
+func (d *DiscordSender) PostEmbeds(embeds []DiscordEmbed) (string, error) {
+	if !d.IsConfigured() {
+		return "", fmt.Errorf("Discord 未配置")
+	}
+
+	var messageID string
+	err := d.retry("发送", func() error {
+		var err error
+		messageID, err = d.doSendEmbeds(embeds)
+		return err
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return messageID, nil
+}
+
+// EditEmbeds 将已发送的消息编辑为新的 Embed
+// 消息已被删除时返回 404 的 *DiscordAPIError（不重试），可以用 isDiscordNotFound 判断
+func (d *DiscordSender) EditEmbeds(messageID string, embeds []DiscordEmbed) error {
 	if !d.IsConfigured() {
 		return fmt.Errorf("Discord 未配置")
 	}
+	requestURL, err := d.messageURL(messageID)
+	if err != nil {
+		return err
+	}
+	return d.retry("编辑", func() error {
+		_, err := d.doRequest(http.MethodPatch, requestURL, d.newMessage(embeds))
+		var apiErr *DiscordAPIError
+		if errors.As(err, &apiErr) {
+			apiErr.Edit = true
+		}
+		return err
+	}, isDiscordNotFound)
+}
+
+// PinMessage 置顶消息，需要 Bot 在频道中有“管理消息”权限，webhook 方式不支持
+func (d *DiscordSender) PinMessage(messageID string) error {
+	if d.webhook.URL != "" || !d.IsConfigured() {
+		return fmt.Errorf("置顶消息需要 Bot 方式（bot_token + channel_id）")
+	}
+	_, err := d.doRequest(http.MethodPut, fmt.Sprintf("%s/channels/%s/pins/%s", d.apiBaseURL, d.channelID, messageID), nil)
+	return err
+}
 
+// retry 执行 op，失败时最多重试 discordMaxRetries 次
+// 认证和权限错误，以及 final 返回 true 的错误不重试
+func (d *DiscordSender) retry(action string, op func() error, final func(error) bool) error {
 	var lastErr error
 	for attempt := 1; attempt <= discordMaxRetries; attempt++ {
-		err := d.doSendEmbed(embed)
+		err := op()
 		if err == nil {
 			return nil
 		}
 		lastErr = err
 
 		// 如果是认证或权限错误，不重试
-		if isDiscordAuthError(err) || isDiscordPermissionError(err) {
+		if isDiscordAuthError(err) || isDiscordPermissionError(err) || (final != nil && final(err)) {
 			return err
 		}
 
 		if attempt < discordMaxRetries {
-			log.Printf("Discord 消息发送失败 (尝试 %d/%d): %v，%v 后重试...",
-				attempt, discordMaxRetries, err, discordRetryInterval)
+			log.Printf("Discord 消息%s失败 (尝试 %d/%d): %v，%v 后重试...",
+				action, attempt, discordMaxRetries, err, discordRetryInterval)
 			time.Sleep(discordRetryInterval)
 		}
 	}
 
-	return fmt.Errorf("Discord 消息发送失败，已重试 %d 次: %w", discordMaxRetries, lastErr)
+	return fmt.Errorf("Discord 消息%s失败，已重试 %d 次: %w", action, discordMaxRetries, lastErr)
 }
 
-// doSendEmbed 执行实际的发送操作
-func (d *DiscordSender) doSendEmbed(embed *DiscordEmbed) error {
-	// 构建消息
+// newMessage 构建消息，webhook 可以覆盖显示名称和头像
+func (d *DiscordSender) newMessage(embeds []DiscordEmbed) discordMessage {
 	message := discordMessage{
-		Embeds: []DiscordEmbed{*embed},
+		Embeds: embeds,
 	}
+	if d.webhook.URL != "" {
+		message.Username = d.webhook.Username
+		message.AvatarURL = d.webhook.AvatarURL
+	}
+	return message
+}
 
-	jsonData, err := json.Marshal(message)
+// doSendEmbeds 执行实际的发送操作，返回消息 ID
+func (d *DiscordSender) doSendEmbeds(embeds []DiscordEmbed) (string, error) {
+	// 构建请求 URL
+	requestURL, err := d.messagesURL()
 	if err != nil {
-		return fmt.Errorf("序列化消息失败: %w", err)
+		return "", err
 	}
 
-	// 构建请求 URL
-	url := fmt.Sprintf("%s/channels/%s/messages", d.apiBaseURL, d.channelID)
+	body, err := d.doRequest(http.MethodPost, requestURL, d.newMessage(embeds))
+	if err != nil {
+		return "", err
+	}
+
+	// 消息已经创建，响应无法解析时只是拿不到消息 ID，不能当作失败重试（否则会重复发送）
+	var created discordMessageResponse
+	json.Unmarshal(body, &created)
+	return created.ID, nil
+}
+
+// doRequest 发送 API 请求并返回响应内容，payload 为 nil 时不带请求体，非 2xx 状态码返回 *DiscordAPIError
+func (d *DiscordSender) doRequest(method, requestURL string, payload interface{}) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("序列化消息失败: %w", err)
+		}
+		reqBody = bytes.NewBuffer(jsonData)
+	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest(method, requestURL, reqBody)
 	if err != nil {
-		return fmt.Errorf("创建请求失败: %w", err)
+		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
-	// 设置请求头
-	req.Header.Set("Authorization", "Bot "+d.botToken)
-	req.Header.Set("Content-Type", "application/json")
+	// 设置请求头，webhook 的 URL 中已经包含令牌
+	if d.webhook.URL == "" {
+		req.Header.Set("Authorization", "Bot "+d.botToken)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	// 发送请求
 	resp, err := d.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("发送请求失败: %w", err)
+		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 检查响应状态
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return &DiscordAPIError{
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &DiscordAPIError{
 			StatusCode: resp.StatusCode,
 			Message:    string(body),
+			Webhook:    d.webhook.URL != "",
 		}
 	}
+	return body, nil
+}
 
-	return nil
+// messageURL 返回编辑指定消息的地址，webhook 方式只能编辑该 webhook 发送的消息
+func (d *DiscordSender) messageURL(messageID string) (string, error) {
+	if d.webhook.URL == "" {
+		return fmt.Sprintf("%s/channels/%s/messages/%s", d.apiBaseURL, d.channelID, messageID), nil
+	}
+
+	u, err := url.Parse(d.webhook.URL)
+	if err != nil {
+		return "", fmt.Errorf("Discord webhook 地址无效: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/messages/" + messageID
+	if d.webhook.ThreadID != "" {
+		query := u.Query()
+		query.Set("thread_id", d.webhook.ThreadID)
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
+
+// messagesURL 返回创建消息的地址
+// webhook 方式加上 wait=true 让 Discord 返回创建的消息，设置了子区时加上 thread_id
+func (d *DiscordSender) messagesURL() (string, error) {
+	if d.webhook.URL == "" {
+		return fmt.Sprintf("%s/channels/%s/messages", d.apiBaseURL, d.channelID), nil
+	}
+
+	u, err := url.Parse(d.webhook.URL)
+	if err != nil {
+		return "", fmt.Errorf("Discord webhook 地址无效: %w", err)
+	}
+	query := u.Query()
+	query.Set("wait", "true")
+	if d.webhook.ThreadID != "" {
+		query.Set("thread_id", d.webhook.ThreadID)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
 }
 
 // DiscordAPIError 表示 Discord API 错误
 type DiscordAPIError struct {
 	StatusCode int
 	Message    string
+	Webhook    bool // 是否通过 webhook 发送，用于给出对应的错误提示
+	Edit       bool // 是否为编辑已发送的消息，此时 404 表示消息不存在
 }
 
 func (e *DiscordAPIError) Error() string {
+	if e.Edit && e.StatusCode == http.StatusNotFound {
+		return "Discord 消息不存在 (404): 消息可能已被删除"
+	}
+	if e.Webhook {
+		switch e.StatusCode {
+		case http.StatusUnauthorized, http.StatusNotFound:
+			return fmt.Sprintf("Discord webhook 无效或已被删除 (%d): 请检查 webhook_url 是否正确", e.StatusCode)
+		case http.StatusBadRequest:
+			return fmt.Sprintf("Discord webhook 请求无效 (400): %s", e.Message)
+		}
+	}
 	switch e.StatusCode {
 	case http.StatusUnauthorized:
 		return fmt.Sprintf("Discord 认证失败 (401): Bot Token 无效或已过期")
@@ -176,14 +379,21 @@ func (e *DiscordAPIError) Error() string {
 	}
 }
 
-// isDiscordAuthError 检查是否是认证错误
+// isDiscordAuthError 检查是否是认证错误，webhook 被删除（404）同样无法通过重试恢复
 func isDiscordAuthError(err error) bool {
 	if apiErr, ok := err.(*DiscordAPIError); ok {
-		return apiErr.StatusCode == http.StatusUnauthorized
+		return apiErr.StatusCode == http.StatusUnauthorized ||
+			(apiErr.Webhook && apiErr.StatusCode == http.StatusNotFound)
 	}
 	return false
 }
 
+// isDiscordNotFound 检查是否是 404 错误（频道、消息或 webhook 不存在）
+func isDiscordNotFound(err error) bool {
+	var apiErr *DiscordAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
 // isDiscordPermissionError 检查是否是权限错误
 func isDiscordPermissionError(err error) bool {
 	if apiErr, ok := err.(*DiscordAPIError); ok {
@@ -193,70 +403,372 @@ func isDiscordPermissionError(err error) bool {
 }
 
 // SendReport 发送加密货币价格报表到 Discord
-func (d *DiscordSender) SendReport(coins []CoinPrice) error {
+// 超过 Discord 长度或数量限制的报表会拆分为多个 Embed 和多条消息，不会丢弃币种
+// thread 和 forum 方式以第一条消息创建子区（标题与邮件主题相同，包含日期），其余消息发送到子区中
+func (d *DiscordSender) SendReport(gen *ReportGenerator, coins []CoinPrice) error {
 	if !d.IsConfigured() {
 		return nil // 未配置时静默跳过
 	}
 
-	gen := NewReportGenerator()
-	embed := gen.GenerateDiscordEmbed(coins)
+	messages := paginateEmbed(gen.GenerateDiscordEmbed(coins))
+	sender, start := d, 0
+	if d.post.Mode == discordPostThread || d.post.Mode == discordPostForum {
+		name := truncateEmbedText(gen.GenerateSubject(coins), maxThreadNameLength)
+		threadID, err := d.createThread(name, messages[0])
+		switch {
+		case err == nil:
+			log.Printf("Discord 子区 ID: %s", threadID)
+			sender, start = d.inThread(threadID), 1
+		case errors.Is(err, errThreadNotCreated):
+			// 报表消息已经发送，只是没能创建子区，其余消息继续发送到频道中
+			log.Printf("%v，其余消息发送到频道中", err)
+			start = 1
+		default:
+			return err
+		}
+	}
+
+	for i := start; i < len(messages); i++ {
+		messageID, err := sender.PostEmbeds(messages[i])
+		if err != nil {
+			if i > 0 {
+				return fmt.Errorf("第 %d/%d 条消息: %w", i+1, len(messages), err)
+			}
+			return err
+		}
+		if messageID != "" {
+			log.Printf("Discord 消息 ID: %s", messageID)
+		}
+	}
+	return nil
+}
+
+// errThreadNotCreated 表示报表的第一条消息已发送，但创建子区失败或没有拿到子区 ID
+var errThreadNotCreated = errors.New("报表消息已发送，但无法创建或获取 Discord 子区")
+
+// createThread 以 embeds 为第一条消息创建子区或论坛帖子，返回子区 ID
+func (d *DiscordSender) createThread(name string, embeds []DiscordEmbed) (string, error) {
+	if d.post.Mode == discordPostForum {
+		return d.createForumPost(name, embeds)
+	}
+
+	// thread 方式需要 Bot：先发送报表消息，再以这条消息创建子区
+	messageID, err := d.PostEmbeds(embeds)
+	if err != nil {
+		return "", err
+	}
+	if messageID == "" {
+		return "", fmt.Errorf("%w: Discord 没有返回消息 ID", errThreadNotCreated)
+	}
+	log.Printf("Discord 消息 ID: %s", messageID)
+	request := discordThreadRequest{Name: name, AutoArchiveDuration: int(d.post.AutoArchive / time.Minute)}
+	requestURL := fmt.Sprintf("%s/channels/%s/messages/%s/threads", d.apiBaseURL, d.channelID, messageID)
+	var thread discordChannelResponse
+	err = d.retry("创建子区", func() error {
+		body, err := d.doRequest(http.MethodPost, requestURL, request)
+		if err != nil {
+			return err
+		}
+		json.Unmarshal(body, &thread)
+		return nil
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errThreadNotCreated, err)
+	}
+	if thread.ID == "" {
+		return "", fmt.Errorf("%w: Discord 没有返回子区 ID", errThreadNotCreated)
+	}
+	return thread.ID, nil
+}
+
+// createForumPost 在论坛频道中创建帖子，返回帖子（子区）ID
+// webhook 方式在消息中带上 thread_name，返回的消息所在频道即为新帖子
+func (d *DiscordSender) createForumPost(name string, embeds []DiscordEmbed) (string, error) {
+	message := d.newMessage(embeds)
+	tags := d.forumTagIDs()
+
+	if d.webhook.URL != "" {
+		message.ThreadName = name
+		message.AppliedTags = tags
+		requestURL, err := d.messagesURL()
+		if err != nil {
+			return "", err
+		}
+		var created discordMessageResponse
+		err = d.retry("创建帖子", func() error {
+			body, err := d.doRequest(http.MethodPost, requestURL, message)
+			if err != nil {
+				return err
+			}
+			// 帖子已经创建，响应无法解析时不能重试（否则会重复发帖）
+			json.Unmarshal(body, &created)
+			return nil
+		}, nil)
+		if err != nil {
+			return "", err
+		}
+		return forumPostID(created.ChannelID)
+	}
+
+	request := discordThreadRequest{
+		Name:                name,
+		AutoArchiveDuration: int(d.post.AutoArchive / time.Minute),
+		AppliedTags:         tags,
+		Message:             &message,
+	}
+	requestURL := fmt.Sprintf("%s/channels/%s/threads", d.apiBaseURL, d.channelID)
+	var thread discordChannelResponse
+	err := d.retry("创建帖子", func() error {
+		body, err := d.doRequest(http.MethodPost, requestURL, request)
+		if err != nil {
+			return err
+		}
+		json.Unmarshal(body, &thread)
+		return nil
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return forumPostID(thread.ID)
+}
+
+// forumPostID 检查 Discord 是否返回了帖子 ID
+// 帖子已经创建，没有 ID 时返回 errThreadNotCreated，其余消息改为发送到频道中
+func forumPostID(id string) (string, error) {
+	if id == "" {
+		return "", fmt.Errorf("%w: 论坛帖子已创建，但 Discord 没有返回帖子 ID", errThreadNotCreated)
+	}
+	return id, nil
+}
+
+// forumTagIDs 返回论坛帖子的标签 ID
+// 标签名称需要通过 Bot 读取论坛频道的可用标签转换为 ID（不区分大小写），找不到的标签只记录日志
+func (d *DiscordSender) forumTagIDs() []string {
+	var ids, names []string
+	for _, tag := range d.post.Tags {
+		if isDiscordID(tag) {
+			ids = append(ids, tag)
+		} else {
+			names = append(names, tag)
+		}
+	}
+	if len(names) == 0 {
+		return ids
+	}
+	if d.webhook.URL != "" {
+		log.Printf("webhook 方式只支持标签 ID，忽略标签: %v", names)
+		return ids
+	}
+
+	body, err := d.doRequest(http.MethodGet, fmt.Sprintf("%s/channels/%s", d.apiBaseURL, d.channelID), nil)
+	var channel discordChannelResponse
+	if err == nil {
+		err = json.Unmarshal(body, &channel)
+	}
+	if err != nil {
+		log.Printf("读取论坛频道的标签失败，忽略标签 %v: %v", names, err)
+		return ids
+	}
+	for _, name := range names {
+		found := false
+		for _, tag := range channel.AvailableTags {
+			if strings.EqualFold(tag.Name, name) {
+				ids = append(ids, tag.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Printf("论坛频道中没有名为 %q 的标签，已忽略", name)
+		}
+	}
+	return ids
+}
 
-	// 检查 Embed 长度限制（Discord 限制为 6000 字符）
-	embed = truncateEmbedIfNeeded(embed)
+// inThread 返回发送到子区 threadID 的发送器
+func (d *DiscordSender) inThread(threadID string) *DiscordSender {
+	sender := *d
+	sender.post = DiscordPostOptions{}
+	if sender.webhook.URL != "" {
+		sender.webhook.ThreadID = threadID
+	} else {
+		sender.channelID = threadID
+	}
+	return &sender
+}
 
-	return d.SendEmbed(embed)
+// isDiscordID 判断是否为 Discord 的 ID（纯数字）
+func isDiscordID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
-// Discord Embed 字符限制
+// Discord 消息的长度和数量限制
+// 参考 https://discord.com/developers/docs/resources/message#embed-object-embed-limits
 const (
-	maxEmbedTotalLength = 6000
-	maxFieldValueLength = 1024
-	maxFieldNameLength  = 256
-	maxTitleLength      = 256
-	maxDescLength       = 4096
+	maxEmbedTotalLength  = 6000 // 一条消息中全部 Embed 的字符总数
+	maxEmbedsPerMessage  = 10
+	maxEmbedFields       = 25
+	maxFieldValueLength  = 1024
+	maxFieldNameLength   = 256
+	maxTitleLength       = 256
+	maxDescLength        = 4096
+	maxFooterLength      = 2048
+	embedTruncatedSuffix = "..."
 )
 
-// truncateEmbedIfNeeded 如果 Embed 超过长度限制，进行截断
-func truncateEmbedIfNeeded(embed *DiscordEmbed) *DiscordEmbed {
-	// 截断标题
-	if len(embed.Title) > maxTitleLength {
-		embed.Title = embed.Title[:maxTitleLength-3] + "..."
+// paginateEmbed 将报表 Embed 拆分为若干条消息，每条消息最多 10 个 Embed
+// 第一个 Embed 保留标题，过长的描述按行拆分到后续 Embed，字段按顺序填入，
+// 每个 Embed 最多 25 个字段，每条消息的字符总数不超过 6000；页脚和时间戳放在最后一个 Embed
+func paginateEmbed(embed *DiscordEmbed) [][]DiscordEmbed {
+	var footer *EmbedFooter
+	reserved := 0
+	if embed.Footer != nil {
+		footer = &EmbedFooter{Text: truncateEmbedText(embed.Footer.Text, maxFooterLength)}
+		reserved = embedTextLength(footer.Text)
+	}
+
+	p := &embedPaginator{color: embed.Color, budget: maxEmbedTotalLength - reserved}
+	chunks := splitEmbedDescription(embed.Description)
+	p.addEmbed(DiscordEmbed{Title: truncateEmbedText(embed.Title, maxTitleLength), Description: chunks[0]})
+	for _, chunk := range chunks[1:] {
+		p.addEmbed(DiscordEmbed{Description: chunk})
+	}
+	for _, field := range embed.Fields {
+		field.Name = truncateEmbedText(field.Name, maxFieldNameLength)
+		field.Value = truncateEmbedText(field.Value, maxFieldValueLength)
+		p.addField(field)
+	}
+
+	last := p.last()
+	last.Footer = footer
+	last.Timestamp = embed.Timestamp
+	return p.messages
+}
+
+// embedPaginator 按 Discord 的限制依次填充 Embed 和消息
+type embedPaginator struct {
+	color    int
+	budget   int // 每条消息可用的字符数（已扣除页脚）
+	messages [][]DiscordEmbed
+	used     int // 当前消息已使用的字符数
+}
+
+// last 返回当前消息的最后一个 Embed
+func (p *embedPaginator) last() *DiscordEmbed {
+	message := p.messages[len(p.messages)-1]
+	return &message[len(message)-1]
+}
+
+// addEmbed 添加一个 Embed，当前消息已满 10 个 Embed 或字符数不够时放入新消息
+func (p *embedPaginator) addEmbed(embed DiscordEmbed) {
+	embed.Color = p.color
+	embed.Fields = []EmbedField{}
+	length := calculateEmbedLength(&embed)
+	if len(p.messages) == 0 || len(p.messages[len(p.messages)-1]) == maxEmbedsPerMessage || p.used+length > p.budget {
+		p.messages = append(p.messages, nil)
+		p.used = 0
 	}
+	p.messages[len(p.messages)-1] = append(p.messages[len(p.messages)-1], embed)
+	p.used += length
+}
 
-	// 截断描述
-	if len(embed.Description) > maxDescLength {
-		embed.Description = embed.Description[:maxDescLength-3] + "..."
+// addField 将字段添加到当前 Embed，字符数不够时放入新消息，当前 Embed 已满 25 个字段时新建 Embed
+func (p *embedPaginator) addField(field EmbedField) {
+	length := embedTextLength(field.Name) + embedTextLength(field.Value)
+	if p.used+length > p.budget {
+		p.messages = append(p.messages, []DiscordEmbed{{Color: p.color, Fields: []EmbedField{}}})
+		p.used = 0
+	} else if len(p.last().Fields) == maxEmbedFields {
+		p.addEmbed(DiscordEmbed{})
 	}
+	last := p.last()
+	last.Fields = append(last.Fields, field)
+	p.used += length
+}
 
-	// 截断字段
-	for i := range embed.Fields {
-		if len(embed.Fields[i].Name) > maxFieldNameLength {
-			embed.Fields[i].Name = embed.Fields[i].Name[:maxFieldNameLength-3] + "..."
+// splitEmbedDescription 按行将描述拆分为不超过 4096 个字符的若干段，至少返回一段
+func splitEmbedDescription(description string) []string {
+	var chunks []string
+	current := ""
+	for _, line := range strings.Split(description, "\n") {
+		line = truncateEmbedText(line, maxDescLength)
+		if current != "" && embedTextLength(current)+1+embedTextLength(line) > maxDescLength {
+			chunks = append(chunks, current)
+			current = line
+			continue
 		}
-		if len(embed.Fields[i].Value) > maxFieldValueLength {
-			embed.Fields[i].Value = embed.Fields[i].Value[:maxFieldValueLength-3] + "..."
+		if current != "" {
+			current += "\n"
 		}
+		current += line
+	}
+	return append(chunks, current)
+}
+
+// embedTextLength 返回文本的字符数，Discord 的长度限制按字符而不是字节计算
+func embedTextLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// truncateEmbedText 将超过 limit 个字符的文本截断并加上省略号
+// 按字符截断，不会切开多字节字符，也不会拆开组合符号、肤色修饰、国旗和 ZWJ 连接的 emoji 序列
+func truncateEmbedText(text string, limit int) string {
+	if embedTextLength(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	cut := limit - embedTextLength(embedTruncatedSuffix)
+	for cut > 0 && splitsGrapheme(runes, cut) {
+		cut--
 	}
+	return string(runes[:cut]) + embedTruncatedSuffix
+}
 
-	// 如果总长度仍然超过限制，移除一些字段
-	for calculateEmbedLength(embed) > maxEmbedTotalLength && len(embed.Fields) > 0 {
-		embed.Fields = embed.Fields[:len(embed.Fields)-1]
-		if len(embed.Fields) > 0 {
-			embed.Fields[len(embed.Fields)-1].Value += "\n... (更多币种已省略)"
+// splitsGrapheme 判断在 runes[i] 之前截断是否会拆开一个用户可见的字符
+func splitsGrapheme(runes []rune, i int) bool {
+	r, prev := runes[i], runes[i-1]
+	switch {
+	case prev == '\u200d', r == '\u200d': // ZWJ 连接的 emoji 序列
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me): // 组合符号
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // 变体选择符
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // 肤色修饰
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // 旗帜标签序列
+		return true
+	case isRegionalIndicator(r) && isRegionalIndicator(prev):
+		// 国旗由两个区域指示符组成，前面连续的区域指示符为奇数个时 prev 和 r 是同一面旗
+		count := 0
+		for j := i - 1; j >= 0 && isRegionalIndicator(runes[j]); j-- {
+			count++
 		}
+		return count%2 == 1
 	}
+	return false
+}
 
-	return embed
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
 }
 
 // calculateEmbedLength 计算 Embed 的总字符数
 func calculateEmbedLength(embed *DiscordEmbed) int {
-	length := len(embed.Title) + len(embed.Description)
+	length := embedTextLength(embed.Title) + embedTextLength(embed.Description)
 	for _, field := range embed.Fields {
-		length += len(field.Name) + len(field.Value)
+		length += embedTextLength(field.Name) + embedTextLength(field.Value)
 	}
 	if embed.Footer != nil {
-		length += len(embed.Footer.Text)
+		length += embedTextLength(embed.Footer.Text)
 	}
 	return length
 }
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 // TestNewDiscordSender 测试 DiscordSender 客户端创建（带代理配置）
@@ -134,3 +139,486 @@ func TestDiscordSenderSendEmbedForbidden(t *testing.T) {
 		t.Error("权限不足时应该返回错误")
 	}
 }
+
+// TestDiscordWebhookSender 测试 webhook 发送：URL 参数、显示名称和头像，以及返回的消息 ID
+func TestDiscordWebhookSender(t *testing.T) {
+	var message discordMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/webhooks/111/secret" {
+			t.Errorf("请求路径错误: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("wait") != "true" || r.URL.Query().Get("thread_id") != "222" {
+			t.Errorf("查询参数错误: %s", r.URL.RawQuery)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("webhook 请求不应该带 Authorization: %s", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		w.Write([]byte(`{"id": "333", "channel_id": "222"}`))
+	}))
+	defer server.Close()
+
+	sender := NewDiscordWebhookSender(DiscordWebhook{
+		URL:       server.URL + "/api/webhooks/111/secret",
+		Username:  "CoinDaily",
+		AvatarURL: "https://example.com/avatar.png",
+		ThreadID:  "222",
+	}, false, "")
+	if !sender.IsConfigured() {
+		t.Fatal("只配置 webhook 时应该视为已配置")
+	}
+
+	messageID, err := sender.PostEmbed(&DiscordEmbed{Title: "测试"})
+	if err != nil {
+		t.Fatalf("PostEmbed 失败: %v", err)
+	}
+	if messageID != "333" {
+		t.Errorf("消息 ID 期望 333，实际为 %q", messageID)
+	}
+	if message.Username != "CoinDaily" || message.AvatarURL != "https://example.com/avatar.png" || len(message.Embeds) != 1 {
+		t.Errorf("消息内容错误: %+v", message)
+	}
+}
+
+// TestDiscordWebhookSenderDeleted 测试 webhook 被删除时不重试，并给出 webhook 相关的提示
+func TestDiscordWebhookSenderDeleted(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Unknown Webhook", "code": 10015}`))
+	}))
+	defer server.Close()
+
+	sender := NewDiscordWebhookSender(DiscordWebhook{URL: server.URL + "/api/webhooks/111/secret"}, false, "")
+	err := sender.SendEmbed(&DiscordEmbed{Title: "测试"})
+	if err == nil || !strings.Contains(err.Error(), "webhook_url") {
+		t.Errorf("应该返回 webhook 相关的错误，实际为 %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("webhook 不存在时不应该重试，实际请求 %d 次", requests)
+	}
+}
+
+// TestDiscordBotMessageID 测试 Bot 方式同样返回消息 ID，且不发送 webhook 专用字段
+func TestDiscordBotMessageID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["username"]; ok {
+			t.Error("Bot 消息不应该包含 username")
+		}
+		w.Write([]byte(`{"id": "444", "channel_id": "123456789"}`))
+	}))
+	defer server.Close()
+
+	sender := NewDiscordSender("test-token", "123456789", false, "")
+	sender.apiBaseURL = server.URL
+	if messageID, err := sender.PostEmbed(&DiscordEmbed{Title: "测试"}); err != nil || messageID != "444" {
+		t.Errorf("PostEmbed = %q, %v，期望 444", messageID, err)
+	}
+}
+
+// checkDiscordLimits 检查拆分后的每条消息都符合 Discord 的限制，返回全部字段
+func checkDiscordLimits(t *testing.T, messages [][]DiscordEmbed) []EmbedField {
+	t.Helper()
+	var fields []EmbedField
+	for i, embeds := range messages {
+		if len(embeds) == 0 || len(embeds) > maxEmbedsPerMessage {
+			t.Errorf("第 %d 条消息有 %d 个 Embed", i+1, len(embeds))
+		}
+		total := 0
+		for _, embed := range embeds {
+			if len(embed.Fields) > maxEmbedFields || embedTextLength(embed.Description) > maxDescLength || embedTextLength(embed.Title) > maxTitleLength {
+				t.Errorf("第 %d 条消息中的 Embed 超过限制: %d 个字段，描述 %d 个字符", i+1, len(embed.Fields), embedTextLength(embed.Description))
+			}
+			if !utf8.ValidString(embed.Title) || !utf8.ValidString(embed.Description) {
+				t.Errorf("第 %d 条消息中的 Embed 包含无效的 UTF-8", i+1)
+			}
+			for _, field := range embed.Fields {
+				if embedTextLength(field.Name) > maxFieldNameLength || embedTextLength(field.Value) > maxFieldValueLength {
+					t.Errorf("字段超过长度限制: %q", field.Name)
+				}
+				if !utf8.ValidString(field.Name) || !utf8.ValidString(field.Value) {
+					t.Errorf("字段包含无效的 UTF-8: %q", field.Name)
+				}
+			}
+			total += calculateEmbedLength(&embed)
+			fields = append(fields, embed.Fields...)
+		}
+		if total > maxEmbedTotalLength {
+			t.Errorf("第 %d 条消息共 %d 个字符，超过 %d", i+1, total, maxEmbedTotalLength)
+		}
+	}
+	return fields
+}
+
+// TestPaginateEmbedManyCoins 测试 40 个币种的报表不丢弃币种，按 25 个字段拆分 Embed
+func TestPaginateEmbedManyCoins(t *testing.T) {
+	var coins []CoinPrice
+	for i := 0; i < 40; i++ {
+		coins = append(coins, CoinPrice{ID: fmt.Sprintf("coin-%d", i), Symbol: fmt.Sprintf("c%d", i), Name: fmt.Sprintf("Coin %d", i), CurrentPrice: float64(i + 1)})
+	}
+	embed := NewReportGenerator().GenerateDiscordEmbed(coins)
+	messages := paginateEmbed(embed)
+
+	fields := checkDiscordLimits(t, messages)
+	if len(fields) != 40 {
+		t.Fatalf("期望保留 40 个字段，实际为 %d", len(fields))
+	}
+	for i, field := range fields {
+		if !strings.Contains(field.Name, fmt.Sprintf("Coin %d", i)) {
+			t.Errorf("第 %d 个字段顺序错误: %q", i, field.Name)
+		}
+	}
+	first := messages[0]
+	if first[0].Title != embed.Title || len(first) < 2 || first[1].Title != "" {
+		t.Error("只有第一个 Embed 应该带标题")
+	}
+
+	last := messages[len(messages)-1]
+	if last[len(last)-1].Footer == nil || last[len(last)-1].Timestamp == "" {
+		t.Error("最后一个 Embed 应该带页脚和时间戳")
+	}
+	if first[0].Footer != nil {
+		t.Error("第一个 Embed 不应该带页脚")
+	}
+}
+
+// TestPaginateEmbedLongFields 测试字段总长度超过 6000 时拆分为多条消息，过长的字段会被截断
+func TestPaginateEmbedLongFields(t *testing.T) {
+	embed := &DiscordEmbed{Title: "报表", Footer: &EmbedFooter{Text: "footer"}}
+	for i := 0; i < 30; i++ {
+		embed.Fields = append(embed.Fields, EmbedField{Name: fmt.Sprintf("field %d", i), Value: strings.Repeat("x", 2000)})
+	}
+	messages := paginateEmbed(embed)
+
+	if len(messages) < 2 {
+		t.Fatalf("应该拆分为多条消息，实际为 %d 条", len(messages))
+	}
+	fields := checkDiscordLimits(t, messages)
+	if len(fields) != 30 || !strings.HasSuffix(fields[0].Value, "...") {
+		t.Errorf("期望保留 30 个截断后的字段，实际为 %d 个", len(fields))
+	}
+}
+
+// TestPaginateEmbedLongDescription 测试 compact 样式的长描述按行拆分到多个 Embed
+func TestPaginateEmbedLongDescription(t *testing.T) {
+	var lines []string
+	for i := 0; i < 300; i++ {
+		lines = append(lines, fmt.Sprintf("**C%d** $%d.00 (+1.00%%)", i, i))
+	}
+	messages := paginateEmbed(&DiscordEmbed{Title: "报表", Description: strings.Join(lines, "\n")})
+	checkDiscordLimits(t, messages)
+
+	var chunks []string
+	for _, embeds := range messages {
+		for _, embed := range embeds {
+			chunks = append(chunks, embed.Description)
+		}
+	}
+	if len(chunks) < 2 || strings.Join(chunks, "\n") != strings.Join(lines, "\n") {
+		t.Errorf("描述应该按行拆分且不丢失内容，实际拆分为 %d 段", len(chunks))
+	}
+}
+
+// TestDiscordSendReportMultipleMessages 测试拆分后的消息按顺序发送
+func TestDiscordSendReportMultipleMessages(t *testing.T) {
+	var received []discordMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message discordMessage
+		json.NewDecoder(r.Body).Decode(&message)
+		received = append(received, message)
+		w.Write([]byte(`{"id": "1"}`))
+	}))
+	defer server.Close()
+
+	var coins []CoinPrice
+	for i := 0; i < 300; i++ {
+		coins = append(coins, CoinPrice{ID: fmt.Sprintf("coin-%d", i), Symbol: fmt.Sprintf("c%d", i), Name: fmt.Sprintf("Coin %d", i)})
+	}
+	sender := NewDiscordWebhookSender(DiscordWebhook{URL: server.URL + "/api/webhooks/1/token"}, false, "")
+	if err := sender.SendReport(NewReportGenerator(), coins); err != nil {
+		t.Fatalf("SendReport 失败: %v", err)
+	}
+
+	if len(received) < 2 {
+		t.Fatalf("300 个币种应该拆分为多条消息，实际为 %d 条", len(received))
+	}
+	var messages [][]DiscordEmbed
+	for _, message := range received {
+		messages = append(messages, message.Embeds)
+	}
+	if fields := checkDiscordLimits(t, messages); len(fields) != 300 {
+		t.Errorf("期望发送 300 个字段，实际为 %d", len(fields))
+	}
+	if received[0].Embeds[0].Title == "" {
+		t.Error("第一条消息应该带标题")
+	}
+}
+
+// TestTruncateEmbedText 测试按字符截断，不会切开中文字符或 emoji 序列
+func TestTruncateEmbedText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{"未超过限制", "比特币", 3, "比特币"},
+		{"中文按字符计数", "比特币以太坊", 5, "比特..."},
+		{"emoji", "🚀🚀🚀🚀🚀", 4, "🚀..."},
+		{"ZWJ 序列", "ab👨‍👩‍👧", 6, "ab..."},
+		{"肤色修饰", "ab👍🏽cde", 6, "ab..."},
+		{"变体选择符", "ab❤️cde", 6, "ab..."},
+		{"组合符号", "abe\u0301cde", 6, "ab..."},
+		{"国旗", "🇨🇳🇺🇸🇯🇵x", 6, "🇨🇳..."},
+	}
+	for _, tt := range tests {
+		got := truncateEmbedText(tt.text, tt.limit)
+		if got != tt.want {
+			t.Errorf("%s: truncateEmbedText(%q, %d) = %q，期望 %q", tt.name, tt.text, tt.limit, got, tt.want)
+		}
+		if !utf8.ValidString(got) || embedTextLength(got) > tt.limit {
+			t.Errorf("%s: 截断结果无效或超过限制: %q", tt.name, got)
+		}
+	}
+}
+
+// TestPaginateEmbedCJKBudget 测试中文和 emoji 按字符计入 6000 的限制，而不是按字节
+func TestPaginateEmbedCJKBudget(t *testing.T) {
+	embed := &DiscordEmbed{Title: "🚀 加密货币价格报表"}
+	for i := 0; i < 20; i++ {
+		// 每个字段 1000 个字符，按字节计算为 3000 多字节
+		embed.Fields = append(embed.Fields, EmbedField{
+			Name:  fmt.Sprintf("币种 %d 🪙", i),
+			Value: strings.Repeat("价", 990),
+		})
+	}
+	embed.Fields[0].Name = strings.Repeat("名", 300)
+	embed.Fields[1].Value = strings.Repeat("🚀", 1100)
+
+	messages := paginateEmbed(embed)
+	fields := checkDiscordLimits(t, messages)
+	if len(fields) != 20 {
+		t.Fatalf("期望保留 20 个字段，实际为 %d", len(fields))
+	}
+	if got := embedTextLength(fields[0].Name); got != maxFieldNameLength {
+		t.Errorf("字段名应该截断为 %d 个字符，实际为 %d", maxFieldNameLength, got)
+	}
+	if got := embedTextLength(fields[1].Value); got != maxFieldValueLength {
+		t.Errorf("字段值应该截断为 %d 个字符，实际为 %d", maxFieldValueLength, got)
+	}
+	// 按字符计算每条消息可以放 5 个字段，按字节计算只能放 1 个
+	if len(messages[0][0].Fields) != 5 {
+		t.Errorf("第一条消息应该包含 5 个字段，实际为 %d", len(messages[0][0].Fields))
+	}
+}
+
+// TestDiscordWebhookEditURL 测试 webhook 方式编辑消息的地址包含消息 ID 和子区
+func TestDiscordWebhookEditURL(t *testing.T) {
+	var path, threadID, method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, threadID = r.Method, r.URL.Path, r.URL.Query().Get("thread_id")
+		w.Write([]byte(`{"id": "555"}`))
+	}))
+	defer server.Close()
+
+	sender := NewDiscordWebhookSender(DiscordWebhook{URL: server.URL + "/api/webhooks/111/secret", ThreadID: "999"}, false, "")
+	if err := sender.EditEmbeds("555", []DiscordEmbed{{Title: "测试"}}); err != nil {
+		t.Fatalf("编辑消息失败: %v", err)
+	}
+	if method != http.MethodPatch || path != "/api/webhooks/111/secret/messages/555" || threadID != "999" {
+		t.Errorf("编辑请求错误: %s %s thread_id=%s", method, path, threadID)
+	}
+}
+
+// manyTestCoins 返回 n 个币种，用于测试拆分为多条消息的报表
+func manyTestCoins(n int) []CoinPrice {
+	var coins []CoinPrice
+	for i := 0; i < n; i++ {
+		coins = append(coins, CoinPrice{ID: fmt.Sprintf("coin-%d", i), Symbol: fmt.Sprintf("c%d", i), Name: fmt.Sprintf("Coin %d", i)})
+	}
+	return coins
+}
+
+// TestDiscordSendReportThread 测试 thread 方式以第一条消息创建以日期命名的子区，其余消息发送到子区中
+func TestDiscordSendReportThread(t *testing.T) {
+	var requests []string
+	var thread discordThreadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/threads") {
+			json.NewDecoder(r.Body).Decode(&thread)
+			w.Write([]byte(`{"id": "777"}`))
+			return
+		}
+		w.Write([]byte(`{"id": "444"}`))
+	}))
+	defer server.Close()
+
+	sender := NewDiscordSender("test-token", "123", false, "")
+	sender.apiBaseURL = server.URL
+	sender.SetPostOptions(DiscordPostOptions{Mode: discordPostThread, AutoArchive: 24 * time.Hour})
+	gen := NewReportGenerator()
+	coins := manyTestCoins(300)
+	if err := sender.SendReport(gen, coins); err != nil {
+		t.Fatalf("SendReport 失败: %v", err)
+	}
+
+	if len(requests) < 3 || requests[0] != "POST /channels/123/messages" || requests[1] != "POST /channels/123/messages/444/threads" {
+		t.Fatalf("应该先发送报表消息再创建子区，实际请求为 %v", requests)
+	}
+	for _, request := range requests[2:] {
+		if request != "POST /channels/777/messages" {
+			t.Errorf("其余消息应该发送到子区中，实际请求为 %s", request)
+		}
+	}
+	if thread.Name != gen.GenerateSubject(coins) || !strings.Contains(thread.Name, gen.Locale().FormatDate(time.Now())) {
+		t.Errorf("子区应该以报表日期命名，实际为 %q", thread.Name)
+	}
+	if thread.AutoArchiveDuration != 1440 {
+		t.Errorf("auto_archive_duration 应该为 1440 分钟，实际为 %d", thread.AutoArchiveDuration)
+	}
+}
+
+// TestDiscordSendReportForum 测试 Bot 方式在论坛频道中发帖，标签名称转换为 ID
+func TestDiscordSendReportForum(t *testing.T) {
+	var requests []string
+	var post discordThreadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /channels/123":
+			w.Write([]byte(`{"id": "123", "available_tags": [{"id": "11", "name": "Daily"}, {"id": "22", "name": "Prices"}]}`))
+		case "POST /channels/123/threads":
+			json.NewDecoder(r.Body).Decode(&post)
+			w.Write([]byte(`{"id": "888"}`))
+		default:
+			w.Write([]byte(`{"id": "444"}`))
+		}
+	}))
+	defer server.Close()
+
+	sender := NewDiscordSender("test-token", "123", false, "")
+	sender.apiBaseURL = server.URL
+	sender.SetPostOptions(DiscordPostOptions{Mode: discordPostForum, Tags: []string{"daily", "33", "missing"}})
+	if err := sender.SendReport(NewReportGenerator(), manyTestCoins(300)); err != nil {
+		t.Fatalf("SendReport 失败: %v", err)
+	}
+
+	if post.Message == nil || len(post.Message.Embeds) == 0 || post.Name == "" {
+		t.Fatalf("论坛帖子应该包含标题和报表的第一条消息: %+v", post)
+	}
+	if strings.Join(post.AppliedTags, ",") != "33,11" {
+		t.Errorf("标签应该为 33,11（找不到的标签忽略），实际为 %v", post.AppliedTags)
+	}
+	for _, request := range requests[2:] {
+		if request != "POST /channels/888/messages" {
+			t.Errorf("其余消息应该发送到帖子中，实际请求为 %s", request)
+		}
+	}
+}
+
+// TestDiscordWebhookSendReportForum 测试 webhook 方式在论坛频道中发帖，其余消息通过 thread_id 发送到帖子中
+func TestDiscordWebhookSendReportForum(t *testing.T) {
+	var received []discordMessage
+	var threadIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message discordMessage
+		json.NewDecoder(r.Body).Decode(&message)
+		received = append(received, message)
+		threadIDs = append(threadIDs, r.URL.Query().Get("thread_id"))
+		w.Write([]byte(`{"id": "1", "channel_id": "999"}`))
+	}))
+	defer server.Close()
+
+	sender := NewDiscordWebhookSender(DiscordWebhook{URL: server.URL + "/api/webhooks/1/token"}, false, "")
+	sender.SetPostOptions(DiscordPostOptions{Mode: discordPostForum, Tags: []string{"11"}})
+	if err := sender.SendReport(NewReportGenerator(), manyTestCoins(300)); err != nil {
+		t.Fatalf("SendReport 失败: %v", err)
+	}
+
+	if len(received) < 2 {
+		t.Fatalf("300 个币种应该拆分为多条消息，实际为 %d 条", len(received))
+	}
+	if received[0].ThreadName == "" || strings.Join(received[0].AppliedTags, ",") != "11" || threadIDs[0] != "" {
+		t.Errorf("第一条消息应该创建帖子: thread_name=%q applied_tags=%v thread_id=%q", received[0].ThreadName, received[0].AppliedTags, threadIDs[0])
+	}
+	for i, message := range received[1:] {
+		if message.ThreadName != "" || threadIDs[i+1] != "999" {
+			t.Errorf("第 %d 条消息应该发送到帖子 999 中，实际 thread_id=%q thread_name=%q", i+2, threadIDs[i+1], message.ThreadName)
+		}
+	}
+}
+
+// TestDiscordSendReportThreadFailed 测试创建子区失败时其余消息继续发送到频道中
+func TestDiscordSendReportThreadFailed(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/threads") {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "Missing Permissions", "code": 50013}`))
+			return
+		}
+		w.Write([]byte(`{"id": "444"}`))
+	}))
+	defer server.Close()
+
+	sender := NewDiscordSender("test-token", "123", false, "")
+	sender.apiBaseURL = server.URL
+	sender.SetPostOptions(DiscordPostOptions{Mode: discordPostThread})
+	if err := sender.SendReport(NewReportGenerator(), manyTestCoins(300)); err != nil {
+		t.Fatalf("创建子区失败时报表仍然应该发送成功: %v", err)
+	}
+	if len(requests) < 3 {
+		t.Fatalf("应该继续发送其余消息，实际请求为 %v", requests)
+	}
+	for _, request := range requests[2:] {
+		if request != "POST /channels/123/messages" {
+			t.Errorf("其余消息应该发送到频道中，实际请求为 %s", request)
+		}
+	}
+}
+
+// TestDiscordSendReportForumWithoutID 测试论坛帖子已创建但响应中没有 ID 时，其余消息仍然发送到频道中
+func TestDiscordSendReportForumWithoutID(t *testing.T) {
+	for _, webhook := range []bool{false, true} {
+		var requests []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.Query().Get("thread_id"))
+			if strings.HasSuffix(r.URL.Path, "/threads") {
+				w.Write([]byte(`{"id": ""}`))
+				return
+			}
+			w.Write([]byte(`{"id": "1", "channel_id": ""}`))
+		}))
+
+		var sender *DiscordSender
+		if webhook {
+			sender = NewDiscordWebhookSender(DiscordWebhook{URL: server.URL + "/api/webhooks/1/token"}, false, "")
+		} else {
+			sender = NewDiscordSender("test-token", "123", false, "")
+			sender.apiBaseURL = server.URL
+		}
+		sender.SetPostOptions(DiscordPostOptions{Mode: discordPostForum})
+		messages := paginateEmbed(NewReportGenerator().GenerateDiscordEmbed(manyTestCoins(300)))
+		if err := sender.SendReport(NewReportGenerator(), manyTestCoins(300)); err != nil {
+			t.Errorf("webhook=%v: 帖子已创建时不应该返回错误: %v", webhook, err)
+		}
+		server.Close()
+
+		if len(requests) != len(messages) {
+			t.Errorf("webhook=%v: 应该发送全部 %d 条消息，实际请求为 %v", webhook, len(messages), requests)
+			continue
+		}
+		for _, request := range requests[1:] {
+			if strings.Contains(request, "/threads") || !strings.HasSuffix(request, "?") {
+				t.Errorf("webhook=%v: 其余消息应该发送到频道中，实际请求为 %s", webhook, request)
+			}
+		}
+	}
+}
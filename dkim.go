@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DKIM 签名算法
+const (
+	dkimRSASHA256     = "rsa-sha256"
+	dkimEd25519SHA256 = "ed25519-sha256" // RFC 8463
+)
+
+// dkimSignedHeaders 是需要签名的邮件头，邮件中不存在的邮件头会被跳过
+var dkimSignedHeaders = []string{
+	"From", "Reply-To", "To", "Subject", "Date", "Message-ID", "List-Id",
+	"MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// dkimSignatureLineLength 是 b= 签名值续行的长度
+const dkimSignatureLineLength = 72
+
+// DKIMSigner 对邮件进行 DKIM 签名（RFC 6376）
+// 邮件头和正文都使用 relaxed 规范化，中继服务器调整空白或折行时签名仍然有效
+type DKIMSigner struct {
+	domain    string
+	selector  string
+	key       crypto.Signer
+	algorithm string
+	now       func() time.Time
+}
+
+// NewDKIMSigner 创建 DKIM 签名器，key 为 *rsa.PrivateKey 或 ed25519.PrivateKey，签名算法由密钥类型决定
+func NewDKIMSigner(domain, selector string, key crypto.Signer) (*DKIMSigner, error) {
+	if domain == "" || selector == "" {
+		return nil, errors.New("DKIM domain and selector are required")
+	}
+
+	var algorithm string
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		// RFC 8301 要求 RSA 密钥至少 1024 位
+		if k.N.BitLen() < 1024 {
+			return nil, fmt.Errorf("DKIM RSA key must be at least 1024 bits, got %d", k.N.BitLen())
+		}
+		algorithm = dkimRSASHA256
+	case ed25519.PrivateKey:
+		algorithm = dkimEd25519SHA256
+	default:
+		return nil, fmt.Errorf("unsupported DKIM key type %T (supported: RSA, Ed25519)", key)
+	}
+
+	return &DKIMSigner{
+		domain:    domain,
+		selector:  selector,
+		key:       key,
+		algorithm: algorithm,
+		now:       time.Now,
+	}, nil
+}
+
+// LoadDKIMSigner 从 PEM 文件加载私钥并创建签名器
+// 支持 PKCS#1（BEGIN RSA PRIVATE KEY）和 PKCS#8（BEGIN PRIVATE KEY）格式
+func LoadDKIMSigner(domain, selector, keyFile string) (*DKIMSigner, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DKIM private key: %w", err)
+	}
+	key, err := parseDKIMKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DKIM private key %s: %w", keyFile, err)
+	}
+	return NewDKIMSigner(domain, selector, key)
+}
+
+// parseDKIMKey 解析 PEM 格式的私钥
+func parseDKIMKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+// Sign 返回在最前面加上 DKIM-Signature 邮件头的邮件，message 的换行必须是 CRLF
+func (s *DKIMSigner) Sign(message []byte) ([]byte, error) {
+	headerEnd := bytes.Index(message, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		return nil, errors.New("message has no header/body separator")
+	}
+	fields := splitHeaderFields(string(message[:headerEnd+2]))
+	bodyHash := sha256.Sum256(canonicalBodyRelaxed(message[headerEnd+4:]))
+
+	// 依次签名存在的邮件头，同名邮件头取最后一个（RFC 6376 5.4.2）
+	hash := sha256.New()
+	var signed []string
+	for _, name := range dkimSignedHeaders {
+		if field, ok := lastHeaderField(fields, name); ok {
+			hash.Write([]byte(canonicalHeaderRelaxed(field)))
+			signed = append(signed, strings.ToLower(name))
+		}
+	}
+	if len(signed) == 0 || signed[0] != "from" {
+		return nil, errors.New("message has no From header")
+	}
+
+	field := foldDKIMTags([]string{
+		"v=1",
+		"a=" + s.algorithm,
+		"c=relaxed/relaxed",
+		"d=" + s.domain,
+		"s=" + s.selector,
+		"t=" + strconv.FormatInt(s.now().Unix(), 10),
+		"h=" + strings.Join(signed, ":"),
+		"bh=" + base64.StdEncoding.EncodeToString(bodyHash[:]),
+		"b=",
+	})
+	// DKIM-Signature 自身以 b= 为空的形式参与签名，且不包含结尾的 CRLF
+	hash.Write([]byte(strings.TrimSuffix(canonicalHeaderRelaxed(field), "\r\n")))
+
+	// Ed25519 对 SHA-256 摘要本身签名（RFC 8463 3），RSA 使用 PKCS#1 v1.5
+	var opts crypto.SignerOpts = crypto.SHA256
+	if s.algorithm == dkimEd25519SHA256 {
+		opts = crypto.Hash(0)
+	}
+	signature, err := s.key.Sign(rand.Reader, hash.Sum(nil), opts)
+	if err != nil {
+		return nil, fmt.Errorf("DKIM signing failed: %w", err)
+	}
+
+	// 签名值先填满 b= 所在的行，其余部分每行 dkimSignatureLineLength 个字符
+	encoded := base64.StdEncoding.EncodeToString(signature)
+	var buf bytes.Buffer
+	buf.WriteString(field)
+	width := headerLineLength - (len(field) - strings.LastIndex(field, "\n") - 1)
+	for len(encoded) > width {
+		buf.WriteString(encoded[:width] + "\r\n\t")
+		encoded = encoded[width:]
+		width = dkimSignatureLineLength
+	}
+	buf.WriteString(encoded + "\r\n")
+	buf.Write(message)
+	return buf.Bytes(), nil
+}
+
+// foldDKIMTags 生成 DKIM-Signature 邮件头（不含结尾的 CRLF），标签之间按行长折行
+func foldDKIMTags(tags []string) string {
+	var b strings.Builder
+	b.WriteString("DKIM-Signature:")
+	lineLength := b.Len()
+	for i, tag := range tags {
+		if i > 0 {
+			b.WriteString(";")
+			lineLength++
+		}
+		if lineLength+1+len(tag) > headerLineLength {
+			b.WriteString("\r\n\t")
+			lineLength = 1
+		} else {
+			b.WriteString(" ")
+			lineLength++
+		}
+		b.WriteString(tag)
+		lineLength += len(tag)
+	}
+	return b.String()
+}
+
+// splitHeaderFields 将邮件头拆分为字段，续行保留在所属字段中，每个字段不含结尾的 CRLF
+func splitHeaderFields(header string) []string {
+	var fields []string
+	for _, line := range strings.SplitAfter(header, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += "\r\n" + strings.TrimSuffix(line, "\r\n")
+			continue
+		}
+		fields = append(fields, strings.TrimSuffix(line, "\r\n"))
+	}
+	return fields
+}
+
+// lastHeaderField 返回最后一个指定名称的字段，名称不区分大小写
+func lastHeaderField(fields []string, name string) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		fieldName, _, ok := strings.Cut(fields[i], ":")
+		if ok && strings.EqualFold(strings.TrimRight(fieldName, " \t"), name) {
+			return fields[i], true
+		}
+	}
+	return "", false
+}
+
+// canonicalHeaderRelaxed 按 relaxed 算法规范化邮件头字段（RFC 6376 3.4.2），结果以 CRLF 结尾
+func canonicalHeaderRelaxed(field string) string {
+	name, value, _ := strings.Cut(field, ":")
+	name = strings.ToLower(strings.TrimRight(name, " \t"))
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = strings.Join(strings.FieldsFunc(value, isWSP), " ")
+	return name + ":" + value + "\r\n"
+}
+
+// canonicalBodyRelaxed 按 relaxed 算法规范化邮件正文（RFC 6376 3.4.4）
+func canonicalBodyRelaxed(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		var b strings.Builder
+		inWSP := false
+		for _, r := range line {
+			if isWSP(r) {
+				inWSP = true
+				continue
+			}
+			if inWSP {
+				b.WriteByte(' ')
+				inWSP = false
+			}
+			b.WriteRune(r)
+		}
+		lines[i] = b.String()
+	}
+
+	// 删除末尾的空行，非空正文以 CRLF 结尾
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dkimSignatureB 匹配 DKIM-Signature 中 b= 标签的值（不包括 bh=）
+var dkimSignatureB = regexp.MustCompile(`(^|;)(\s*b\s*=)[^;]*`)
+
+// verifyDKIM 按 RFC 6376 第 6 节校验邮件中的第一个 DKIM-Signature，只支持 relaxed/relaxed
+// 独立于 DKIMSigner 实现：按 h= 从下往上选取邮件头，重复或不存在的邮件头按空处理
+func verifyDKIM(message []byte, publicKey crypto.PublicKey) error {
+	headerEnd := bytes.Index(message, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		return errors.New("no header/body separator")
+	}
+	fields := splitHeaderFields(string(message[:headerEnd+2]))
+
+	signatureIndex := -1
+	for i, field := range fields {
+		if strings.HasPrefix(strings.ToLower(field), "dkim-signature:") {
+			signatureIndex = i
+			break
+		}
+	}
+	if signatureIndex < 0 {
+		return errors.New("no DKIM-Signature header")
+	}
+	signatureField := fields[signatureIndex]
+
+	tags := make(map[string]string)
+	_, value, _ := strings.Cut(signatureField, ":")
+	for _, tag := range strings.Split(value, ";") {
+		name, tagValue, ok := strings.Cut(tag, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.TrimSpace(name)] = strings.Join(strings.Fields(tagValue), "")
+	}
+	if tags["c"] != "relaxed/relaxed" {
+		return fmt.Errorf("unsupported canonicalization %q", tags["c"])
+	}
+
+	bodyHash := sha256.Sum256(canonicalBodyRelaxed(message[headerEnd+4:]))
+	if base64.StdEncoding.EncodeToString(bodyHash[:]) != tags["bh"] {
+		return errors.New("body hash mismatch")
+	}
+
+	hash := sha256.New()
+	used := make(map[int]bool)
+	for _, name := range strings.Split(tags["h"], ":") {
+		name = strings.TrimSpace(name)
+		for i := len(fields) - 1; i >= 0; i-- {
+			fieldName, _, _ := strings.Cut(fields[i], ":")
+			if !used[i] && i != signatureIndex && strings.EqualFold(strings.TrimSpace(fieldName), name) {
+				used[i] = true
+				hash.Write([]byte(canonicalHeaderRelaxed(fields[i])))
+				break
+			}
+		}
+	}
+	withoutB := dkimSignatureB.ReplaceAllString(signatureField, "$1$2")
+	hash.Write([]byte(strings.TrimSuffix(canonicalHeaderRelaxed(withoutB), "\r\n")))
+	digest := hash.Sum(nil)
+
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return fmt.Errorf("invalid b= tag: %w", err)
+	}
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if tags["a"] != dkimRSASHA256 {
+			return fmt.Errorf("unexpected algorithm %q", tags["a"])
+		}
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature)
+	case ed25519.PublicKey:
+		if tags["a"] != dkimEd25519SHA256 {
+			return fmt.Errorf("unexpected algorithm %q", tags["a"])
+		}
+		if !ed25519.Verify(key, digest, signature) {
+			return errors.New("ed25519 signature mismatch")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key %T", publicKey)
+	}
+}
+
+// testDKIMSigner 使用 testdata 中的固定密钥创建签名器
+func testDKIMSigner(t *testing.T, keyFile string) *DKIMSigner {
+	t.Helper()
+	signer, err := LoadDKIMSigner("example.com", "coindaily", filepath.Join("testdata", keyFile))
+	if err != nil {
+		t.Fatalf("加载 DKIM 密钥失败: %v", err)
+	}
+	signer.now = func() time.Time { return time.Unix(1760000000, 0) }
+	return signer
+}
+
+// TestDKIMRFC8463Example 使用 RFC 8463 附录 A 的密钥和签名校验规范化与 Ed25519 签名
+func TestDKIMRFC8463Example(t *testing.T) {
+	message := strings.ReplaceAll(`DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;
+ d=football.example.com; i=@football.example.com;
+ q=dns/txt; s=brisbane; t=1528637909; h=from : to :
+ subject : date : message-id : from : subject : date;
+ bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;
+ b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus
+ Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==
+From: Joe SixPack <joe@football.example.com>
+To: Suzie Q <suzie@shopping.example.net>
+Subject: Is dinner ready?
+Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)
+Message-ID: <20030712040037.46341.5F8J@football.example.com>
+
+Hi.
+
+We lost the game.  Are you hungry yet?
+
+Joe.
+`, "\n", "\r\n")
+
+	publicKey, _ := base64.StdEncoding.DecodeString("11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=")
+	if err := verifyDKIM([]byte(message), ed25519.PublicKey(publicKey)); err != nil {
+		t.Fatalf("RFC 8463 示例签名校验失败: %v", err)
+	}
+
+	// 同一个密钥加载自 testdata，确保 PKCS#8 解析得到的是 RFC 中的密钥
+	signer := testDKIMSigner(t, "dkim_ed25519.pem")
+	if !bytes.Equal(signer.key.Public().(ed25519.PublicKey), publicKey) {
+		t.Error("testdata/dkim_ed25519.pem 应该是 RFC 8463 的示例密钥")
+	}
+}
+
+// TestDKIMCanonicalization 测试 RFC 6376 3.4.5 中的规范化示例
+func TestDKIMCanonicalization(t *testing.T) {
+	fields := splitHeaderFields("A: X\r\nB : Y\t\r\n\tZ  \r\n")
+	var got string
+	for _, field := range fields {
+		got += canonicalHeaderRelaxed(field)
+	}
+	if got != "a:X\r\nb:Y Z\r\n" {
+		t.Errorf("邮件头规范化结果为 %q", got)
+	}
+
+	if body := canonicalBodyRelaxed([]byte(" C \r\nD \t E\r\n\r\n\r\n")); string(body) != " C\r\nD E\r\n" {
+		t.Errorf("正文规范化结果为 %q", body)
+	}
+	if body := canonicalBodyRelaxed([]byte("\r\n\r\n")); len(body) != 0 {
+		t.Errorf("空正文规范化后应该为空: %q", body)
+	}
+	if body := canonicalBodyRelaxed([]byte("no newline")); string(body) != "no newline\r\n" {
+		t.Errorf("正文末尾应该补上 CRLF: %q", body)
+	}
+}
+
+// TestDKIMSign 测试 RSA 和 Ed25519 签名可以被校验，且对空白修改不敏感、对内容修改敏感
+func TestDKIMSign(t *testing.T) {
+	builder := testMessageBuilder()
+	builder.ReplyTo = "support@example.com"
+	message, err := builder.Build("<p>价格报表</p>", "价格报表")
+	if err != nil {
+		t.Fatalf("构建邮件失败: %v", err)
+	}
+
+	for _, keyFile := range []string{"dkim_rsa.pem", "dkim_ed25519.pem"} {
+		signer := testDKIMSigner(t, keyFile)
+		signed, err := signer.Sign(message)
+		if err != nil {
+			t.Fatalf("%s: 签名失败: %v", keyFile, err)
+		}
+		if err := verifyDKIM(signed, signer.key.Public()); err != nil {
+			t.Errorf("%s: 签名校验失败: %v", keyFile, err)
+		}
+
+		msg, err := mail.ReadMessage(bytes.NewReader(signed))
+		if err != nil {
+			t.Fatalf("%s: 解析签名后的邮件失败: %v", keyFile, err)
+		}
+		signature := msg.Header.Get("DKIM-Signature")
+		for _, want := range []string{"a=" + signer.algorithm, "d=example.com", "s=coindaily", "t=1760000000",
+			"h=from:reply-to:to:subject:date:message-id:mime-version:content-type"} {
+			if !strings.Contains(signature, want) {
+				t.Errorf("%s: DKIM-Signature 应该包含 %q: %s", keyFile, want, signature)
+			}
+		}
+		for _, line := range strings.Split(string(signed[:bytes.Index(signed, []byte("\r\n\r\n"))]), "\r\n") {
+			if len(line) > headerLineLength {
+				t.Errorf("%s: 邮件头行超过 %d 个字符: %q", keyFile, headerLineLength, line)
+			}
+		}
+
+		// relaxed 规范化允许中继调整空白和折行
+		relaxed := bytes.Replace(signed, []byte("Subject: "), []byte("subject:   "), 1)
+		relaxed = bytes.Replace(relaxed, []byte("</p>\r\n"), []byte("</p> \t \r\n"), 1)
+		relaxed = append(relaxed, "\r\n\r\n"...)
+		if err := verifyDKIM(relaxed, signer.key.Public()); err != nil {
+			t.Errorf("%s: 空白修改后签名应该仍然有效: %v", keyFile, err)
+		}
+
+		// 修改邮件头或正文内容后签名失效
+		tampered := bytes.Replace(signed, []byte("bot@example.com"), []byte("evil@example.com"), 1)
+		if verifyDKIM(tampered, signer.key.Public()) == nil {
+			t.Errorf("%s: 修改发件人后签名应该失效", keyFile)
+		}
+		tampered = bytes.Replace(signed, []byte("<p>=E4"), []byte("<p>=E5"), 1)
+		if bytes.Equal(tampered, signed) || verifyDKIM(tampered, signer.key.Public()) == nil {
+			t.Errorf("%s: 修改正文后签名应该失效", keyFile)
+		}
+	}
+}
+
+// TestLoadDKIMSignerErrors 测试无效的密钥配置
+func TestLoadDKIMSignerErrors(t *testing.T) {
+	if _, err := LoadDKIMSigner("example.com", "coindaily", filepath.Join("testdata", "missing.pem")); err == nil {
+		t.Error("密钥文件不存在时应该返回错误")
+	}
+	if _, err := LoadDKIMSigner("example.com", "", filepath.Join("testdata", "dkim_rsa.pem")); err == nil {
+		t.Error("缺少 selector 时应该返回错误")
+	}
+
+	notKey := filepath.Join(t.TempDir(), "key.pem")
+	writeTestFile(t, notKey, "not a pem file")
+	if _, err := LoadDKIMSigner("example.com", "coindaily", notKey); err == nil {
+		t.Error("无效的 PEM 文件应该返回错误")
+	}
+
+	signer := testDKIMSigner(t, "dkim_rsa.pem")
+	if _, err := signer.Sign([]byte("To: a@test.com\r\n\r\nbody")); err == nil {
+		t.Error("没有 From 邮件头的邮件应该返回错误")
+	}
+}
+
+// TestSendReportDKIM 测试发送的邮件带有可校验的 DKIM 签名
+func TestSendReportDKIM(t *testing.T) {
+	server := &fakeSMTPServer{}
+	server.start(t)
+	config := testEmailConfig(server)
+	config.TLS = tlsModeNone
+	config.DKIMDomain = "example.com"
+	config.DKIMSelector = "coindaily"
+	config.DKIMKeyFile = filepath.Join("testdata", "dkim_ed25519.pem")
+
+	if err := NewEmailSender(config).SendReportTo([]string{"a@test.com"}, "每日报表", "<p>价格</p>", "价格"); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("期望收到 1 封邮件，实际为 %d", len(messages))
+	}
+
+	// 测试服务器按行读取 DATA，换行被转换为 LF
+	data := strings.ReplaceAll(messages[0].Data, "\n", "\r\n")
+	if !strings.HasPrefix(data, "DKIM-Signature: ") {
+		t.Fatal("DKIM-Signature 应该是第一个邮件头")
+	}
+	key := testDKIMSigner(t, "dkim_ed25519.pem").key.Public()
+	if err := verifyDKIM([]byte(data), key); err != nil {
+		t.Errorf("收到的邮件签名校验失败: %v", err)
+	}
+}
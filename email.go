@@ -1,145 +1,234 @@
 package main
 
 import (
-	"bufio"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
+	"net/mail"
 	"net/smtp"
-	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type EmailConfig struct {
-	SMTPServer   string
-	SMTPPort     int
-	Username     string
-	Password     string
-	To           []string
-	ProxyEnabled bool
-	ProxyURL     string
+	SMTPServer       string
+	SMTPPort         int
+	Username         string
+	Password         string
+	Auth             string       // plain（默认）或 xoauth2
+	OAuth2           OAuth2Config // Auth 为 xoauth2 时使用
+	To               []string
+	From             string         // 发件人地址，为空时使用 Username
+	FromName         string         // 发件人显示名称（可选）
+	ReplyTo          string         // 回复地址（可选）
+	ListID           string         // List-Id 邮件头（可选），便于收件人按列表过滤
+	TransferEncoding string         // quoted-printable（默认）或 base64
+	TLS              string         // starttls、implicit 或 none，为空时按端口选择
+	TLSCAFile        string         // 自定义 CA 证书文件（PEM，可选）
+	TLSMinVersion    string         // 最低 TLS 版本，如 "1.2"（可选）
+	TLSSkipVerify    bool           // 跳过证书校验，仅用于测试环境
+	DKIMDomain       string         // DKIM 签名域名（d=）
+	DKIMSelector     string         // DKIM 选择器（s=）
+	DKIMKeyFile      string         // DKIM 私钥文件（PEM），为空时不签名
+	Transport        EmailTransport // HTTP API 等其他投递方式，为 nil 时通过 SMTP 投递
+	ProxyEnabled     bool
+	ProxyURL         string
 }
 
 type EmailSender struct {
 	config EmailConfig
+	tokens *OAuth2TokenSource // 使用 XOAUTH2 认证时的访问令牌来源
 }
 
 func NewEmailSender(config EmailConfig) *EmailSender {
-	return &EmailSender{
+	sender := &EmailSender{
 		config: config,
 	}
+	if config.Auth == authXOAuth2 {
+		// 令牌地址和 SMTP 服务器使用相同的代理
+		client := newProxyHTTPClient(config.ProxyEnabled, config.ProxyURL, 30*time.Second)
+		sender.tokens = NewOAuth2TokenSource(config.OAuth2, client)
+	}
+	return sender
 }
 
 // IsConfigured 检查邮件发送器是否已正确配置
 func (e *EmailSender) IsConfigured() bool {
+	if e.config.Transport != nil {
+		return e.fromAddress() != "" && len(e.config.To) > 0
+	}
 	return e.config.SMTPServer != "" &&
 		e.config.SMTPPort > 0 &&
 		e.config.Username != "" &&
-		e.config.Password != "" &&
+		(e.config.Password != "" || e.tokens != nil) &&
 		len(e.config.To) > 0
 }
 
-// dialWithProxy 通过 HTTP 代理建立 TCP 连接（HTTP CONNECT 隧道）
-func (e *EmailSender) dialWithProxy(targetAddr string) (net.Conn, error) {
-	proxyURL, err := url.Parse(e.config.ProxyURL)
-	if err != nil {
-		return nil, fmt.Errorf("invalid proxy URL: %w", err)
-	}
+func (e *EmailSender) SendReport(subject string, htmlContent string) error {
+	return e.SendReportTo(e.config.To, subject, htmlContent, "")
+}
 
-	proxyAddr := proxyURL.Host
-	if proxyURL.Port() == "" {
-		proxyAddr = proxyURL.Hostname() + ":80"
+// SendReportTo 将报表发送给指定的收件人
+// textContent 非空时发送 multipart/alternative 邮件，同时包含纯文本和 HTML 版本
+func (e *EmailSender) SendReportTo(to []string, subject, htmlContent, textContent string) error {
+	email, err := e.Compose(to, subject, htmlContent, textContent)
+	if err != nil {
+		return err
 	}
+	return e.Deliver(email)
+}
 
-	// 连接到代理服务器
-	conn, err := net.DialTimeout("tcp", proxyAddr, 30*time.Second)
+// Compose 构建待发送的邮件，不进行网络连接
+// to 中的地址可以包含显示名称（如 "Alice <alice@example.com>"），信封中只使用邮箱地址；
+// attachments 为附件和 HTML 中通过 cid: 引用的内嵌图片
+func (e *EmailSender) Compose(to []string, subject, htmlContent, textContent string, attachments ...Attachment) (*OutgoingEmail, error) {
+	envelope, err := envelopeAddresses(to)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to proxy: %w", err)
+		return nil, err
 	}
+	return e.compose(to, envelope, subject, htmlContent, textContent, attachments)
+}
 
-	// 发送 HTTP CONNECT 请求
-	connectReq := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", targetAddr, targetAddr)
-	if _, err := conn.Write([]byte(connectReq)); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to send CONNECT request: %w", err)
+// ComposeBcc 构建密送邮件：收件人只出现在信封中，To 邮件头为 undisclosed-recipients
+func (e *EmailSender) ComposeBcc(bcc []string, subject, htmlContent, textContent string, attachments ...Attachment) (*OutgoingEmail, error) {
+	envelope, err := envelopeAddresses(bcc)
+	if err != nil {
+		return nil, err
 	}
+	return e.compose(nil, envelope, subject, htmlContent, textContent, attachments)
+}
 
-	// 读取代理响应
-	reader := bufio.NewReader(conn)
-	resp, err := reader.ReadString('\n')
+func (e *EmailSender) compose(headerTo, envelope []string, subject, htmlContent, textContent string, attachments []Attachment) (*OutgoingEmail, error) {
+	message, err := e.buildMessage(headerTo, subject, htmlContent, textContent, attachments)
 	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to read proxy response: %w", err)
+		return nil, err
 	}
-
-	// 检查响应状态
-	if !strings.Contains(resp, "200") {
-		conn.Close()
-		return nil, fmt.Errorf("proxy CONNECT failed: %s", strings.TrimSpace(resp))
+	// 签名后的邮件保存到发件箱，重试时无需重新签名
+	if e.config.DKIMKeyFile != "" {
+		signer, err := LoadDKIMSigner(e.config.DKIMDomain, e.config.DKIMSelector, e.config.DKIMKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if message, err = signer.Sign(message); err != nil {
+			return nil, err
+		}
 	}
+	return &OutgoingEmail{
+		From:    e.fromAddress(),
+		To:      envelope,
+		Subject: subject,
+		Message: message,
+	}, nil
+}
 
-	// 读取剩余的响应头（直到空行）
-	for {
-		line, err := reader.ReadString('\n')
+// envelopeAddresses 提取信封使用的邮箱地址
+func envelopeAddresses(addresses []string) ([]string, error) {
+	envelope := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		parsed, err := mail.ParseAddress(address)
 		if err != nil {
-			conn.Close()
-			return nil, fmt.Errorf("failed to read proxy headers: %w", err)
-		}
-		if line == "\r\n" || line == "\n" {
-			break
+			return nil, fmt.Errorf("invalid recipient address %q: %w", address, err)
 		}
+		envelope = append(envelope, parsed.Address)
 	}
+	return envelope, nil
+}
 
-	return conn, nil
+// RecipientResult 是单个收件人的投递结果
+type RecipientResult struct {
+	Address string
+	Err     error // 为 nil 表示服务器已接受
 }
 
-func (e *EmailSender) SendReport(subject string, htmlContent string) error {
-	from := e.config.Username
-	to := e.config.To
+// RecipientError 表示部分或全部收件人被服务器拒绝，被接受的收件人已经收到邮件
+type RecipientError struct {
+	Results []RecipientResult // 按收件人顺序排列，包含被接受的收件人
+}
 
-	headers := make(map[string]string)
-	headers["From"] = from
-	headers["To"] = strings.Join(to, ",")
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=utf-8"
-	headers["Date"] = time.Now().Format(time.RFC1123Z)
+func (e *RecipientError) Error() string {
+	rejected := e.Rejected()
+	parts := make([]string, len(rejected))
+	for i, result := range rejected {
+		parts[i] = result.Address + ": " + result.Err.Error()
+	}
+	return fmt.Sprintf("%d of %d recipients rejected: %s", len(rejected), len(e.Results), strings.Join(parts, "; "))
+}
 
-	message := ""
-	for k, v := range headers {
-		message += fmt.Sprintf("%s: %s\r\n", k, v)
+// Rejected 返回被服务器拒绝的收件人
+func (e *RecipientError) Rejected() []RecipientResult {
+	var rejected []RecipientResult
+	for _, result := range e.Results {
+		if result.Err != nil {
+			rejected = append(rejected, result)
+		}
 	}
-	message += "\r\n" + htmlContent
+	return rejected
+}
 
-	addr := fmt.Sprintf("%s:%d", e.config.SMTPServer, e.config.SMTPPort)
+// Accepted 返回服务器已接受的收件人地址
+func (e *RecipientError) Accepted() []string {
+	var accepted []string
+	for _, result := range e.Results {
+		if result.Err == nil {
+			accepted = append(accepted, result.Address)
+		}
+	}
+	return accepted
+}
 
-	// 根据是否启用代理选择连接方式
-	if e.config.ProxyEnabled && e.config.ProxyURL != "" {
-		return e.sendWithProxy(addr, from, to, []byte(message))
+// Deliver 投递已构建的邮件，配置了 Transport 时交给对应的 HTTP API，否则连接 SMTP 服务器
+// SMTP 服务器拒绝时返回的错误包含 *textproto.Error，可用于区分临时和永久失败；
+// 部分收件人被拒绝时仍投递给其余收件人，并返回 *RecipientError
+func (e *EmailSender) Deliver(email *OutgoingEmail) error {
+	if e.config.Transport != nil {
+		return e.config.Transport.Deliver(email)
 	}
 
-	// 直连模式
-	auth := smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.SMTPServer)
-	err := smtp.SendMail(addr, auth, from, to, []byte(message))
+	addr := net.JoinHostPort(e.config.SMTPServer, strconv.Itoa(e.config.SMTPPort))
+
+	// 根据是否启用代理选择连接方式，两种方式使用相同的 TLS 策略
+	proxyURL := ""
+	if e.config.ProxyEnabled {
+		proxyURL = e.config.ProxyURL
+	}
+	dialer, err := NewProxyDialer(proxyURL)
 	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+		return err
 	}
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer conn.Close()
 
+	if err := e.send(conn, email.From, email.To, email.Message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
 	return nil
 }
 
-// sendWithProxy 通过代理发送邮件
-func (e *EmailSender) sendWithProxy(addr, from string, to []string, msg []byte) error {
-	// 通过代理建立连接
-	conn, err := e.dialWithProxy(addr)
+// send 在已建立的连接上完成一次 SMTP 会话
+func (e *EmailSender) send(conn net.Conn, from string, to []string, msg []byte) error {
+	// 设置连接超时
+	conn.SetDeadline(time.Now().Add(2 * time.Minute))
+
+	tlsConfig, err := e.tlsConfig()
 	if err != nil {
-		return fmt.Errorf("failed to dial via proxy: %w", err)
+		return err
 	}
-	defer conn.Close()
 
-	// 设置连接超时
-	conn.SetDeadline(time.Now().Add(2 * time.Minute))
+	// 隐式 TLS（SMTPS）在 SMTP 会话开始前完成握手
+	mode := e.tlsMode()
+	if mode == tlsModeImplicit {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		conn = tlsConn
+	}
 
 	// 创建 SMTP 客户端
 	client, err := smtp.NewClient(conn, e.config.SMTPServer)
@@ -153,18 +242,29 @@ func (e *EmailSender) sendWithProxy(addr, from string, to []string, msg []byte)
 		return fmt.Errorf("EHLO failed: %w", err)
 	}
 
-	// 启用 STARTTLS
-	tlsConfig := &tls.Config{
-		ServerName: e.config.SMTPServer,
-	}
-	if err := client.StartTLS(tlsConfig); err != nil {
-		return fmt.Errorf("STARTTLS failed: %w", err)
+	// 启用 STARTTLS，服务器不支持时直接失败，避免以明文发送凭据
+	if mode == tlsModeSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("server does not support STARTTLS (set email.tls to implicit for port 465)")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
 	}
 
-	// 认证
-	auth := smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.SMTPServer)
-	if err := client.Auth(auth); err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
+	// 认证，服务器未声明 AUTH 扩展时跳过（如本地中继）
+	if ok, _ := client.Extension("AUTH"); ok {
+		auth, err := e.auth()
+		if err != nil {
+			return err
+		}
+		if err := client.Auth(auth); err != nil {
+			if e.tokens != nil {
+				// 令牌可能已被撤销，下次发送时重新获取
+				e.tokens.Invalidate()
+			}
+			return fmt.Errorf("authentication failed: %w", err)
+		}
 	}
 
 	// 设置发件人
@@ -172,11 +272,20 @@ func (e *EmailSender) sendWithProxy(addr, from string, to []string, msg []byte)
 		return fmt.Errorf("MAIL FROM failed: %w", err)
 	}
 
-	// 设置收件人
+	// 设置收件人，单个收件人被拒绝时继续投递给其余收件人
+	results := make([]RecipientResult, 0, len(to))
+	accepted := 0
 	for _, recipient := range to {
-		if err := client.Rcpt(recipient); err != nil {
-			return fmt.Errorf("RCPT TO failed: %w", err)
+		err := client.Rcpt(recipient)
+		if err != nil {
+			err = fmt.Errorf("RCPT TO failed: %w", err)
+		} else {
+			accepted++
 		}
+		results = append(results, RecipientResult{Address: recipient, Err: err})
+	}
+	if accepted == 0 {
+		return &RecipientError{Results: results}
 	}
 
 	// 发送邮件内容
@@ -191,11 +300,107 @@ func (e *EmailSender) sendWithProxy(addr, from string, to []string, msg []byte)
 		return fmt.Errorf("failed to close writer: %w", err)
 	}
 
-	// 退出
-	if err := client.Quit(); err != nil {
-		// Quit 错误通常可以忽略，邮件已发送
-		return nil
-	}
+	// 退出，Quit 错误通常可以忽略，邮件已发送
+	client.Quit()
 
+	if accepted < len(to) {
+		return &RecipientError{Results: results}
+	}
 	return nil
 }
+
+// auth 返回配置的 SMTP 认证方式
+func (e *EmailSender) auth() (smtp.Auth, error) {
+	if e.tokens == nil {
+		return smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.SMTPServer), nil
+	}
+	token, err := e.tokens.Token()
+	if err != nil {
+		return nil, err
+	}
+	return XOAuth2Auth(e.config.Username, token, e.config.SMTPServer), nil
+}
+
+// fromAddress 返回信封和 From 邮件头使用的发件人地址
+func (e *EmailSender) fromAddress() string {
+	if e.config.From != "" {
+		return e.config.From
+	}
+	return e.config.Username
+}
+
+// buildMessage 构建完整的邮件内容（邮件头和正文）
+func (e *EmailSender) buildMessage(to []string, subject, htmlContent, textContent string, attachments []Attachment) ([]byte, error) {
+	builder := &MessageBuilder{
+		From:             mail.Address{Name: e.config.FromName, Address: e.fromAddress()},
+		To:               to,
+		ReplyTo:          e.config.ReplyTo,
+		ListID:           e.config.ListID,
+		Subject:          subject,
+		TransferEncoding: e.config.TransferEncoding,
+		Attachments:      attachments,
+	}
+	return builder.Build(htmlContent, textContent)
+}
+
+// 邮件服务器的 TLS 模式
+const (
+	tlsModeSTARTTLS = "starttls" // 明文连接后通过 STARTTLS 升级（通常为 587 端口）
+	tlsModeImplicit = "implicit" // 连接建立后立即进行 TLS 握手（SMTPS，通常为 465 端口）
+	tlsModeNone     = "none"     // 不使用 TLS，仅用于本地中继
+)
+
+// tlsVersions 是 tls_min_version 支持的取值
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsMode 返回实际使用的 TLS 模式，未配置时 465 端口使用隐式 TLS，其余端口使用 STARTTLS
+func (e *EmailSender) tlsMode() string {
+	if e.config.TLS != "" {
+		return e.config.TLS
+	}
+	if e.config.SMTPPort == 465 {
+		return tlsModeImplicit
+	}
+	return tlsModeSTARTTLS
+}
+
+// tlsConfig 根据配置创建 TLS 配置
+func (e *EmailSender) tlsConfig() (*tls.Config, error) {
+	return newTLSConfig(e.config.SMTPServer, e.config.TLSCAFile, e.config.TLSMinVersion, e.config.TLSSkipVerify)
+}
+
+// newTLSConfig 创建校验指定服务器证书的 TLS 配置
+func newTLSConfig(serverName, caFile, minVersion string, skipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         serverName,
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: skipVerify,
+	}
+
+	if minVersion != "" {
+		version, ok := tlsVersions[minVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS version %q (supported: 1.0, 1.1, 1.2, 1.3)", minVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
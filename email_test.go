@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// readMessage 解析邮件内容，返回邮件头和各 MIME 部分解码后的内容（按 Content-Type 索引）
+func readMessage(t *testing.T, raw []byte) (mail.Header, map[string]string) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("解析邮件失败: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("解析 Content-Type 失败: %v", err)
+	}
+
+	parts := make(map[string]string)
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		parts[mediaType] = decodePart(t, msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+		return msg.Header, parts
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("读取 MIME 部分失败: %v", err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[partType] = decodePart(t, part.Header.Get("Content-Transfer-Encoding"), part)
+	}
+	return msg.Header, parts
+}
+
+// decodePart 按传输编码解码正文
+func decodePart(t *testing.T, encoding string, r io.Reader) string {
+	t.Helper()
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("读取正文失败: %v", err)
+	}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("编码后的行长度超过 76: %d", len(line))
+		}
+	}
+
+	var decoded io.Reader
+	switch encoding {
+	case "quoted-printable":
+		decoded = quotedprintableReader(raw)
+	case "base64":
+		decoded = base64Reader(raw)
+	default:
+		t.Fatalf("未知的传输编码: %q", encoding)
+	}
+	data, err := io.ReadAll(decoded)
+	if err != nil {
+		t.Fatalf("解码正文失败: %v", err)
+	}
+	return string(data)
+}
+
+// TestBuildMessageMultipartAlternative 测试同时包含纯文本和 HTML 的邮件
+func TestBuildMessageMultipartAlternative(t *testing.T) {
+	longLine := strings.Repeat("比特币价格报表 ", 40)
+	html := "<p>" + longLine + "</p>"
+	text := "Bitcoin  $45,000.00\n" + longLine
+
+	for _, encoding := range []string{"", "quoted-printable", "base64"} {
+		t.Run(encoding, func(t *testing.T) {
+			sender := NewEmailSender(EmailConfig{Username: "bot@test.com", TransferEncoding: encoding})
+			raw, err := sender.buildMessage([]string{"a@test.com"}, "每日报表", html, text, nil)
+			if err != nil {
+				t.Fatalf("buildMessage 失败: %v", err)
+			}
+
+			header, parts := readMessage(t, raw)
+			if mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type")); mediaType != "multipart/alternative" {
+				t.Errorf("期望 multipart/alternative，实际为 %s", mediaType)
+			}
+			if got := strings.ReplaceAll(strings.TrimRight(parts["text/plain"], "\r\n"), "\r\n", "\n"); got != text {
+				t.Errorf("纯文本部分解码后不一致: %q", got)
+			}
+			if got := strings.TrimRight(parts["text/html"], "\r\n"); got != html {
+				t.Errorf("HTML 部分解码后不一致: %q", got)
+			}
+
+			// 纯文本部分必须在 HTML 之前，客户端会优先显示最后一个可识别的部分
+			if bytes.Index(raw, []byte("text/plain")) > bytes.Index(raw, []byte("text/html")) {
+				t.Error("纯文本部分应该位于 HTML 部分之前")
+			}
+		})
+	}
+}
+
+// TestBuildMessageHTMLOnly 测试未提供纯文本时发送单一 HTML 正文
+func TestBuildMessageHTMLOnly(t *testing.T) {
+	sender := NewEmailSender(EmailConfig{Username: "bot@test.com"})
+	raw, err := sender.buildMessage([]string{"a@test.com"}, "Report", "<p>你好</p>", "", nil)
+	if err != nil {
+		t.Fatalf("buildMessage 失败: %v", err)
+	}
+
+	_, parts := readMessage(t, raw)
+	if strings.TrimSpace(parts["text/html"]) != "<p>你好</p>" {
+		t.Errorf("HTML 正文不正确: %+v", parts)
+	}
+}
+
+// TestBuildMessageInvalidEncoding 测试不支持的传输编码
+func TestBuildMessageInvalidEncoding(t *testing.T) {
+	sender := NewEmailSender(EmailConfig{Username: "bot@test.com", TransferEncoding: "8bit"})
+	if _, err := sender.buildMessage([]string{"a@test.com"}, "Report", "<p>x</p>", "x", nil); err == nil {
+		t.Error("不支持的传输编码应该返回错误")
+	}
+}
+
+func quotedprintableReader(raw []byte) io.Reader {
+	return quotedprintable.NewReader(bytes.NewReader(raw))
+}
+
+func base64Reader(raw []byte) io.Reader {
+	return base64.NewDecoder(base64.StdEncoding, bytes.NewReader(raw))
+}
+
+// testEmailConfig 返回连接到测试 SMTP 服务器的邮件配置
+func testEmailConfig(server *fakeSMTPServer) EmailConfig {
+	return EmailConfig{
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   server.port(),
+		Username:   "bot@test.com",
+		Password:   "secret",
+		To:         []string{"a@test.com"},
+	}
+}
+
+// TestSendReportTLSModes 测试直连和代理两种方式下的各种 TLS 模式
+func TestSendReportTLSModes(t *testing.T) {
+	cert, caFile := testCertificate(t)
+	serverTLS := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	tests := []struct {
+		name    string
+		server  *fakeSMTPServer
+		mode    string
+		wantTLS bool
+	}{
+		{"starttls", &fakeSMTPServer{starttls: true, auth: true, tlsConfig: serverTLS}, tlsModeSTARTTLS, true},
+		{"implicit", &fakeSMTPServer{implicitTLS: true, auth: true, tlsConfig: serverTLS}, tlsModeImplicit, true},
+		{"none", &fakeSMTPServer{}, tlsModeNone, false},
+	}
+
+	for _, tt := range tests {
+		tt.server.start(t)
+		for _, proxy := range []bool{false, true} {
+			name := tt.name
+			if proxy {
+				name += "_proxy"
+			}
+			t.Run(name, func(t *testing.T) {
+				config := testEmailConfig(tt.server)
+				config.TLS = tt.mode
+				config.TLSCAFile = caFile
+				if proxy {
+					config.ProxyEnabled = true
+					config.ProxyURL = (&testHTTPProxy{}).start(t)
+				}
+
+				before := len(tt.server.received())
+				if err := NewEmailSender(config).SendReportTo(config.To, "Report", "<p>x</p>", "x"); err != nil {
+					t.Fatalf("发送邮件失败: %v", err)
+				}
+
+				messages := tt.server.received()
+				if len(messages) != before+1 {
+					t.Fatalf("服务器应该收到一封邮件，实际为 %d", len(messages)-before)
+				}
+				msg := messages[len(messages)-1]
+				if msg.TLS != tt.wantTLS {
+					t.Errorf("TLS 状态不正确: %v", msg.TLS)
+				}
+				if msg.Auth != tt.server.auth {
+					t.Errorf("认证状态不正确: %v", msg.Auth)
+				}
+				if msg.From != "bot@test.com" || len(msg.To) != 1 || msg.To[0] != "a@test.com" {
+					t.Errorf("信封地址不正确: %+v", msg)
+				}
+			})
+		}
+	}
+}
+
+// TestSendReportTLSPolicy 测试 STARTTLS 缺失、证书校验和最低版本的处理
+func TestSendReportTLSPolicy(t *testing.T) {
+	cert, caFile := testCertificate(t)
+
+	plain := &fakeSMTPServer{auth: true}
+	plain.start(t)
+	config := testEmailConfig(plain)
+	config.TLS = tlsModeSTARTTLS
+	if err := NewEmailSender(config).SendReport("Report", "<p>x</p>"); err == nil {
+		t.Error("服务器不支持 STARTTLS 时应该返回错误，而不是以明文发送")
+	}
+
+	secure := &fakeSMTPServer{implicitTLS: true, tlsConfig: &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MaxVersion:   tls.VersionTLS12,
+	}}
+	secure.start(t)
+	config = testEmailConfig(secure)
+	config.TLS = tlsModeImplicit
+
+	if err := NewEmailSender(config).SendReport("Report", "<p>x</p>"); err == nil {
+		t.Error("未信任的证书应该返回错误")
+	}
+
+	config.TLSSkipVerify = true
+	if err := NewEmailSender(config).SendReport("Report", "<p>x</p>"); err != nil {
+		t.Errorf("跳过证书校验时应该发送成功: %v", err)
+	}
+
+	config.TLSSkipVerify = false
+	config.TLSCAFile = caFile
+	config.TLSMinVersion = "1.3"
+	if err := NewEmailSender(config).SendReport("Report", "<p>x</p>"); err == nil {
+		t.Error("服务器版本低于 tls_min_version 时应该返回错误")
+	}
+
+	config.TLSMinVersion = "1.2"
+	if err := NewEmailSender(config).SendReport("Report", "<p>x</p>"); err != nil {
+		t.Errorf("使用自定义 CA 时应该发送成功: %v", err)
+	}
+}
+
+// TestEmailSenderDefaultTLSMode 测试未配置 TLS 模式时按端口选择
+func TestEmailSenderDefaultTLSMode(t *testing.T) {
+	tests := map[int]string{465: tlsModeImplicit, 587: tlsModeSTARTTLS, 25: tlsModeSTARTTLS}
+	for port, want := range tests {
+		if got := NewEmailSender(EmailConfig{SMTPPort: port}).tlsMode(); got != want {
+			t.Errorf("端口 %d 的默认 TLS 模式为 %s，期望 %s", port, got, want)
+		}
+	}
+	if got := NewEmailSender(EmailConfig{SMTPPort: 465, TLS: tlsModeNone}).tlsMode(); got != tlsModeNone {
+		t.Errorf("显式配置的 TLS 模式应该优先，实际为 %s", got)
+	}
+}
+
+// TestDeliverPartialRecipients 测试部分收件人被拒绝时仍投递给其余收件人
+func TestDeliverPartialRecipients(t *testing.T) {
+	server := &fakeSMTPServer{}
+	server.start(t)
+	server.setRejectRcpt("b@test.com", "550 5.1.1 no such user")
+	config := testEmailConfig(server)
+	config.TLS = tlsModeNone
+	sender := NewEmailSender(config)
+
+	email, err := sender.Compose([]string{"a@test.com", "b@test.com", "c@test.com"}, "Report", "<p>x</p>", "x")
+	if err != nil {
+		t.Fatalf("构建邮件失败: %v", err)
+	}
+	err = sender.Deliver(email)
+
+	var recipientErr *RecipientError
+	if !errors.As(err, &recipientErr) {
+		t.Fatalf("部分收件人被拒绝时应该返回 RecipientError: %v", err)
+	}
+	if got := strings.Join(recipientErr.Accepted(), ","); got != "a@test.com,c@test.com" {
+		t.Errorf("被接受的收件人不正确: %s", got)
+	}
+	if rejected := recipientErr.Rejected(); len(rejected) != 1 || rejected[0].Address != "b@test.com" {
+		t.Errorf("被拒绝的收件人不正确: %+v", rejected)
+	}
+	if !isPermanentSMTPError(err) {
+		t.Error("收件人均返回 5xx 时应该视为永久错误")
+	}
+
+	messages := server.received()
+	if len(messages) != 1 || strings.Join(messages[0].To, ",") != "a@test.com,c@test.com" {
+		t.Fatalf("其余收件人应该收到邮件: %+v", messages)
+	}
+
+	// 发件箱只保留被拒绝的收件人
+	outbox := NewOutbox(t.TempDir(), RetryPolicy{})
+	entry, _ := outbox.Enqueue(email, err)
+	if strings.Join(entry.To, ",") != "b@test.com" {
+		t.Errorf("发件箱应该只重试被拒绝的收件人: %v", entry.To)
+	}
+
+	// 全部被拒绝时不发送 DATA
+	server.setRejectRcpt("a@test.com", "451 4.2.1 mailbox busy")
+	email, _ = sender.Compose([]string{"a@test.com", "b@test.com"}, "Report", "<p>x</p>", "x")
+	err = sender.Deliver(email)
+	if !errors.As(err, &recipientErr) || len(recipientErr.Accepted()) != 0 {
+		t.Fatalf("全部收件人被拒绝时应该返回 RecipientError: %v", err)
+	}
+	if isPermanentSMTPError(err) {
+		t.Error("存在 4xx 拒绝时应该视为临时错误")
+	}
+	if len(server.received()) != 1 {
+		t.Error("全部收件人被拒绝时不应该发送邮件内容")
+	}
+}
+
+// TestComposeRecipients 测试显示名称和密送的邮件头与信封
+func TestComposeRecipients(t *testing.T) {
+	sender := NewEmailSender(EmailConfig{Username: "bot@test.com"})
+
+	email, err := sender.Compose([]string{"张三 <zhang@test.com>"}, "Report", "<p>x</p>", "")
+	if err != nil {
+		t.Fatalf("构建邮件失败: %v", err)
+	}
+	header, _ := readMessage(t, email.Message)
+	to, err := header.AddressList("To")
+	if err != nil || len(to) != 1 || to[0].Name != "张三" || to[0].Address != "zhang@test.com" {
+		t.Errorf("To 邮件头应该包含显示名称: %q", header.Get("To"))
+	}
+	if strings.Join(email.To, ",") != "zhang@test.com" {
+		t.Errorf("信封中只应该包含邮箱地址: %v", email.To)
+	}
+
+	email, err = sender.ComposeBcc([]string{"a@test.com", "b@test.com"}, "Report", "<p>x</p>", "")
+	if err != nil {
+		t.Fatalf("构建邮件失败: %v", err)
+	}
+	header, _ = readMessage(t, email.Message)
+	if got := header.Get("To"); got != "undisclosed-recipients:;" {
+		t.Errorf("密送邮件的 To 邮件头不正确: %q", got)
+	}
+	if header.Get("Bcc") != "" || bytes.Contains(email.Message, []byte("<a@test.com>")) {
+		t.Error("密送邮件的内容中不应该出现收件人地址")
+	}
+	if strings.Join(email.To, ",") != "a@test.com,b@test.com" {
+		t.Errorf("密送收件人应该出现在信封中: %v", email.To)
+	}
+
+	if _, err := sender.Compose([]string{"not an address"}, "Report", "<p>x</p>", ""); err == nil {
+		t.Error("无效的收件人地址应该返回错误")
+	}
+}
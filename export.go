@@ -0,0 +1,190 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 数据导出格式
+const (
+	exportCSV  = "csv"
+	exportXLSX = "xlsx"
+)
+
+// exportContentTypes 是各导出格式的 MIME 类型
+var exportContentTypes = map[string]string{
+	exportCSV:  "text/csv; charset=utf-8",
+	exportXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// exportColumn 是导出数据中的一列，列名与 CoinGecko API 的字段名一致
+type exportColumn struct {
+	name  string
+	value func(CoinPrice) interface{} // 返回 string 或 float64
+}
+
+var exportColumns = []exportColumn{
+	{"id", func(c CoinPrice) interface{} { return c.ID }},
+	{"symbol", func(c CoinPrice) interface{} { return c.Symbol }},
+	{"name", func(c CoinPrice) interface{} { return c.Name }},
+	{"current_price", func(c CoinPrice) interface{} { return c.CurrentPrice }},
+	{"market_cap", func(c CoinPrice) interface{} { return c.MarketCap }},
+	{"price_change_24h", func(c CoinPrice) interface{} { return c.PriceChange24h }},
+	{"price_change_percentage_24h", func(c CoinPrice) interface{} { return c.PriceChangePerc24h }},
+	{"total_volume", func(c CoinPrice) interface{} { return c.Volume24h }},
+	{"high_24h", func(c CoinPrice) interface{} { return c.High24h }},
+	{"low_24h", func(c CoinPrice) interface{} { return c.Low24h }},
+	{"ath", func(c CoinPrice) interface{} { return c.ATH }},
+	{"last_updated", func(c CoinPrice) interface{} { return c.LastUpdated }},
+}
+
+// ExportCoins 按指定格式导出币种数据，返回文件内容
+func ExportCoins(format string, coins []CoinPrice) ([]byte, error) {
+	switch format {
+	case exportCSV:
+		return exportCoinsCSV(coins)
+	case exportXLSX:
+		return exportCoinsXLSX(coins)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q (supported: csv, xlsx)", format)
+	}
+}
+
+// exportCoinsCSV 导出 CSV，数字保留完整精度，不做本地化格式
+func exportCoinsCSV(coins []CoinPrice) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true // RFC 4180
+
+	record := make([]string, len(exportColumns))
+	for i, column := range exportColumns {
+		record[i] = column.name
+	}
+	w.Write(record)
+
+	for _, coin := range coins {
+		for i, column := range exportColumns {
+			switch v := column.value(coin).(type) {
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			case string:
+				record[i] = csvSafeText(v)
+			}
+		}
+		w.Write(record)
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// csvSafeText 在以 =、+、-、@、制表符或回车开头的文本前加上 '，
+// 避免 API 返回的名称在电子表格中打开时被当作公式执行（CSV 注入）
+func csvSafeText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// xlsxPart 是 XLSX 压缩包中的一个文件
+type xlsxPart struct {
+	name    string
+	content string
+}
+
+// xlsxParts 是 XLSX 文件中除工作表以外的固定部分（ECMA-376 最小工作簿）
+var xlsxParts = []xlsxPart{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Coins" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// exportCoinsXLSX 导出只有一个工作表的 XLSX，文本使用内联字符串，数字保留为数值单元格
+// 内联字符串不会被当作公式，因此以 = 开头的名称不需要额外处理
+func exportCoinsXLSX(coins []CoinPrice) ([]byte, error) {
+	var sheet strings.Builder
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	writeRow := func(row int, values []interface{}) {
+		fmt.Fprintf(&sheet, `<row r="%d">`, row)
+		for i, value := range values {
+			ref := xlsxColumnName(i) + strconv.Itoa(row)
+			switch v := value.(type) {
+			case float64:
+				fmt.Fprintf(&sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+			case string:
+				fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr"><is><t>`, ref)
+				xml.EscapeText(&sheet, []byte(v))
+				sheet.WriteString(`</t></is></c>`)
+			}
+		}
+		sheet.WriteString(`</row>`)
+	}
+
+	values := make([]interface{}, len(exportColumns))
+	for i, column := range exportColumns {
+		values[i] = column.name
+	}
+	writeRow(1, values)
+	for n, coin := range coins {
+		for i, column := range exportColumns {
+			values[i] = column.value(coin)
+		}
+		writeRow(n+2, values)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := append([]xlsxPart{}, xlsxParts...)
+	files = append(files, xlsxPart{"xl/worksheets/sheet1.xml", sheet.String()})
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write XLSX: %w", err)
+		}
+		if _, err := w.Write([]byte(file.content)); err != nil {
+			return nil, fmt.Errorf("failed to write XLSX: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write XLSX: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// xlsxColumnName 返回从 0 开始的列序号对应的列名：A、B……Z、AA
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
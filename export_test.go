@@ -0,0 +1,188 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// exportTestCoins 返回用于导出测试的币种数据，名称中带有需要转义的字符
+func exportTestCoins() []CoinPrice {
+	return []CoinPrice{
+		{ID: "bitcoin", Symbol: "btc", Name: "Bitcoin", CurrentPrice: 45000.12345678, MarketCap: 850e9, PriceChangePerc24h: 2.5, LastUpdated: "2026-10-19T01:00:00Z"},
+		{ID: "evil", Symbol: "x", Name: `Evil, "Coin" <&>`, CurrentPrice: 0.00000123, PriceChangePerc24h: -1},
+	}
+}
+
+// TestExportCoinsCSV 测试 CSV 导出保留完整精度并正确转义
+func TestExportCoinsCSV(t *testing.T) {
+	data, err := ExportCoins(exportCSV, exportTestCoins())
+	if err != nil {
+		t.Fatalf("导出 CSV 失败: %v", err)
+	}
+	if !strings.HasSuffix(string(data), "\r\n") {
+		t.Error("CSV 应该使用 CRLF 换行")
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("解析 CSV 失败: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("CSV 应该有 3 行（含表头），实际 %d 行", len(records))
+	}
+	if records[0][0] != "id" || records[0][6] != "price_change_percentage_24h" {
+		t.Errorf("表头不正确: %v", records[0])
+	}
+	if records[1][3] != "45000.12345678" {
+		t.Errorf("价格应该保留完整精度，实际 %s", records[1][3])
+	}
+	if records[2][2] != `Evil, "Coin" <&>` {
+		t.Errorf("名称转义不正确: %s", records[2][2])
+	}
+	if records[2][3] != "0.00000123" {
+		t.Errorf("小数不应该使用科学计数法，实际 %s", records[2][3])
+	}
+}
+
+// TestExportCoinsXLSX 测试 XLSX 导出的压缩包结构和单元格内容
+func TestExportCoinsXLSX(t *testing.T) {
+	data, err := ExportCoins(exportXLSX, exportTestCoins())
+	if err != nil {
+		t.Fatalf("导出 XLSX 失败: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("XLSX 应该是 zip 文件: %v", err)
+	}
+
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("打开 %s 失败: %v", file.Name, err)
+		}
+		files[file.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("XLSX 缺少 %s", name)
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("解析工作表失败: %v", err)
+	}
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("工作表应该有 3 行，实际 %d 行", len(sheet.Rows))
+	}
+
+	header := sheet.Rows[0].Cells
+	if header[0].Type != "inlineStr" || header[0].Inline != "id" {
+		t.Errorf("表头第一列不正确: %+v", header[0])
+	}
+	price := sheet.Rows[1].Cells[3]
+	if price.Ref != "D2" || price.Type != "" || price.Value != "45000.12345678" {
+		t.Errorf("价格应该是数值单元格 D2，实际 %+v", price)
+	}
+	name := sheet.Rows[2].Cells[2]
+	if name.Ref != "C3" || name.Inline != `Evil, "Coin" <&>` {
+		t.Errorf("名称单元格不正确: %+v", name)
+	}
+}
+
+// TestExportCoinsFormulaNames 测试以公式字符开头的名称不会在电子表格中被当作公式
+func TestExportCoinsFormulaNames(t *testing.T) {
+	hostile := []string{"=HYPERLINK(\"http://evil\")", "+1+1", "-2+3", "@SUM(A1)", "\tcmd", "\rcmd"}
+	var coins []CoinPrice
+	for _, name := range hostile {
+		coins = append(coins, CoinPrice{ID: "evil", Symbol: name, Name: name, PriceChangePerc24h: -5})
+	}
+
+	data, err := ExportCoins(exportCSV, coins)
+	if err != nil {
+		t.Fatalf("导出 CSV 失败: %v", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("解析 CSV 失败: %v", err)
+	}
+	for i, name := range hostile {
+		record := records[i+1]
+		// UseCRLF 时 csv.Writer 不输出字段中单独的回车
+		expected := "'" + strings.ReplaceAll(name, "\r", "")
+		if record[1] != expected || record[2] != expected {
+			t.Errorf("名称 %q 应该加上 ' 前缀，实际为 %q, %q", name, record[1], record[2])
+		}
+		if record[6] != "-5" {
+			t.Errorf("数值列不应该加前缀，实际为 %q", record[6])
+		}
+	}
+
+	data, err = ExportCoins(exportXLSX, coins)
+	if err != nil {
+		t.Fatalf("导出 XLSX 失败: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("XLSX 应该是 zip 文件: %v", err)
+	}
+	for _, file := range archive.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		r, _ := file.Open()
+		content, _ := io.ReadAll(r)
+		r.Close()
+		var sheet struct {
+			Rows []struct {
+				Cells []struct {
+					Type    string `xml:"t,attr"`
+					Formula string `xml:"f"`
+					Inline  string `xml:"is>t"`
+				} `xml:"c"`
+			} `xml:"sheetData>row"`
+		}
+		if err := xml.Unmarshal(content, &sheet); err != nil {
+			t.Fatalf("解析工作表失败: %v", err)
+		}
+		for i, name := range hostile {
+			cell := sheet.Rows[i+1].Cells[2]
+			if cell.Type != "inlineStr" || cell.Formula != "" || cell.Inline != name {
+				t.Errorf("名称 %q 应该是内联字符串单元格，实际 %+v", name, cell)
+			}
+		}
+	}
+}
+
+// TestExportCoinsUnsupportedFormat 测试不支持的导出格式
+func TestExportCoinsUnsupportedFormat(t *testing.T) {
+	if _, err := ExportCoins("pdf", exportTestCoins()); err == nil {
+		t.Error("不支持的格式应该返回错误")
+	}
+}
+
+// TestXLSXColumnName 测试列序号到列名的转换
+func TestXLSXColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 11: "L", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+	for index, want := range tests {
+		if got := xlsxColumnName(index); got != want {
+			t.Errorf("xlsxColumnName(%d) = %s, 期望 %s", index, got, want)
+		}
+	}
+}
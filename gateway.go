@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+// GatewayBot 通过 Discord Gateway 接收斜杠命令，启动时注册命令
+type GatewayBot struct {
+	session *discordgo.Session
+	handler *CommandHandler
+	guildID string // 非空时只在该服务器注册命令
+}
+
+// NewGatewayBot 创建 Gateway 机器人，proxyURL 非空时 REST 请求和 WebSocket 连接都通过代理
+func NewGatewayBot(botToken, guildID string, handler *CommandHandler, proxyURL string) (*GatewayBot, error) {
+	session, err := newDiscordSession(botToken, proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	// 斜杠命令不需要接收消息内容，只订阅服务器事件
+	session.Identify.Intents = discordgo.IntentsGuilds
+
+	bot := &GatewayBot{session: session, handler: handler, guildID: guildID}
+	session.AddHandler(bot.onInteraction)
+	return bot, nil
+}
+
+// newDiscordSession 创建 discordgo 会话，proxyURL 非空时 REST 请求和 WebSocket 连接都通过代理
+func newDiscordSession(botToken, proxyURL string) (*discordgo.Session, error) {
+	session, err := discordgo.New("Bot " + botToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Discord session: %w", err)
+	}
+	dialer, err := NewProxyDialer(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	session.Client = dialer.HTTPClient(30 * time.Second)
+	session.Dialer = &websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return dialer.DialContext(context.Background(), network, addr)
+		},
+		HandshakeTimeout: 45 * time.Second,
+	}
+	return session, nil
+}
+
+// Start 连接 Gateway 并注册斜杠命令
+func (b *GatewayBot) Start() error {
+	if err := b.session.Open(); err != nil {
+		return fmt.Errorf("failed to connect to Discord gateway: %w", err)
+	}
+	if err := registerSlashCommands(b.session, b.session.State.User.ID, b.guildID); err != nil {
+		b.session.Close()
+		return err
+	}
+	return nil
+}
+
+// registerSlashCommands 注册斜杠命令，已注册但不在列表中的旧命令会被删除
+func registerSlashCommands(session *discordgo.Session, appID, guildID string) error {
+	if _, err := session.ApplicationCommandBulkOverwrite(appID, guildID, slashCommands); err != nil {
+		return fmt.Errorf("failed to register slash commands: %w", err)
+	}
+	return nil
+}
+
+// Close 断开 Gateway 连接
+func (b *GatewayBot) Close() error {
+	return b.session.Close()
+}
+
+// onInteraction 处理斜杠命令
+// 获取价格可能需要几秒（失败时还会重试），因此先回复“思考中”，再编辑为实际结果
+func (b *GatewayBot) onInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		log.Printf("响应斜杠命令失败: %v", err)
+		return
+	}
+
+	response := b.handler.Handle(i.GuildID, i.Locale, i.ApplicationCommandData())
+	if err := sendCommandResponse(s, i.Interaction, response); err != nil {
+		log.Printf("发送斜杠命令结果失败: %v", err)
+	}
+}
+
+// sendCommandResponse 将延迟响应编辑为命令结果，Embed 超过一条消息时其余部分作为后续消息发送
+// 回复中不会提及任何用户或身份组
+func sendCommandResponse(s *discordgo.Session, interaction *discordgo.Interaction, response CommandResponse) error {
+	noMentions := &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}
+	edit := &discordgo.WebhookEdit{AllowedMentions: noMentions}
+	if response.Content != "" {
+		edit.Content = &response.Content
+	}
+	if len(response.Messages) > 0 {
+		embeds := toMessageEmbeds(response.Messages[0])
+		edit.Embeds = &embeds
+	}
+	if _, err := s.InteractionResponseEdit(interaction, edit); err != nil {
+		return err
+	}
+
+	if len(response.Messages) < 2 {
+		return nil
+	}
+	for _, embeds := range response.Messages[1:] {
+		params := &discordgo.WebhookParams{Embeds: toMessageEmbeds(embeds), AllowedMentions: noMentions}
+		if _, err := s.FollowupMessageCreate(interaction, true, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
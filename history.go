@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistorySnapshot 表示某一天报表生成时的币种数据快照
+type HistorySnapshot struct {
+	Date  string      `json:"date"` // 2006-01-02
+	Time  time.Time   `json:"time"`
+	Coins []CoinPrice `json:"coins"`
+}
+
+// Coin 返回快照中指定 ID 的币种数据
+func (h HistorySnapshot) Coin(id string) (CoinPrice, bool) {
+	for _, coin := range h.Coins {
+		if coin.ID == id {
+			return coin, true
+		}
+	}
+	return CoinPrice{}, false
+}
+
+// HistoryStore 负责在磁盘上保存最近若干天的快照
+type HistoryStore struct {
+	path    string
+	maxDays int
+}
+
+// NewHistoryStore 创建历史快照存储
+func NewHistoryStore(path string, maxDays int) *HistoryStore {
+	return &HistoryStore{
+		path:    path,
+		maxDays: maxDays,
+	}
+}
+
+// Load 读取全部历史快照，文件不存在时返回空列表
+func (h *HistoryStore) Load() ([]HistorySnapshot, error) {
+	data, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	var snapshots []HistorySnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse history file: %w", err)
+	}
+	return snapshots, nil
+}
+
+// Record 保存一次快照，同一天的快照会被覆盖，超出天数的旧快照会被丢弃
+func (h *HistoryStore) Record(coins []CoinPrice, at time.Time) error {
+	snapshots, err := h.Load()
+	if err != nil {
+		return err
+	}
+
+	snapshot := HistorySnapshot{
+		Date:  at.Format("2006-01-02"),
+		Time:  at,
+		Coins: coins,
+	}
+	if n := len(snapshots); n > 0 && snapshots[n-1].Date == snapshot.Date {
+		snapshots[n-1] = snapshot
+	} else {
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if h.maxDays > 0 && len(snapshots) > h.maxDays {
+		snapshots = snapshots[len(snapshots)-h.maxDays:]
+	}
+
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+	return writeFileAtomic(h.path, data, 0644)
+}
+
+// writeFileAtomic 先写入临时文件再重命名，避免进程中断时留下损坏的文件
+// 文件使用 perm 权限创建，不存在的目录对同样的用户可读写并可进入（0600 对应 0700，0644 对应 0755）
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), perm|(perm&0444)>>2); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// 上次中断留下的临时文件可能使用了其他权限，os.WriteFile 不会修改已有文件的权限
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	return nil
+}
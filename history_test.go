@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestHistoryStoreLoadMissing 测试历史文件不存在时返回空列表
+func TestHistoryStoreLoadMissing(t *testing.T) {
+	store := NewHistoryStore(filepath.Join(t.TempDir(), "history.json"), 30)
+
+	snapshots, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load 失败: %v", err)
+	}
+	if len(snapshots) != 0 {
+		t.Errorf("期望 0 个快照，实际为 %d", len(snapshots))
+	}
+}
+
+// TestHistoryStoreRecord 测试同一天覆盖、超出天数裁剪
+func TestHistoryStoreRecord(t *testing.T) {
+	store := NewHistoryStore(filepath.Join(t.TempDir(), "data", "history.json"), 2)
+	day := time.Date(2026, 2, 7, 9, 0, 0, 0, time.UTC)
+
+	records := []struct {
+		at    time.Time
+		price float64
+	}{
+		{day, 100},
+		{day.Add(time.Hour), 110}, // 同一天，覆盖
+		{day.AddDate(0, 0, 1), 120},
+		{day.AddDate(0, 0, 2), 130},
+	}
+	for _, r := range records {
+		coins := []CoinPrice{{ID: "bitcoin", CurrentPrice: r.price}}
+		if err := store.Record(coins, r.at); err != nil {
+			t.Fatalf("Record 失败: %v", err)
+		}
+	}
+
+	snapshots, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load 失败: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("期望保留 2 个快照，实际为 %d", len(snapshots))
+	}
+	if snapshots[0].Date != "2026-02-08" || snapshots[1].Date != "2026-02-09" {
+		t.Errorf("快照日期不正确: %s, %s", snapshots[0].Date, snapshots[1].Date)
+	}
+
+	coin, ok := snapshots[1].Coin("bitcoin")
+	if !ok || coin.CurrentPrice != 130 {
+		t.Errorf("最新快照价格不正确: %+v", coin)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// defaultLocale 是未配置语言区域时使用的默认值
+const defaultLocale = "zh-CN"
+
+// Locale 表示一个语言区域的消息目录和格式约定
+type Locale struct {
+	Code       string
+	DateFormat string
+	Numbers    NumberFormat
+	messages   map[string]string
+}
+
+// T 返回指定键对应的本地化文本，args 非空时按 fmt 格式化
+// 缺少翻译时回退到默认语言区域，仍然缺少时返回键本身
+func (l *Locale) T(key string, args ...interface{}) string {
+	msg, ok := l.messages[key]
+	if !ok {
+		msg, ok = locales[defaultLocale].messages[key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// FormatDate 按语言区域格式化日期
+func (l *Locale) FormatDate(t time.Time) string {
+	return t.Format(l.DateFormat)
+}
+
+// locales 是所有内置的语言区域
+var locales = map[string]*Locale{
+	"zh-CN": {
+		Code:       "zh-CN",
+		DateFormat: "2006年01月02日",
+		Numbers: NumberFormat{
+			Decimal: ".",
+			Group:   ",",
+			Compact: []compactUnit{{1e12, "万亿"}, {1e8, "亿"}, {1e4, "万"}},
+		},
+		messages: map[string]string{
+			"report.title":          "每日加密货币价格报表",
+			"report.subject":        "每日加密货币价格报表 - %s",
+			"report.others":         "其他",
+			"report.subtotal":       "小计",
+			"report.greeting":       "%s，您好：",
+			"chart.change_24h":      "24h 涨跌幅",
+			"column.name":           "币种",
+			"column.symbol":         "符号",
+			"column.price":          "当前价格 (%s)",
+			"column.change":         "24h 变化",
+			"column.change_perc":    "24h 变化率",
+			"column.market_cap":     "市值",
+			"column.volume":         "24h 交易量",
+			"footer.source":         "数据来源: %s",
+			"footer.generated":      "此报表由 %s 自动生成",
+			"discord.footer":        "数据来源: %s | %s 自动生成",
+			"board.truncated":       "另有 %d 个币种未显示",
+			"summary.title":         "今日概要",
+			"summary.top_gainer":    "领涨: %s %s",
+			"summary.top_loser":     "领跌: %s %s",
+			"summary.breadth":       "上涨 %d · 下跌 %d · 持平 %d",
+			"summary.weighted":      "市值加权涨跌: %s",
+			"event.high_24h":        "%s 触及 24 小时新高 %s",
+			"event.low_24h":         "%s 跌至 24 小时新低 %s",
+			"event.ath":             "%s 创历史新高 %s",
+			"event.volume_spike":    "%s 交易量放大至上次报表的 %.1f 倍",
+			"price.range_24h":       "24h 区间",
+			"command.not_found":     "未找到币种 `%s`，请使用 CoinGecko ID（如 bitcoin）",
+			"command.invalid_coin":  "请输入 CoinGecko ID，只包含小写字母、数字和 -（如 bitcoin）",
+			"command.failed":        "获取价格失败，请稍后重试",
+			"command.guild_only":    "该命令只能在服务器中使用",
+			"watchlist.added":       "已将 `%s` 加入本服务器的关注列表",
+			"watchlist.exists":      "`%s` 已在关注列表中",
+			"watchlist.removed":     "已将 `%s` 移出关注列表",
+			"watchlist.missing":     "`%s` 不在关注列表中",
+			"watchlist.current":     "当前关注列表：%s",
+			"watchlist.empty":       "关注列表为空，/report 将使用默认币种",
+			"watchlist.save_failed": "保存关注列表失败，修改未生效，请稍后重试",
+		},
+	},
+	"en": {
+		Code:       "en",
+		DateFormat: "January 2, 2006",
+		Numbers: NumberFormat{
+			Decimal: ".",
+			Group:   ",",
+			Compact: []compactUnit{{1e12, "T"}, {1e9, "B"}, {1e6, "M"}, {1e3, "K"}},
+		},
+		messages: map[string]string{
+			"report.title":          "Daily Crypto Price Report",
+			"report.subject":        "Daily Crypto Price Report - %s",
+			"report.others":         "Others",
+			"report.subtotal":       "Subtotal",
+			"report.greeting":       "Hi %s,",
+			"chart.change_24h":      "24h Change",
+			"column.name":           "Coin",
+			"column.symbol":         "Symbol",
+			"column.price":          "Price (%s)",
+			"column.change":         "24h Change",
+			"column.change_perc":    "24h Change %",
+			"column.market_cap":     "Market Cap",
+			"column.volume":         "24h Volume",
+			"footer.source":         "Data source: %s",
+			"footer.generated":      "This report was generated automatically by %s",
+			"discord.footer":        "Data source: %s | Generated by %s",
+			"board.truncated":       "+%d more coins not shown",
+			"summary.title":         "Summary",
+			"summary.top_gainer":    "Top gainer: %s %s",
+			"summary.top_loser":     "Top loser: %s %s",
+			"summary.breadth":       "Up %d · Down %d · Flat %d",
+			"summary.weighted":      "Cap-weighted move: %s",
+			"event.high_24h":        "%s hit a new 24h high at %s",
+			"event.low_24h":         "%s fell to a new 24h low at %s",
+			"event.ath":             "%s set a new all-time high at %s",
+			"event.volume_spike":    "%s volume is %.1fx the previous report",
+			"price.range_24h":       "24h range",
+			"command.not_found":     "Coin `%s` not found, please use its CoinGecko ID (e.g. bitcoin)",
+			"command.invalid_coin":  "Please enter a CoinGecko ID made of lowercase letters, digits and - (e.g. bitcoin)",
+			"command.failed":        "Failed to fetch prices, please try again later",
+			"command.guild_only":    "This command can only be used in a server",
+			"watchlist.added":       "Added `%s` to this server's watchlist",
+			"watchlist.exists":      "`%s` is already on the watchlist",
+			"watchlist.removed":     "Removed `%s` from the watchlist",
+			"watchlist.missing":     "`%s` is not on the watchlist",
+			"watchlist.current":     "Current watchlist: %s",
+			"watchlist.empty":       "The watchlist is empty, /report will use the default coins",
+			"watchlist.save_failed": "Failed to save the watchlist, the change was not applied. Please try again later",
+		},
+	},
+}
+
+// LookupLocale 根据代码查找语言区域，code 为空时返回默认语言区域
+func LookupLocale(code string) (*Locale, error) {
+	if code == "" {
+		code = defaultLocale
+	}
+	locale, ok := locales[code]
+	if !ok {
+		return nil, fmt.Errorf("unsupported locale %q (supported: %v)", code, localeCodes())
+	}
+	return locale, nil
+}
+
+// localeCodes 返回所有支持的语言区域代码
+func localeCodes() []string {
+	codes := make([]string, 0, len(locales))
+	for code := range locales {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
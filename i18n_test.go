@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLookupLocale 测试语言区域查找
+func TestLookupLocale(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{"", "zh-CN", false},
+		{"zh-CN", "zh-CN", false},
+		{"en", "en", false},
+		{"fr", "", true},
+	}
+
+	for _, tt := range tests {
+		locale, err := LookupLocale(tt.code)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("LookupLocale(%q) 应该返回错误", tt.code)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("LookupLocale(%q) 返回错误: %v", tt.code, err)
+			continue
+		}
+		if locale.Code != tt.want {
+			t.Errorf("LookupLocale(%q) = %s，期望 %s", tt.code, locale.Code, tt.want)
+		}
+	}
+}
+
+// TestLocaleT 测试消息查找、格式化参数和缺失回退
+func TestLocaleT(t *testing.T) {
+	en := locales["en"]
+
+	if got := en.T("column.market_cap"); got != "Market Cap" {
+		t.Errorf("英文市值列名不正确: %s", got)
+	}
+	if got := locales["zh-CN"].T("footer.source", "CoinGecko API"); got != "数据来源: CoinGecko API" {
+		t.Errorf("带参数的消息不正确: %s", got)
+	}
+	if got := en.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("缺失的键应该原样返回，实际为 %s", got)
+	}
+}
+
+// TestLocaleCatalogsComplete 测试所有语言区域的消息键一致
+func TestLocaleCatalogsComplete(t *testing.T) {
+	base := locales[defaultLocale]
+	for code, locale := range locales {
+		for key := range base.messages {
+			if _, ok := locale.messages[key]; !ok {
+				t.Errorf("语言区域 %s 缺少消息 %s", code, key)
+			}
+		}
+		for key := range locale.messages {
+			if _, ok := base.messages[key]; !ok {
+				t.Errorf("语言区域 %s 包含默认语言区域没有的消息 %s", code, key)
+			}
+		}
+	}
+}
+
+// TestLocaleFormatDate 测试日期格式
+func TestLocaleFormatDate(t *testing.T) {
+	day := time.Date(2026, 2, 7, 9, 0, 0, 0, time.UTC)
+
+	if got := locales["zh-CN"].FormatDate(day); got != "2026年02月07日" {
+		t.Errorf("中文日期格式不正确: %s", got)
+	}
+	if got := locales["en"].FormatDate(day); got != "February 7, 2026" {
+		t.Errorf("英文日期格式不正确: %s", got)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 斜杠命令的接收方式
+const (
+	commandModeGateway = "gateway" // 默认，Bot 保持 Gateway 长连接
+	commandModeHTTP    = "http"    // Discord 将命令 POST 到 interactions 地址
+)
+
+// http 模式的默认监听地址和路径
+const (
+	defaultInteractionsListen = ":8080"
+	defaultInteractionsPath   = "/interactions"
+)
+
+// interactionMaxClockSkew 是请求时间戳与本机时间允许的最大差值，超过时拒绝请求，防止截获的请求被重放
+const interactionMaxClockSkew = 5 * time.Minute
+
+// InteractionServer 是 Discord interactions 的 HTTP 接收地址（在开发者后台填写为 Interactions Endpoint URL）
+// 每个请求都要校验 Ed25519 签名，未通过校验的请求返回 401，Discord 保存地址时也会用错误的签名检查这一点
+type InteractionServer struct {
+	publicKey ed25519.PublicKey
+	handler   *CommandHandler
+	// respond 将命令结果发送给 Discord，测试中替换为本地函数
+	respond func(interaction *discordgo.Interaction, response CommandResponse) error
+	now     func() time.Time
+	server  *http.Server
+	pending sync.WaitGroup // 后台执行中的命令，Close 时等待完成
+}
+
+// NewInteractionServer 创建 interactions 接收服务，publicKey 为开发者后台中应用的 Public Key（十六进制）
+// 命令结果通过 session 编辑延迟响应发送，这些请求使用 interaction token，不需要 Bot 权限
+func NewInteractionServer(publicKey string, handler *CommandHandler, session *discordgo.Session) (*InteractionServer, error) {
+	key, err := parseInteractionPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &InteractionServer{
+		publicKey: key,
+		handler:   handler,
+		respond: func(interaction *discordgo.Interaction, response CommandResponse) error {
+			return sendCommandResponse(session, interaction, response)
+		},
+		now: time.Now,
+	}, nil
+}
+
+// parseInteractionPublicKey 解析十六进制的 Ed25519 公钥
+func parseInteractionPublicKey(publicKey string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public_key must be a %d-byte hex-encoded Ed25519 key", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Start 在 addr 上监听，path 为接收 interactions 的路径
+func (s *InteractionServer) Start(addr, path string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, s)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("interactions 服务异常退出: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Close 停止接收新的请求，等待处理中的请求和后台执行中的命令完成
+func (s *InteractionServer) Close() error {
+	var err error
+	if s.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err = s.server.Shutdown(ctx)
+	}
+	s.pending.Wait()
+	return err
+}
+
+// ServeHTTP 校验签名后处理 interaction
+// PING 直接回复 PONG；斜杠命令先回复延迟响应（Discord 要求 3 秒内回复），再在后台获取价格并编辑响应
+func (s *InteractionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if !s.verify(r.Header, body) {
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+
+	var interaction discordgo.Interaction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		http.Error(w, "invalid interaction", http.StatusBadRequest)
+		return
+	}
+
+	switch interaction.Type {
+	case discordgo.InteractionPing:
+		writeInteractionResponse(w, discordgo.InteractionResponsePong)
+	case discordgo.InteractionApplicationCommand:
+		// 检查命令数据后再回复，缺少 data 时 ApplicationCommandData 会 panic，而后台 goroutine 中的 panic 会导致进程退出
+		data, ok := interaction.Data.(discordgo.ApplicationCommandInteractionData)
+		if !ok || data.Name == "" {
+			http.Error(w, "missing command data", http.StatusBadRequest)
+			return
+		}
+		writeInteractionResponse(w, discordgo.InteractionResponseDeferredChannelMessageWithSource)
+		s.pending.Add(1)
+		go func() {
+			defer s.pending.Done()
+			response := s.handler.Handle(interaction.GuildID, interaction.Locale, data)
+			if err := s.respond(&interaction, response); err != nil {
+				log.Printf("发送斜杠命令结果失败: %v", err)
+			}
+		}()
+	default:
+		http.Error(w, "unsupported interaction type", http.StatusBadRequest)
+	}
+}
+
+// verify 校验 X-Signature-Ed25519 签名，签名内容为 X-Signature-Timestamp 加上请求体
+// 时间戳（Unix 秒）与本机时间相差超过 interactionMaxClockSkew 的请求同样视为无效
+func (s *InteractionServer) verify(header http.Header, body []byte) bool {
+	signature, err := hex.DecodeString(header.Get("X-Signature-Ed25519"))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+	timestamp := header.Get("X-Signature-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := s.now().Sub(time.Unix(seconds, 0)); skew > interactionMaxClockSkew || skew < -interactionMaxClockSkew {
+		return false
+	}
+	return ed25519.Verify(s.publicKey, append([]byte(timestamp), body...), signature)
+}
+
+// writeInteractionResponse 回复只包含类型的 interaction 响应
+func writeInteractionResponse(w http.ResponseWriter, responseType discordgo.InteractionResponseType) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(discordgo.InteractionResponse{Type: responseType})
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTestInteractionServer 使用本地生成的密钥创建 interactions 服务，命令结果写入返回的通道
+func newTestInteractionServer(t *testing.T) (*InteractionServer, ed25519.PrivateKey, chan CommandResponse) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	handler, _ := newTestCommandHandler(t)
+	server, err := NewInteractionServer(hex.EncodeToString(publicKey), handler, nil)
+	if err != nil {
+		t.Fatalf("创建 interactions 服务失败: %v", err)
+	}
+	responses := make(chan CommandResponse, 1)
+	server.respond = func(interaction *discordgo.Interaction, response CommandResponse) error {
+		if interaction.Token != "interaction-token" {
+			t.Errorf("interaction token 错误: %q", interaction.Token)
+		}
+		responses <- response
+		return nil
+	}
+	return server, privateKey, responses
+}
+
+// postInteraction 发送用 key 签名的请求，返回响应
+func postInteraction(server *InteractionServer, key ed25519.PrivateKey, body string) *httptest.ResponseRecorder {
+	return postInteractionAt(server, key, body, time.Now())
+}
+
+// postInteractionAt 发送时间戳为 at 的签名请求
+func postInteractionAt(server *InteractionServer, key ed25519.PrivateKey, body string, at time.Time) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/interactions", strings.NewReader(body))
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body))))
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// TestInteractionServerPing 测试签名正确的 PING 回复 PONG
+func TestInteractionServerPing(t *testing.T) {
+	server, key, _ := newTestInteractionServer(t)
+
+	recorder := postInteraction(server, key, `{"type": 1, "id": "1", "application_id": "2", "token": "t"}`)
+	if recorder.Code != http.StatusOK || strings.TrimSpace(recorder.Body.String()) != `{"type":1}` {
+		t.Errorf("PING 应该回复 PONG，实际为 %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+// TestInteractionServerRejectsInvalidSignature 测试签名错误、缺失或请求体被篡改时返回 401
+func TestInteractionServerRejectsInvalidSignature(t *testing.T) {
+	server, key, _ := newTestInteractionServer(t)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	if recorder := postInteraction(server, otherKey, `{"type": 1}`); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("其他密钥签名的请求应该返回 401，实际为 %d", recorder.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/interactions", strings.NewReader(`{"type": 1}`))
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("没有签名的请求应该返回 401，实际为 %d", recorder.Code)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req = httptest.NewRequest(http.MethodPost, "/interactions", strings.NewReader(`{"type": 2}`))
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+`{"type": 1}`))))
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("请求体被篡改时应该返回 401，实际为 %d", recorder.Code)
+	}
+}
+
+// TestInteractionServerRejectsStaleTimestamp 测试签名正确但时间戳过旧或过新的请求返回 401，防止重放
+func TestInteractionServerRejectsStaleTimestamp(t *testing.T) {
+	server, key, _ := newTestInteractionServer(t)
+	now := time.Now()
+
+	for _, at := range []time.Time{now.Add(-time.Hour), now.Add(-6 * time.Minute), now.Add(6 * time.Minute)} {
+		if recorder := postInteractionAt(server, key, `{"type": 1}`, at); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("时间戳相差 %v 的请求应该返回 401，实际为 %d", at.Sub(now).Round(time.Minute), recorder.Code)
+		}
+	}
+	if recorder := postInteractionAt(server, key, `{"type": 1}`, now.Add(-time.Minute)); recorder.Code != http.StatusOK {
+		t.Errorf("时间戳相差 1 分钟的请求应该通过校验，实际为 %d", recorder.Code)
+	}
+}
+
+// TestInteractionServerCommand 测试斜杠命令先回复延迟响应，再发送 GenerateDiscordEmbed 生成的报表
+func TestInteractionServerCommand(t *testing.T) {
+	server, key, responses := newTestInteractionServer(t)
+
+	body := `{"type": 2, "id": "1", "application_id": "2", "token": "interaction-token", "guild_id": "g", "locale": "en-US",
+		"data": {"id": "3", "name": "report", "type": 1}}`
+	recorder := postInteraction(server, key, body)
+	if recorder.Code != http.StatusOK || strings.TrimSpace(recorder.Body.String()) != `{"type":5}` {
+		t.Fatalf("斜杠命令应该先回复延迟响应，实际为 %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	select {
+	case response := <-responses:
+		if len(response.Messages) != 1 || !strings.Contains(response.Messages[0][0].Title, "Daily Crypto Price Report") {
+			t.Errorf("/report 的结果错误: %+v", response)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有发送命令结果")
+	}
+}
+
+// TestInteractionServerMissingData 测试缺少命令数据的斜杠命令返回 400，不回复延迟响应
+func TestInteractionServerMissingData(t *testing.T) {
+	server, key, responses := newTestInteractionServer(t)
+
+	bodies := []string{
+		`{"type": 2, "id": "1", "application_id": "2", "token": "interaction-token"}`,
+		`{"type": 2, "id": "1", "application_id": "2", "token": "interaction-token", "data": null}`,
+		`{"type": 2, "id": "1", "application_id": "2", "token": "interaction-token", "data": {}}`,
+	}
+	for _, body := range bodies {
+		if recorder := postInteraction(server, key, body); recorder.Code != http.StatusBadRequest {
+			t.Errorf("缺少命令数据时应该返回 400，实际为 %d: %s", recorder.Code, body)
+		}
+	}
+	select {
+	case response := <-responses:
+		t.Errorf("缺少命令数据时不应该发送命令结果: %+v", response)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestInteractionServerCloseWaitsForCommands 测试 Close 等待后台执行中的命令发送完结果后才返回
+func TestInteractionServerCloseWaitsForCommands(t *testing.T) {
+	server, key, _ := newTestInteractionServer(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var responded atomic.Bool
+	server.respond = func(interaction *discordgo.Interaction, response CommandResponse) error {
+		close(started)
+		<-release
+		responded.Store(true)
+		return nil
+	}
+
+	body := `{"type": 2, "id": "1", "application_id": "2", "token": "interaction-token", "data": {"id": "3", "name": "report", "type": 1}}`
+	if recorder := postInteraction(server, key, body); recorder.Code != http.StatusOK {
+		t.Fatalf("斜杠命令应该回复延迟响应，实际为 %d", recorder.Code)
+	}
+	<-started
+
+	closed := make(chan struct{})
+	go func() {
+		server.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("命令还在执行时 Close 不应该返回")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("命令完成后 Close 应该返回")
+	}
+	if !responded.Load() {
+		t.Error("Close 返回前应该已经发送命令结果")
+	}
+}
+
+// TestParseInteractionPublicKey 测试公钥格式校验
+func TestParseInteractionPublicKey(t *testing.T) {
+	for _, key := range []string{"", "xyz", strings.Repeat("ab", 31)} {
+		if _, err := parseInteractionPublicKey(key); err == nil {
+			t.Errorf("无效的公钥应该返回错误: %q", key)
+		}
+	}
+	if _, err := parseInteractionPublicKey(strings.Repeat("ab", 32)); err != nil {
+		t.Errorf("有效的公钥不应该返回错误: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 报表中可以显示的列
+const (
+	columnName       = "name"
+	columnSymbol     = "symbol"
+	columnPrice      = "price"
+	columnChange     = "change"
+	columnChangePerc = "change_perc"
+	columnMarketCap  = "market_cap"
+	columnVolume     = "volume"
+)
+
+var allColumns = []string{
+	columnName, columnSymbol, columnPrice, columnChange, columnChangePerc, columnMarketCap, columnVolume,
+}
+
+// 未配置 report.columns 时各渲染器默认显示的列
+var (
+	defaultHTMLColumns    = allColumns
+	defaultDiscordColumns = []string{columnName, columnSymbol, columnPrice, columnChangePerc, columnMarketCap}
+)
+
+// 报表排序方式
+const (
+	sortByConfig    = "config"
+	sortByMarketCap = "market_cap"
+	sortByChange    = "change_24h"
+	sortByName      = "name"
+)
+
+// ReportLayout 描述报表的列、排序和分组方式
+type ReportLayout struct {
+	Columns   []string    // 为空时使用各渲染器的默认列
+	Sort      string      // config、market_cap、change_24h、name
+	Order     string      // asc 或 desc，为空时使用排序方式的自然顺序
+	Groups    []CoinGroup // 为空时不分组
+	CoinOrder []string    // 配置中的币种顺序，用于 config 排序
+}
+
+// CoinGroup 表示报表中的一个命名分组
+type CoinGroup struct {
+	Name  string   `yaml:"name"`
+	Coins []string `yaml:"coins"`
+}
+
+// ReportSection 表示报表中的一个分组区块
+type ReportSection struct {
+	Name     string // 未分组时为空
+	Coins    []CoinPrice
+	Subtotal ReportTotals
+}
+
+// newReportLayout 根据配置创建报表布局
+func newReportLayout(config *Config) ReportLayout {
+	return ReportLayout{
+		Columns:   config.Report.Columns,
+		Sort:      config.Report.Sort,
+		Order:     config.Report.Order,
+		Groups:    config.Report.Groups,
+		CoinOrder: config.Coins,
+	}
+}
+
+// validateReportLayout 校验报表布局配置
+func validateReportLayout(config *Config) error {
+	for _, column := range config.Report.Columns {
+		if !containsString(allColumns, column) {
+			return fmt.Errorf("report.columns: unknown column %q (supported: %s)", column, strings.Join(allColumns, ", "))
+		}
+	}
+
+	switch config.Report.Sort {
+	case "", sortByConfig, sortByMarketCap, sortByChange, sortByName:
+	default:
+		return fmt.Errorf("report.sort must be one of config, market_cap, change_24h, name")
+	}
+
+	switch config.Report.Order {
+	case "", "asc", "desc":
+	default:
+		return fmt.Errorf("report.order must be asc or desc")
+	}
+
+	seen := make(map[string]string)
+	for i, group := range config.Report.Groups {
+		if group.Name == "" {
+			return fmt.Errorf("report.groups[%d].name is required", i)
+		}
+		if len(group.Coins) == 0 {
+			return fmt.Errorf("report.groups[%d].coins is required", i)
+		}
+		for _, id := range group.Coins {
+			if !containsString(config.Coins, id) {
+				return fmt.Errorf("report.groups[%d]: coin %q is not listed in coins", i, id)
+			}
+			if other, ok := seen[id]; ok {
+				return fmt.Errorf("report.groups[%d]: coin %q already belongs to group %q", i, id, other)
+			}
+			seen[id] = group.Name
+		}
+	}
+
+	return nil
+}
+
+// columnsOr 返回配置的列，未配置时返回默认列
+func (l ReportLayout) columnsOr(defaults []string) []string {
+	if len(l.Columns) > 0 {
+		return l.Columns
+	}
+	return defaults
+}
+
+// sortCoins 返回按布局排序后的币种副本
+func (l ReportLayout) sortCoins(coins []CoinPrice) []CoinPrice {
+	sorted := make([]CoinPrice, len(coins))
+	copy(sorted, coins)
+
+	var less func(a, b CoinPrice) bool
+	desc := false
+	switch l.Sort {
+	case sortByMarketCap:
+		less = func(a, b CoinPrice) bool { return a.MarketCap < b.MarketCap }
+		desc = true
+	case sortByChange:
+		less = func(a, b CoinPrice) bool { return a.PriceChangePerc24h < b.PriceChangePerc24h }
+		desc = true
+	case sortByName:
+		less = func(a, b CoinPrice) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	default:
+		// 按配置顺序排序，不在配置中的币种排在最后并保持原有顺序
+		position := make(map[string]int, len(l.CoinOrder))
+		for i, id := range l.CoinOrder {
+			position[id] = i
+		}
+		index := func(c CoinPrice) int {
+			if i, ok := position[c.ID]; ok {
+				return i
+			}
+			return len(l.CoinOrder)
+		}
+		less = func(a, b CoinPrice) bool { return index(a) < index(b) }
+	}
+
+	if l.Order == "asc" {
+		desc = false
+	} else if l.Order == "desc" {
+		desc = true
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if desc {
+			return less(sorted[j], sorted[i])
+		}
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// buildSections 将排序后的币种按分组拆分，未归入任何分组的币种放在最后的 "其他" 分组
+func (l ReportLayout) buildSections(coins []CoinPrice, othersName string) []ReportSection {
+	if len(l.Groups) == 0 {
+		return []ReportSection{{Coins: coins, Subtotal: computeTotals(coins)}}
+	}
+
+	groupOf := make(map[string]int)
+	for i, group := range l.Groups {
+		for _, id := range group.Coins {
+			groupOf[id] = i
+		}
+	}
+
+	grouped := make([][]CoinPrice, len(l.Groups))
+	var others []CoinPrice
+	for _, coin := range coins {
+		if i, ok := groupOf[coin.ID]; ok {
+			grouped[i] = append(grouped[i], coin)
+		} else {
+			others = append(others, coin)
+		}
+	}
+
+	sections := make([]ReportSection, 0, len(l.Groups)+1)
+	for i, group := range l.Groups {
+		if len(grouped[i]) == 0 {
+			continue
+		}
+		sections = append(sections, ReportSection{
+			Name:     group.Name,
+			Coins:    grouped[i],
+			Subtotal: computeTotals(grouped[i]),
+		})
+	}
+	if len(others) > 0 {
+		sections = append(sections, ReportSection{
+			Name:     othersName,
+			Coins:    others,
+			Subtotal: computeTotals(others),
+		})
+	}
+	return sections
+}
+
+// computeTotals 计算一组币种的汇总数据
+func computeTotals(coins []CoinPrice) ReportTotals {
+	totals := ReportTotals{Count: len(coins)}
+	weighted := 0.0
+	for _, coin := range coins {
+		totals.MarketCap += coin.MarketCap
+		totals.Volume24h += coin.Volume24h
+		weighted += coin.PriceChangePerc24h * coin.MarketCap
+		switch {
+		case coin.PriceChangePerc24h > 0:
+			totals.Gainers++
+		case coin.PriceChangePerc24h < 0:
+			totals.Losers++
+		default:
+			totals.Unchanged++
+		}
+	}
+	if totals.MarketCap > 0 {
+		totals.WeightedChangePerc = weighted / totals.MarketCap
+	}
+	return totals
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// selectCoins 按 ids 筛选币种，ids 为空时返回全部币种
+func selectCoins(coins []CoinPrice, ids []string) []CoinPrice {
+	if len(ids) == 0 {
+		return coins
+	}
+	var selected []CoinPrice
+	for _, coin := range coins {
+		if containsString(ids, coin.ID) {
+			selected = append(selected, coin)
+		}
+	}
+	return selected
+}
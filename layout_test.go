@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// layoutTestCoins 返回按市值降序排列的测试数据（与 CoinGecko 返回顺序一致）
+func layoutTestCoins() []CoinPrice {
+	return []CoinPrice{
+		{ID: "bitcoin", Name: "Bitcoin", MarketCap: 850e9, PriceChangePerc24h: 2, Volume24h: 25e9},
+		{ID: "ethereum", Name: "Ethereum", MarketCap: 320e9, PriceChangePerc24h: -1, Volume24h: 15e9},
+		{ID: "uniswap", Name: "uniswap", MarketCap: 5e9, PriceChangePerc24h: 8, Volume24h: 1e8},
+		{ID: "dogecoin", Name: "Dogecoin", MarketCap: 20e9, PriceChangePerc24h: -6, Volume24h: 1e9},
+	}
+}
+
+func coinIDs(coins []CoinPrice) string {
+	ids := make([]string, len(coins))
+	for i, coin := range coins {
+		ids[i] = coin.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+// TestReportLayoutSortCoins 测试各种排序方式
+func TestReportLayoutSortCoins(t *testing.T) {
+	coinOrder := []string{"dogecoin", "ethereum", "bitcoin", "uniswap"}
+
+	tests := []struct {
+		sort  string
+		order string
+		want  string
+	}{
+		{"", "", "dogecoin,ethereum,bitcoin,uniswap"},
+		{"config", "desc", "uniswap,bitcoin,ethereum,dogecoin"},
+		{"market_cap", "", "bitcoin,ethereum,dogecoin,uniswap"},
+		{"market_cap", "asc", "uniswap,dogecoin,ethereum,bitcoin"},
+		{"change_24h", "", "uniswap,bitcoin,ethereum,dogecoin"},
+		{"name", "", "bitcoin,dogecoin,ethereum,uniswap"},
+	}
+
+	for _, tt := range tests {
+		layout := ReportLayout{Sort: tt.sort, Order: tt.order, CoinOrder: coinOrder}
+		if got := coinIDs(layout.sortCoins(layoutTestCoins())); got != tt.want {
+			t.Errorf("sort=%q order=%q 结果为 %s，期望 %s", tt.sort, tt.order, got, tt.want)
+		}
+	}
+}
+
+// TestReportLayoutBuildSections 测试分组及小计
+func TestReportLayoutBuildSections(t *testing.T) {
+	layout := ReportLayout{
+		Groups: []CoinGroup{
+			{Name: "Majors", Coins: []string{"bitcoin", "ethereum"}},
+			{Name: "DeFi", Coins: []string{"uniswap", "aave"}},
+			{Name: "Empty", Coins: []string{"solana"}},
+		},
+	}
+
+	sections := layout.buildSections(layoutTestCoins(), "Others")
+	if len(sections) != 3 {
+		t.Fatalf("期望 3 个分组（空分组被省略），实际为 %d", len(sections))
+	}
+
+	if sections[0].Name != "Majors" || coinIDs(sections[0].Coins) != "bitcoin,ethereum" {
+		t.Errorf("Majors 分组不正确: %s %s", sections[0].Name, coinIDs(sections[0].Coins))
+	}
+	if sections[2].Name != "Others" || coinIDs(sections[2].Coins) != "dogecoin" {
+		t.Errorf("未分组的币种应该放入 Others: %s %s", sections[2].Name, coinIDs(sections[2].Coins))
+	}
+
+	subtotal := sections[0].Subtotal
+	if subtotal.MarketCap != 1170e9 || subtotal.Volume24h != 40e9 || subtotal.Count != 2 {
+		t.Errorf("Majors 小计不正确: %+v", subtotal)
+	}
+	// (2*850 - 1*320) / 1170
+	if want := 1380.0 / 1170.0; subtotal.WeightedChangePerc < want-1e-9 || subtotal.WeightedChangePerc > want+1e-9 {
+		t.Errorf("加权涨跌幅为 %v，期望 %v", subtotal.WeightedChangePerc, want)
+	}
+
+	// 未配置分组时只有一个无名区块
+	single := ReportLayout{}.buildSections(layoutTestCoins(), "Others")
+	if len(single) != 1 || single[0].Name != "" || len(single[0].Coins) != 4 {
+		t.Errorf("未分组时应该只有一个无名区块: %+v", single)
+	}
+}
+
+// TestValidateReportLayout 测试布局配置校验
+func TestValidateReportLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{"默认配置", func(c *Config) {}, false},
+		{"合法配置", func(c *Config) {
+			c.Report.Columns = []string{"name", "price"}
+			c.Report.Sort = "market_cap"
+			c.Report.Order = "asc"
+			c.Report.Groups = []CoinGroup{{Name: "Majors", Coins: []string{"bitcoin"}}}
+		}, false},
+		{"未知列", func(c *Config) { c.Report.Columns = []string{"rank"} }, true},
+		{"未知排序", func(c *Config) { c.Report.Sort = "volume" }, true},
+		{"未知顺序", func(c *Config) { c.Report.Order = "up" }, true},
+		{"分组缺少名称", func(c *Config) { c.Report.Groups = []CoinGroup{{Coins: []string{"bitcoin"}}} }, true},
+		{"分组币种不在列表中", func(c *Config) {
+			c.Report.Groups = []CoinGroup{{Name: "Memes", Coins: []string{"dogecoin"}}}
+		}, true},
+		{"币种属于多个分组", func(c *Config) {
+			c.Report.Groups = []CoinGroup{
+				{Name: "A", Coins: []string{"bitcoin"}},
+				{Name: "B", Coins: []string{"bitcoin"}},
+			}
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Coins: []string{"bitcoin", "ethereum"}}
+			tt.modify(config)
+			err := validateReportLayout(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateReportLayout() error = %v，wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestSelectCoins 测试按收件人关注的币种筛选
+func TestSelectCoins(t *testing.T) {
+	if got := coinIDs(selectCoins(layoutTestCoins(), []string{"dogecoin", "bitcoin", "solana"})); got != "bitcoin,dogecoin" {
+		t.Errorf("筛选结果为 %s", got)
+	}
+	if got := coinIDs(selectCoins(layoutTestCoins(), nil)); got != "bitcoin,ethereum,uniswap,dogecoin" {
+		t.Errorf("未指定币种时应该返回全部币种: %s", got)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maildir 投递方式的目录格式
+const (
+	maildirFormatMaildir = "maildir" // Maildir 的 tmp/new/cur 结构，邮件客户端可以直接打开
+	maildirFormatEML     = "eml"     // 每封邮件一个 .eml 文件
+)
+
+// MaildirTransport 将邮件写入本地目录而不发送，用于离线环境或检查实际会发出的邮件
+// 文件内容是完整的邮件，前面加上记录信封的 Return-Path 和 X-Envelope-To 邮件头
+type MaildirTransport struct {
+	dir    string
+	format string
+	now    func() time.Time
+}
+
+// NewMaildirTransport 创建 maildir 投递方式，format 为空时使用 Maildir 格式
+func NewMaildirTransport(dir, format string) (*MaildirTransport, error) {
+	switch format {
+	case "":
+		format = maildirFormatMaildir
+	case maildirFormatMaildir, maildirFormatEML:
+	default:
+		return nil, fmt.Errorf("unsupported maildir format %q (supported: maildir, eml)", format)
+	}
+	return &MaildirTransport{dir: dir, format: format, now: time.Now}, nil
+}
+
+// Deliver 写入邮件，Maildir 格式先写入 tmp 再移动到 new，读取方不会看到写了一半的邮件
+func (t *MaildirTransport) Deliver(email *OutgoingEmail) error {
+	id, err := newOutboxID(t.now())
+	if err != nil {
+		return err
+	}
+	data := []byte("Return-Path: <" + email.From + ">\r\nX-Envelope-To: " + strings.Join(email.To, ", ") + "\r\n")
+	data = append(data, email.Message...)
+
+	if t.format == maildirFormatEML {
+		if err := writeFileAtomic(filepath.Join(t.dir, id+".eml"), data, 0600); err != nil {
+			return fmt.Errorf("failed to write email: %w", err)
+		}
+		return nil
+	}
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(t.dir, sub), 0700); err != nil {
+			return fmt.Errorf("failed to create maildir: %w", err)
+		}
+	}
+	name := maildirName(id)
+	tmp := filepath.Join(t.dir, "tmp", name)
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(t.dir, "new", name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to deliver email to maildir: %w", err)
+	}
+	return nil
+}
+
+// maildirName 返回 Maildir 中唯一的文件名：<时间和随机数>.<进程号>.<主机名>
+// 主机名中的 / 和 : 按惯例替换为八进制转义
+func maildirName(id string) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	host = strings.NewReplacer("/", `\057`, ":", `\072`).Replace(host)
+	return fmt.Sprintf("%s.P%d.%s", id, os.Getpid(), host)
+}
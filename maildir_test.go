@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMaildirTransport 测试邮件写入 Maildir 的 new 目录，并记录信封
+func TestMaildirTransport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "maildir")
+	transport, err := NewMaildirTransport(dir, "")
+	if err != nil {
+		t.Fatalf("创建 maildir 投递方式失败: %v", err)
+	}
+
+	message := "Subject: Report\r\n\r\nbody\r\n"
+	for i := 0; i < 2; i++ {
+		if err := transport.Deliver(&OutgoingEmail{From: "bot@example.com", To: []string{"a@test.com", "b@test.com"}, Message: []byte(message)}); err != nil {
+			t.Fatalf("写入邮件失败: %v", err)
+		}
+	}
+
+	for _, sub := range []string{"tmp", "cur"} {
+		if entries, err := os.ReadDir(filepath.Join(dir, sub)); err != nil || len(entries) != 0 {
+			t.Errorf("%s 目录应该存在且为空: %v", sub, err)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("new 目录中应该有 2 封邮件: %v", err)
+	}
+	if strings.Contains(entries[0].Name(), ":") || strings.Contains(entries[0].Name(), "/") {
+		t.Errorf("文件名不应该包含 : 或 /: %s", entries[0].Name())
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "new", entries[0].Name()))
+	want := "Return-Path: <bot@example.com>\r\nX-Envelope-To: a@test.com, b@test.com\r\n" + message
+	if string(data) != want {
+		t.Errorf("邮件内容不正确: %q", data)
+	}
+}
+
+// TestMaildirTransportEML 测试 eml 格式每封邮件一个文件
+func TestMaildirTransportEML(t *testing.T) {
+	dir := t.TempDir()
+	transport, err := NewMaildirTransport(dir, maildirFormatEML)
+	if err != nil {
+		t.Fatalf("创建 maildir 投递方式失败: %v", err)
+	}
+	transport.now = func() time.Time { return time.Date(2026, 10, 19, 1, 0, 0, 0, time.UTC) }
+
+	if err := transport.Deliver(&OutgoingEmail{From: "bot@example.com", To: []string{"a@test.com"}, Message: []byte("Subject: x\r\n\r\n")}); err != nil {
+		t.Fatalf("写入邮件失败: %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "20261019T010000Z-*.eml"))
+	if len(matches) != 1 {
+		t.Fatalf("应该生成 1 个 .eml 文件，实际为 %v", matches)
+	}
+
+	if _, err := NewMaildirTransport(dir, "mbox"); err == nil {
+		t.Error("不支持的格式应该返回错误")
+	}
+}
+
+// TestSchedulerMaildirTransport 测试 transport 为 maildir 时报表写入 data_dir/maildir
+func TestSchedulerMaildirTransport(t *testing.T) {
+	dataDir := t.TempDir()
+	content := `
+coingecko:
+  api_key: "test-api-key"
+data_dir: "` + dataDir + `"
+email:
+  transport: "maildir"
+  maildir:
+    format: "eml"
+  from: "bot@example.com"
+  to: ["a@test.com"]
+coins: ["bitcoin", "ethereum"]
+`
+	config, err := LoadConfig(createTempConfigFile(t, content))
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	scheduler := NewScheduler(config)
+	if !scheduler.sendEmailReport(emailRecipientGroup{To: []string{"a@test.com"}}, sampleCoins) {
+		t.Fatal("写入邮件报表失败")
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dataDir, "maildir", "*.eml"))
+	if len(matches) != 1 {
+		t.Fatalf("应该生成 1 个 .eml 文件，实际为 %v", matches)
+	}
+	data, _ := os.ReadFile(matches[0])
+	if !strings.Contains(string(data), "From: <bot@example.com>") || !strings.Contains(string(data), "multipart/alternative") {
+		t.Errorf("应该写入完整的报表邮件: %.200s", data)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mailgunEndpoints 是 Mailgun 各区域的 API 地址，未配置区域时使用美国区域
+var mailgunEndpoints = map[string]string{
+	"":   "https://api.mailgun.net",
+	"us": "https://api.mailgun.net",
+	"eu": "https://api.eu.mailgun.net",
+}
+
+// MailgunTransport 通过 Mailgun 的 messages.mime API 投递原始邮件
+type MailgunTransport struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewMailgunTransport 创建 Mailgun 投递方式，endpoint 为 API 地址（如 https://api.eu.mailgun.net）
+func NewMailgunTransport(domain, apiKey, endpoint string, client *http.Client) (*MailgunTransport, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("unsupported Mailgun region (supported: us, eu)")
+	}
+	return &MailgunTransport{
+		url:    strings.TrimSuffix(endpoint, "/") + "/v3/" + url.PathEscape(domain) + "/messages.mime",
+		apiKey: apiKey,
+		client: client,
+	}, nil
+}
+
+// Deliver 投递邮件，收件人使用信封地址，邮件内容作为 message 文件上传
+func (t *MailgunTransport) Deliver(email *OutgoingEmail) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, to := range email.To {
+		if err := form.WriteField("to", to); err != nil {
+			return err
+		}
+	}
+	part, err := form.CreateFormFile("message", "message.eml")
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(email.Message); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.SetBasicAuth("api", t.apiKey)
+	_, err = doTransportRequest(t.client, req, "Mailgun")
+	return err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMailgunTransport 测试 Mailgun 请求的地址、认证和表单内容
+func TestMailgunTransport(t *testing.T) {
+	var path, user, key, message string
+	var to []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		user, key, _ = r.BasicAuth()
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("解析表单失败: %v", err)
+			return
+		}
+		to = r.MultipartForm.Value["to"]
+		if files := r.MultipartForm.File["message"]; len(files) == 1 {
+			f, _ := files[0].Open()
+			data, _ := io.ReadAll(f)
+			f.Close()
+			message = string(data)
+		}
+		io.WriteString(w, `{"id":"<20261019.1@mg.example.com>","message":"Queued. Thank you."}`)
+	}))
+	defer server.Close()
+
+	transport, err := NewMailgunTransport("mg.example.com", "key-123", server.URL, server.Client())
+	if err != nil {
+		t.Fatalf("创建 Mailgun 投递方式失败: %v", err)
+	}
+	email := &OutgoingEmail{From: "bot@example.com", To: []string{"a@test.com", "b@test.com"}, Message: []byte("Subject: Report\r\n\r\nbody\r\n")}
+	if err := transport.Deliver(email); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+
+	if path != "/v3/mg.example.com/messages.mime" {
+		t.Errorf("请求路径 = %s", path)
+	}
+	if user != "api" || key != "key-123" {
+		t.Errorf("Basic 认证不正确: %s:%s", user, key)
+	}
+	if strings.Join(to, ",") != "a@test.com,b@test.com" {
+		t.Errorf("收件人 = %v", to)
+	}
+	if message != string(email.Message) {
+		t.Errorf("邮件内容不正确: %q", message)
+	}
+}
+
+// TestMailgunEndpoints 测试区域对应的 API 地址
+func TestMailgunEndpoints(t *testing.T) {
+	transport, err := NewMailgunTransport("mg.example.com", "key", mailgunEndpoints["eu"], nil)
+	if err != nil {
+		t.Fatalf("创建 Mailgun 投递方式失败: %v", err)
+	}
+	if transport.url != "https://api.eu.mailgun.net/v3/mg.example.com/messages.mime" {
+		t.Errorf("EU 区域的地址 = %s", transport.url)
+	}
+	if _, err := NewMailgunTransport("mg.example.com", "key", mailgunEndpoints["asia"], nil); err == nil {
+		t.Error("不支持的区域应该返回错误")
+	}
+}
@@ -2,15 +2,19 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func main() {
 	configPath := flag.String("config", "config.yaml", "配置文件路径")
 	once := flag.Bool("once", false, "只运行一次，不启动定时任务")
+	outbox := flag.String("outbox", "", "管理发件箱: list 列出待重试和失败的邮件，flush 立即重新发送待重试的邮件")
 	flag.Parse()
 
 	log.Println("CoinDaily - 每日加密货币价格报表工具启动中...")
@@ -30,20 +34,47 @@ func main() {
 		log.Println("邮件通知未配置")
 	}
 
-	if isDiscordConfigured(config) {
+	if len(config.Discord.Targets) > 0 {
+		log.Printf("Discord 通知已启用，共 %d 个发送目标", len(discordTargets(config)))
+	} else if config.Discord.WebhookURL != "" {
+		log.Println("Discord 通知已启用，通过 webhook 发送")
+	} else if isDiscordConfigured(config) {
 		log.Printf("Discord 通知已启用，频道 ID: %s", config.Discord.ChannelID)
 	} else {
 		log.Println("Discord 通知未配置")
 	}
+	if config.Discord.Board.Enabled {
+		log.Printf("Discord 实时看板已启用，频道 ID: %s", boardChannelID(config))
+	}
 
 	scheduler := NewScheduler(config)
 
+	if *outbox != "" {
+		if err := runOutboxCommand(scheduler, *outbox); err != nil {
+			log.Fatalf("发件箱命令执行失败: %v", err)
+		}
+		return
+	}
+
 	if *once {
 		log.Println("单次运行模式，生成并发送报表后退出...")
-		scheduler.runDailyReport()
+		scheduler.retryOutbox(false)
+		scheduler.runReport(true, scheduler.discordTargets)
+		scheduler.updateBoard(time.Now())
 		return
 	}
 
+	// 斜杠命令启动失败不影响定时报表
+	var commands io.Closer
+	if config.Discord.Commands.Enabled {
+		commands, err = startSlashCommands(config, scheduler)
+		if err != nil {
+			log.Printf("启动 Discord 斜杠命令失败: %v", err)
+		} else {
+			log.Println("Discord 斜杠命令已启用: /price、/report、/watchlist")
+		}
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -54,5 +85,96 @@ func main() {
 
 	log.Println("收到停止信号，正在关闭...")
 	scheduler.Stop()
+	if commands != nil {
+		commands.Close()
+	}
 	log.Println("CoinDaily 已停止")
-}
\ No newline at end of file
+}
+
+// startSlashCommands 注册斜杠命令并开始接收，与定时报表共用 CoinGecko 客户端和报表模板
+// gateway 模式连接 Discord Gateway，http 模式启动 interactions 接收服务
+func startSlashCommands(config *Config, scheduler *Scheduler) (io.Closer, error) {
+	commands := config.Discord.Commands
+	handler, err := NewCommandHandler(config, scheduler.coinClient, scheduler.reportGen.ForLocale(discordLocale(config)))
+	if err != nil {
+		return nil, err
+	}
+	handler.SetHistory(scheduler.history)
+	proxy := serviceProxyURL(config, config.Proxy.Discord)
+
+	if commands.Mode != commandModeHTTP {
+		bot, err := NewGatewayBot(config.Discord.BotToken, commands.GuildID, handler, proxy)
+		if err != nil {
+			return nil, err
+		}
+		if err := bot.Start(); err != nil {
+			return nil, err
+		}
+		return bot, nil
+	}
+
+	session, err := newDiscordSession(config.Discord.BotToken, proxy)
+	if err != nil {
+		return nil, err
+	}
+	app, err := session.Application("@me")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Discord application: %w", err)
+	}
+	if err := registerSlashCommands(session, app.ID, commands.GuildID); err != nil {
+		return nil, err
+	}
+	server, err := NewInteractionServer(commands.PublicKey, handler, session)
+	if err != nil {
+		return nil, err
+	}
+	listen := firstNonEmpty(commands.Listen, defaultInteractionsListen)
+	path := firstNonEmpty(commands.Path, defaultInteractionsPath)
+	if err := server.Start(listen, path); err != nil {
+		return nil, err
+	}
+	log.Printf("Discord interactions 服务已启动: %s%s", listen, path)
+	return server, nil
+}
+
+// runOutboxCommand 执行 -outbox 命令
+func runOutboxCommand(scheduler *Scheduler, command string) error {
+	if scheduler.outbox == nil {
+		return fmt.Errorf("邮件未配置")
+	}
+
+	switch command {
+	case "list":
+		return printOutbox(os.Stdout, scheduler.outbox)
+	case "flush":
+		scheduler.retryOutbox(true)
+		return printOutbox(os.Stdout, scheduler.outbox)
+	default:
+		return fmt.Errorf("未知的发件箱命令 %q（支持 list、flush）", command)
+	}
+}
+
+// printOutbox 输出发件箱中的邮件
+func printOutbox(w io.Writer, outbox *Outbox) error {
+	pending, err := outbox.Pending()
+	if err != nil {
+		return err
+	}
+	failed, err := outbox.Failed()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "待重试 (%d):\n", len(pending))
+	for _, entry := range pending {
+		fmt.Fprintf(w, "  %s  %s  %v  尝试 %d 次，下次 %s\n    %s\n",
+			entry.ID, entry.Subject, entry.To, entry.Attempts,
+			entry.NextAttempt.Local().Format("2006-01-02 15:04:05"), entry.LastError)
+	}
+	fmt.Fprintf(w, "已放弃 (%d):\n", len(failed))
+	for _, entry := range failed {
+		fmt.Fprintf(w, "  %s  %s  %v  尝试 %d 次\n    %s\n",
+			entry.ID, entry.Subject, entry.To, entry.Attempts, entry.LastError)
+	}
+	return nil
+}
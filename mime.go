@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 正文的传输编码
+const (
+	encodingQuotedPrintable = "quoted-printable"
+	encodingBase64          = "base64"
+)
+
+// 邮件行长度限制（RFC 5322 建议 78，RFC 2045 要求编码后的正文不超过 76）
+const (
+	headerLineLength = 78
+	base64LineLength = 76
+)
+
+// MessageBuilder 构建符合 RFC 5322 和 RFC 2045-2047 的邮件
+// 邮件头按固定顺序输出，非 ASCII 的主题和显示名称使用 encoded-word 编码
+type MessageBuilder struct {
+	From             mail.Address
+	To               []string
+	ReplyTo          string // 可选
+	ListID           string // 可选，如 "Crypto Daily <crypto-daily.example.com>"
+	Subject          string
+	Date             time.Time    // 为零值时使用当前时间
+	MessageID        string       // 为空时自动生成，不含尖括号
+	Boundary         string       // 为空时随机生成，测试中固定以便比对
+	TransferEncoding string       // quoted-printable（默认）或 base64
+	Attachments      []Attachment // 附件和内嵌图片（可选）
+}
+
+// header 是一个有序的邮件头
+type header struct {
+	name  string
+	value string
+}
+
+// Build 生成完整的邮件内容，textContent 非空时使用 multipart/alternative
+func (b *MessageBuilder) Build(htmlContent, textContent string) ([]byte, error) {
+	bodyHeaders, body, err := b.buildBody(htmlContent, textContent)
+	if err != nil {
+		return nil, err
+	}
+
+	date := b.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	messageID := b.MessageID
+	if messageID == "" {
+		if messageID, err = generateMessageID(b.From.Address, date); err != nil {
+			return nil, err
+		}
+	}
+
+	headers := []header{
+		{"Date", date.Format(time.RFC1123Z)},
+		{"From", formatAddress(b.From)},
+	}
+	if b.ReplyTo != "" {
+		replyTo, err := mail.ParseAddress(b.ReplyTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Reply-To address: %w", err)
+		}
+		headers = append(headers, header{"Reply-To", formatAddress(*replyTo)})
+	}
+	// 密送时 To 为空，使用空的收件人组（RFC 5322 3.4）
+	to := "undisclosed-recipients:;"
+	if len(b.To) > 0 {
+		if to, err = formatAddressList(b.To); err != nil {
+			return nil, err
+		}
+	}
+	headers = append(headers,
+		header{"To", to},
+		header{"Subject", encodeHeaderText(stripControlChars(b.Subject))},
+		header{"Message-ID", "<" + messageID + ">"},
+	)
+	if b.ListID != "" {
+		headers = append(headers, header{"List-Id", formatListID(b.ListID)})
+	}
+	headers = append(headers, header{"MIME-Version", "1.0"})
+	headers = append(headers, bodyHeaders...)
+
+	var buf bytes.Buffer
+	for _, h := range headers {
+		buf.WriteString(foldHeader(h.name, h.value))
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+// buildBody 构建邮件正文及其 Content-Type 等邮件头，结构为：
+//
+//	multipart/mixed（有附件时）
+//	├── multipart/alternative（提供纯文本版本时）
+//	│   ├── text/plain
+//	│   └── multipart/related（有内嵌图片时）
+//	│       ├── text/html
+//	│       └── image/png ...
+//	└── 附件 ...
+//
+// 纯文本在前、HTML 在后（客户端优先显示最后一个）
+func (b *MessageBuilder) buildBody(htmlContent, textContent string) ([]header, []byte, error) {
+	encoding := b.TransferEncoding
+	if encoding == "" {
+		encoding = encodingQuotedPrintable
+	}
+
+	var inline, attached []mimePart
+	for _, attachment := range b.Attachments {
+		if attachment.ContentID != "" {
+			inline = append(inline, attachment.part())
+		} else {
+			attached = append(attached, attachment.part())
+		}
+	}
+
+	body, err := textPart("text/html; charset=utf-8", htmlContent, encoding)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(inline) > 0 {
+		if body, err = b.multipartPart("related", "-related", append([]mimePart{body}, inline...)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if textContent != "" {
+		text, err := textPart("text/plain; charset=utf-8", textContent, encoding)
+		if err != nil {
+			return nil, nil, err
+		}
+		if body, err = b.multipartPart("alternative", "", []mimePart{text, body}); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(attached) > 0 {
+		if body, err = b.multipartPart("mixed", "-mixed", append([]mimePart{body}, attached...)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	headers := []header{{"Content-Type", body.header.Get("Content-Type")}}
+	if cte := body.header.Get("Content-Transfer-Encoding"); cte != "" {
+		headers = append(headers, header{"Content-Transfer-Encoding", cte})
+	}
+	return headers, body.body, nil
+}
+
+// Attachment 是邮件附件或内嵌图片
+type Attachment struct {
+	Filename    string
+	ContentType string // 如 "text/csv; charset=utf-8"，为空时按扩展名推断
+	ContentID   string // 非空时作为内嵌图片放入 multipart/related，HTML 中用 cid:<ContentID> 引用
+	Data        []byte
+}
+
+// part 返回附件的 MIME 部分，内容使用 base64 编码
+func (a Attachment) part() mimePart {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(a.Filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	disposition := "attachment"
+	header := textproto.MIMEHeader{
+		"Content-Transfer-Encoding": {encodingBase64},
+	}
+	if a.ContentID != "" {
+		disposition = "inline"
+		header.Set("Content-ID", "<"+a.ContentID+">")
+	}
+	header.Set("Content-Type", contentType)
+	// 非 ASCII 文件名按 RFC 2231 编码
+	if a.Filename != "" {
+		disposition = mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename})
+	}
+	header.Set("Content-Disposition", disposition)
+
+	var body bytes.Buffer
+	encodeBase64Lines(&body, a.Data)
+	return mimePart{header: header, body: body.Bytes()}
+}
+
+// mimePart 是一个已编码的 MIME 部分
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// textPart 创建按指定传输编码的文本部分
+func textPart(contentType, content, encoding string) (mimePart, error) {
+	var body bytes.Buffer
+	if err := encodeBody(&body, content, encoding); err != nil {
+		return mimePart{}, err
+	}
+	return mimePart{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {encoding},
+		},
+		body: body.Bytes(),
+	}, nil
+}
+
+// multipartPart 将多个部分组合为 multipart/<subtype>
+// 设置了 Boundary 时各层使用 Boundary 加上 suffix，保证嵌套的分隔符互不相同
+func (b *MessageBuilder) multipartPart(subtype, suffix string, parts []mimePart) (mimePart, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if b.Boundary != "" {
+		if err := writer.SetBoundary(b.Boundary + suffix); err != nil {
+			return mimePart{}, fmt.Errorf("invalid MIME boundary: %w", err)
+		}
+	}
+	for _, part := range parts {
+		w, err := writer.CreatePart(part.header)
+		if err != nil {
+			return mimePart{}, fmt.Errorf("failed to create MIME part: %w", err)
+		}
+		if _, err := w.Write(part.body); err != nil {
+			return mimePart{}, fmt.Errorf("failed to write MIME part: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return mimePart{}, fmt.Errorf("failed to close MIME writer: %w", err)
+	}
+
+	params := map[string]string{"boundary": writer.Boundary()}
+	if subtype == "related" {
+		params["type"] = "text/html"
+	}
+	return mimePart{
+		header: textproto.MIMEHeader{"Content-Type": {mime.FormatMediaType("multipart/"+subtype, params)}},
+		body:   body.Bytes(),
+	}, nil
+}
+
+// encodeBody 按指定的传输编码写入正文，保证每行长度不超过 SMTP 限制
+func encodeBody(w io.Writer, content, encoding string) error {
+	switch encoding {
+	case encodingQuotedPrintable:
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(content)); err != nil {
+			return fmt.Errorf("failed to encode body: %w", err)
+		}
+		if err := qp.Close(); err != nil {
+			return fmt.Errorf("failed to encode body: %w", err)
+		}
+		_, err := io.WriteString(w, "\r\n")
+		return err
+	case encodingBase64:
+		return encodeBase64Lines(w, []byte(content))
+	default:
+		return fmt.Errorf("unsupported transfer encoding %q", encoding)
+	}
+}
+
+// encodeBase64Lines 以 base64 编码写入数据，每行不超过 base64LineLength 个字符
+func encodeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := base64LineLength
+		if n > len(encoded) {
+			n = len(encoded)
+		}
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+// encodedWordLength 是单个 encoded-word 的目标长度，加上邮件头名称后仍不超过 78 个字符
+const encodedWordLength = 60
+
+// encodeHeaderText 按 RFC 2047 编码包含非 ASCII 字符的邮件头文本
+// Q 编码和 B 编码中选择较短的一种：西文主题通常用 Q 编码，中文主题用 B 编码。
+// 文本按字符拆分为多个 encoded-word，每个都不超过 encodedWordLength，折行后不会超长。
+// 纯 ASCII 文本原样输出，除非其中包含 "=?"，否则会被收件端误当作 encoded-word 解码
+func encodeHeaderText(s string) string {
+	if isASCII(s) && !strings.Contains(s, "=?") {
+		return s
+	}
+	encoding := byte('q')
+	if len(encodeWord('b', s)) < len(encodeWord('q', s)) {
+		encoding = 'b'
+	}
+
+	var words []string
+	chunk := ""
+	for _, r := range s {
+		next := chunk + string(r)
+		if chunk != "" && len(encodeWord(encoding, next)) > encodedWordLength {
+			words = append(words, encodeWord(encoding, chunk))
+			next = string(r)
+		}
+		chunk = next
+	}
+	words = append(words, encodeWord(encoding, chunk))
+	return strings.Join(words, " ")
+}
+
+// encodeWord 将文本编码为单个 encoded-word，encoding 为 'b' 或 'q'
+// 与 mime.WordEncoder 不同，纯 ASCII 文本同样会被编码，保证相邻的 encoded-word 解码后无缝拼接
+func encodeWord(encoding byte, s string) string {
+	if encoding == 'b' {
+		return "=?utf-8?b?" + base64.StdEncoding.EncodeToString([]byte(s)) + "?="
+	}
+
+	var b strings.Builder
+	b.WriteString("=?utf-8?q?")
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ':
+			b.WriteByte('_')
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', strings.IndexByte("!*+-/", c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "=%02X", c)
+		}
+	}
+	b.WriteString("?=")
+	return b.String()
+}
+
+// formatAddress 格式化单个地址，非 ASCII 的显示名称按 RFC 2047 编码
+func formatAddress(address mail.Address) string {
+	if address.Name == "" || (isASCII(address.Name) && !strings.Contains(address.Name, "=?")) {
+		return address.String()
+	}
+	return encodeHeaderText(address.Name) + " <" + address.Address + ">"
+}
+
+// formatAddressList 解析并格式化收件人列表
+func formatAddressList(addresses []string) (string, error) {
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return "", fmt.Errorf("invalid recipient address %q: %w", address, err)
+		}
+		formatted = append(formatted, formatAddress(*parsed))
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+// formatListID 格式化 List-Id 邮件头（RFC 2919），只有标识时自动加上尖括号
+func formatListID(value string) string {
+	i := strings.LastIndex(value, "<")
+	if i < 0 {
+		return "<" + strings.TrimSpace(value) + ">"
+	}
+	phrase := strings.TrimSpace(value[:i])
+	id := strings.TrimSpace(value[i:])
+	if phrase == "" {
+		return id
+	}
+	return encodeHeaderText(phrase) + " " + id
+}
+
+// generateMessageID 生成全局唯一的 Message-ID（不含尖括号），域名取自发件人地址
+func generateMessageID(from string, at time.Time) (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate Message-ID: %w", err)
+	}
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 && i < len(from)-1 {
+		domain = from[i+1:]
+	}
+	return fmt.Sprintf("%d.%s@%s", at.UnixNano(), hex.EncodeToString(random), domain), nil
+}
+
+// foldHeader 输出一行邮件头，超过 78 个字符时在空白处折行（RFC 5322 2.2.3）
+// encoded-word 内部不含空白，因此折行不会破坏编码
+func foldHeader(name, value string) string {
+	var b strings.Builder
+	line := name + ":"
+	for i, word := range strings.Split(value, " ") {
+		if i > 0 && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > headerLineLength {
+			b.WriteString(line + "\r\n")
+			line = " " + word
+			continue
+		}
+		line += " " + word
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
+
+// isASCII 判断字符串是否只包含可打印的 ASCII 字符
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
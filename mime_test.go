@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "更新 testdata 中的 golden 文件")
+
+// testMessageBuilder 返回邮件头固定的 MessageBuilder，便于和 golden 文件比对
+func testMessageBuilder() *MessageBuilder {
+	return &MessageBuilder{
+		From:      mail.Address{Address: "bot@example.com"},
+		To:        []string{"alice@example.com"},
+		Subject:   "Daily Crypto Price Report - October 19, 2026",
+		Date:      time.Date(2026, 10, 19, 9, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+		MessageID: "1760835600.0123456789abcdef@example.com",
+		Boundary:  "coindaily-test-boundary",
+	}
+}
+
+// TestMessageBuilderGolden 测试生成的邮件与 testdata 中的 golden 文件一致
+func TestMessageBuilderGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *MessageBuilder)
+		html  string
+		text  string
+	}{
+		{
+			name:  "html_only",
+			build: func(b *MessageBuilder) {},
+			html:  "<p>Bitcoin $45,000.00</p>",
+		},
+		{
+			name: "chinese_multipart",
+			build: func(b *MessageBuilder) {
+				b.From.Name = "加密货币日报"
+				b.To = []string{"张三 <zhangsan@example.com>", "bob@example.com"}
+				b.ReplyTo = "Support Team <support@example.com>"
+				b.ListID = "每日报表 <crypto-daily.example.com>"
+				b.Subject = "每日加密货币价格报表 - 2026年10月19日"
+			},
+			html: "<p>比特币 $45,000.00</p>",
+			text: "比特币  $45,000.00",
+		},
+		{
+			name: "base64_long_subject",
+			build: func(b *MessageBuilder) {
+				b.From.Name = "Crypto Daily"
+				b.ListID = "crypto-daily.example.com"
+				b.Subject = "Daily Crypto Price Report with a deliberately long subject line – Bitcoin, Ethereum and friends"
+				b.TransferEncoding = encodingBase64
+			},
+			html: "<p>Bitcoin $45,000.00</p>",
+			text: "Bitcoin  $45,000.00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := testMessageBuilder()
+			tt.build(builder)
+			got, err := builder.Build(tt.html, tt.text)
+			if err != nil {
+				t.Fatalf("Build 失败: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".eml")
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatalf("写入 golden 文件失败: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("读取 golden 文件失败: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("邮件内容与 %s 不一致（使用 -update 更新）:\n%s", golden, got)
+			}
+
+			for _, line := range strings.Split(string(got), "\r\n") {
+				if len(line) > headerLineLength {
+					t.Errorf("行长度超过 %d: %q", headerLineLength, line)
+				}
+			}
+		})
+	}
+}
+
+// TestMessageBuilderHeaders 测试编码后的邮件头可以被标准库正确解析
+func TestMessageBuilderHeaders(t *testing.T) {
+	builder := testMessageBuilder()
+	builder.From.Name = "加密货币日报"
+	builder.ReplyTo = "support@example.com"
+	builder.ListID = "每日报表 <crypto-daily.example.com>"
+	builder.Subject = strings.Repeat("每日加密货币价格报表 ", 6)
+
+	raw, err := builder.Build("<p>x</p>", "")
+	if err != nil {
+		t.Fatalf("Build 失败: %v", err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("解析邮件失败: %v", err)
+	}
+
+	decoder := new(mime.WordDecoder)
+	subject, err := decoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("解码主题失败: %v", err)
+	}
+	if subject != builder.Subject {
+		t.Errorf("主题解码后不一致: %q", subject)
+	}
+
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) != 1 || from[0].Name != "加密货币日报" || from[0].Address != "bot@example.com" {
+		t.Errorf("From 邮件头不正确: %v %v", from, err)
+	}
+	if got := msg.Header.Get("Message-ID"); got != "<1760835600.0123456789abcdef@example.com>" {
+		t.Errorf("Message-ID 不正确: %s", got)
+	}
+	if got, _ := decoder.DecodeHeader(msg.Header.Get("List-Id")); got != "每日报表 <crypto-daily.example.com>" {
+		t.Errorf("List-Id 不正确: %s", got)
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date 邮件头无法解析: %v", err)
+	}
+}
+
+// TestMessageBuilderGeneratesMessageID 测试未指定时自动生成唯一的 Message-ID
+func TestMessageBuilderGeneratesMessageID(t *testing.T) {
+	ids := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		builder := testMessageBuilder()
+		builder.MessageID = ""
+		builder.Date = time.Time{}
+
+		raw, err := builder.Build("<p>x</p>", "")
+		if err != nil {
+			t.Fatalf("Build 失败: %v", err)
+		}
+		msg, _ := mail.ReadMessage(bytes.NewReader(raw))
+		id := msg.Header.Get("Message-ID")
+		if !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.com>") {
+			t.Errorf("Message-ID 格式不正确: %s", id)
+		}
+		ids[id] = true
+	}
+	if len(ids) != 2 {
+		t.Error("每封邮件的 Message-ID 应该不同")
+	}
+}
+
+// TestMessageBuilderInvalidAddress 测试无效的收件人和回复地址
+func TestMessageBuilderInvalidAddress(t *testing.T) {
+	builder := testMessageBuilder()
+	builder.To = []string{"not an address"}
+	if _, err := builder.Build("<p>x</p>", ""); err == nil {
+		t.Error("无效的收件人地址应该返回错误")
+	}
+
+	builder = testMessageBuilder()
+	builder.ReplyTo = "@@"
+	if _, err := builder.Build("<p>x</p>", ""); err == nil {
+		t.Error("无效的 Reply-To 地址应该返回错误")
+	}
+}
+
+// TestMessageBuilderAttachments 测试带附件和内嵌图片的邮件结构：
+// multipart/mixed 包含 multipart/alternative 和附件，HTML 与内嵌图片放在 multipart/related 中
+func TestMessageBuilderAttachments(t *testing.T) {
+	builder := testMessageBuilder()
+	builder.Attachments = []Attachment{
+		{Filename: "数据.csv", ContentType: "text/csv; charset=utf-8", Data: []byte("id,symbol\r\nbitcoin,btc\r\n")},
+		{Filename: "chart.png", ContentID: "chart@coindaily", Data: []byte("\x89PNG")},
+	}
+	data, err := builder.Build(`<img src="cid:chart@coindaily">`, "text")
+	if err != nil {
+		t.Fatalf("生成邮件失败: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("解析邮件失败: %v", err)
+	}
+	mixed := readMultipart(t, msg.Header.Get("Content-Type"), msg.Body, "multipart/mixed")
+	if len(mixed) != 2 {
+		t.Fatalf("multipart/mixed 应该有 2 个部分，实际 %d 个", len(mixed))
+	}
+
+	alternative := readMultipart(t, mixed[0].header.Get("Content-Type"), bytes.NewReader(mixed[0].body), "multipart/alternative")
+	if len(alternative) != 2 {
+		t.Fatalf("multipart/alternative 应该有 2 个部分，实际 %d 个", len(alternative))
+	}
+	if ct := alternative[0].header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("第一个备选部分应该是纯文本，实际 %s", ct)
+	}
+
+	related := readMultipart(t, alternative[1].header.Get("Content-Type"), bytes.NewReader(alternative[1].body), "multipart/related")
+	if len(related) != 2 {
+		t.Fatalf("multipart/related 应该有 2 个部分，实际 %d 个", len(related))
+	}
+	if ct := related[0].header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("related 的第一个部分应该是 HTML，实际 %s", ct)
+	}
+	image := related[1]
+	if got := image.header.Get("Content-ID"); got != "<chart@coindaily>" {
+		t.Errorf("Content-ID = %q, 期望 <chart@coindaily>", got)
+	}
+	if got := image.header.Get("Content-Type"); got != "image/png" {
+		t.Errorf("内嵌图片的类型应该按扩展名推断为 image/png，实际 %s", got)
+	}
+	if disposition, _, _ := mime.ParseMediaType(image.header.Get("Content-Disposition")); disposition != "inline" {
+		t.Errorf("内嵌图片应该是 inline，实际 %s", disposition)
+	}
+	if decoded, _ := base64.StdEncoding.DecodeString(string(image.body)); string(decoded) != "\x89PNG" {
+		t.Errorf("内嵌图片内容不正确: %q", decoded)
+	}
+
+	attachment := mixed[1]
+	disposition, params, err := mime.ParseMediaType(attachment.header.Get("Content-Disposition"))
+	if err != nil || disposition != "attachment" {
+		t.Fatalf("附件的 Content-Disposition 不正确: %q", attachment.header.Get("Content-Disposition"))
+	}
+	if params["filename"] != "数据.csv" {
+		t.Errorf("附件文件名 = %q, 期望 数据.csv", params["filename"])
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(attachment.body), "\r\n", ""))
+	if string(decoded) != "id,symbol\r\nbitcoin,btc\r\n" {
+		t.Errorf("附件内容不正确: %q", decoded)
+	}
+
+}
+
+// testPart 是测试中解析出的 MIME 部分
+type testPart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// readMultipart 检查 Content-Type 并读取 multipart 的各个部分（不解码传输编码）
+func readMultipart(t *testing.T, contentType string, body io.Reader, want string) []testPart {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != want {
+		t.Fatalf("Content-Type = %q, 期望 %s", contentType, want)
+	}
+	var parts []testPart
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatalf("读取 %s 失败: %v", want, err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("读取 %s 失败: %v", want, err)
+		}
+		parts = append(parts, testPart{header: part.Header, body: data})
+	}
+}
+
+// TestEncodeHeaderText 测试 RFC 2047 编码的选择
+func TestEncodeHeaderText(t *testing.T) {
+	if got := encodeHeaderText("Daily Report"); got != "Daily Report" {
+		t.Errorf("纯 ASCII 文本不应该编码: %s", got)
+	}
+	if got := encodeHeaderText("每日报表"); !strings.HasPrefix(got, "=?utf-8?b?") {
+		t.Errorf("中文文本应该使用 B 编码: %s", got)
+	}
+	if got := encodeHeaderText("Café report"); !strings.HasPrefix(got, "=?utf-8?q?") {
+		t.Errorf("以 ASCII 为主的文本应该使用 Q 编码: %s", got)
+	}
+
+	// 拆分为多个 encoded-word 后解码结果不变，不会在拆分处多出空格
+	decoder := new(mime.WordDecoder)
+	for _, s := range []string{
+		"Daily Crypto Price Report with a deliberately long subject line – Bitcoin, Ethereum and friends",
+		strings.Repeat("比特币 ", 20),
+		"Report =?utf-8?q?not_a_word?= _underscore_ 100%",
+	} {
+		encoded := encodeHeaderText(s)
+		for _, word := range strings.Split(encoded, " ") {
+			if len(word) > encodedWordLength {
+				t.Errorf("encoded-word 长度超过 %d: %s", encodedWordLength, word)
+			}
+		}
+		if got, err := decoder.DecodeHeader(encoded); err != nil || got != s {
+			t.Errorf("解码结果不一致: %q, 期望 %q (%v)", got, s, err)
+		}
+	}
+}
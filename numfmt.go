@@ -0,0 +1,178 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// NumberFormat 描述一个语言区域的数字格式约定
+type NumberFormat struct {
+	Decimal string        // 小数点
+	Group   string        // 千位分隔符
+	Compact []compactUnit // 紧凑表示的单位，按数值从大到小排列
+}
+
+// compactUnit 表示紧凑表示中的一个数量级单位
+type compactUnit struct {
+	Value  float64
+	Suffix string
+}
+
+// 价格小于 1 时保留的有效数字位数，以及最多保留的小数位数
+const (
+	priceSignificantDigits = 4
+	maxPriceDecimals       = 12
+)
+
+// compactDecimals 是紧凑单位格式保留的小数位数
+const compactDecimals = 2
+
+// currencySymbols 是常见计价货币的符号
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CNY": "¥",
+	"BTC": "₿",
+}
+
+// currencySymbol 返回货币代码对应的符号，未知货币返回代码加空格
+func currencySymbol(code string) string {
+	if symbol, ok := currencySymbols[strings.ToUpper(code)]; ok {
+		return symbol
+	}
+	return strings.ToUpper(code) + " "
+}
+
+// FormatDecimal 按固定小数位格式化数字，带千位分隔符，负数带 "-" 号
+func (f NumberFormat) FormatDecimal(v float64, decimals int) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	digits := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	intPart, fracPart := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		intPart, fracPart = digits[:i], digits[i+1:]
+	}
+
+	var b strings.Builder
+	if v < 0 && !isZeroDigits(digits) {
+		b.WriteByte('-')
+	}
+	b.WriteString(groupDigits(intPart, f.Group))
+	if fracPart != "" {
+		b.WriteString(f.Decimal)
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
+
+// FormatPrice 格式化价格：绝对值不小于 1 时保留两位小数，
+// 小于 1 时按有效数字保留，避免小市值币种的价格显示为 0.00
+func (f NumberFormat) FormatPrice(v float64) string {
+	return f.FormatDecimal(v, priceDecimals(v))
+}
+
+// FormatCompact 使用紧凑单位（K/M/B/T 或 万/亿）格式化大数字
+func (f NumberFormat) FormatCompact(v float64) string {
+	abs := math.Abs(v)
+	i, divisor := len(f.Compact), 1.0
+	for j, unit := range f.Compact {
+		if abs >= unit.Value {
+			i, divisor = j, unit.Value
+			break
+		}
+	}
+	// 舍入后达到上一级单位时进位，如 999,999 显示为 1.00M 而不是 1,000.00K
+	scale := math.Pow(10, compactDecimals)
+	if i > 0 && math.Round(abs/divisor*scale)/scale*divisor >= f.Compact[i-1].Value {
+		i--
+	}
+	if i == len(f.Compact) {
+		return f.FormatDecimal(v, compactDecimals)
+	}
+	return f.FormatDecimal(v/f.Compact[i].Value, compactDecimals) + f.Compact[i].Suffix
+}
+
+// FormatPercent 格式化百分比，保留两位小数
+func (f NumberFormat) FormatPercent(v float64) string {
+	return f.FormatDecimal(v, 2) + "%"
+}
+
+// FormatSignedPercent 格式化带符号的百分比，非负数前加 "+"
+func (f NumberFormat) FormatSignedPercent(v float64) string {
+	return withSign(v, f.FormatPercent(v))
+}
+
+// FormatCurrency 格式化金额，符号位于货币符号之前，如 -$1,000.00
+func (f NumberFormat) FormatCurrency(v float64, currency string) string {
+	return currencyString(f.FormatPrice(v), currency)
+}
+
+// FormatSignedCurrency 格式化带符号的金额变化，如 +$1,000.00
+func (f NumberFormat) FormatSignedCurrency(v float64, currency string) string {
+	return withSign(v, f.FormatCurrency(v, currency))
+}
+
+// FormatCompactCurrency 使用紧凑单位格式化金额，如 $850.00B
+func (f NumberFormat) FormatCompactCurrency(v float64, currency string) string {
+	return currencyString(f.FormatCompact(v), currency)
+}
+
+// priceDecimals 计算价格需要保留的小数位数
+func priceDecimals(v float64) int {
+	abs := math.Abs(v)
+	if abs >= 1 || abs == 0 || math.IsNaN(abs) || math.IsInf(abs, 0) {
+		return 2
+	}
+	decimals := priceSignificantDigits - 1 - int(math.Floor(math.Log10(abs)))
+	if decimals > maxPriceDecimals {
+		decimals = maxPriceDecimals
+	}
+	return decimals
+}
+
+// currencyString 将货币符号插入到已格式化数字的符号之后
+func currencyString(formatted, currency string) string {
+	symbol := currencySymbol(currency)
+	if strings.HasPrefix(formatted, "-") {
+		return "-" + symbol + formatted[1:]
+	}
+	return symbol + formatted
+}
+
+// withSign 为非负数的格式化结果加上 "+" 号
+func withSign(v float64, formatted string) string {
+	if strings.HasPrefix(formatted, "-") || v < 0 {
+		return formatted
+	}
+	return "+" + formatted
+}
+
+// groupDigits 每三位插入一个千位分隔符
+func groupDigits(intPart, sep string) string {
+	if sep == "" || len(intPart) <= 3 {
+		return intPart
+	}
+
+	var b strings.Builder
+	head := len(intPart) % 3
+	if head > 0 {
+		b.WriteString(intPart[:head])
+	}
+	for i := head; i < len(intPart); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(intPart[i : i+3])
+	}
+	return b.String()
+}
+
+// isZeroDigits 判断格式化后的数字是否全为 0（用于避免输出 "-0.00"）
+func isZeroDigits(digits string) bool {
+	return strings.Trim(digits, "0.") == ""
+}
@@ -0,0 +1,96 @@
+package main
+
+import "testing"
+
+// TestFormatPrice 测试价格格式化：千位分隔符、有效数字和负数
+func TestFormatPrice(t *testing.T) {
+	f := locales["en"].Numbers
+
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{45000, "45,000.00"},
+		{1234567.891, "1,234,567.89"},
+		{999.999, "1,000.00"},
+		{1, "1.00"},
+		{0.5, "0.5000"},
+		{0.012345, "0.01235"},
+		{0.00001234, "0.00001234"},
+		{0, "0.00"},
+		{-1000, "-1,000.00"},
+		{-0.5, "-0.5000"},
+		{-0.000001, "-0.000001000"},
+		{-0.0000001, "-0.0000001000"},
+	}
+
+	for _, tt := range tests {
+		if got := f.FormatPrice(tt.in); got != tt.want {
+			t.Errorf("FormatPrice(%v) = %q，期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestFormatCompact 测试中英文紧凑单位和负数缩放
+func TestFormatCompact(t *testing.T) {
+	en := locales["en"].Numbers
+	zh := locales["zh-CN"].Numbers
+
+	tests := []struct {
+		f    NumberFormat
+		in   float64
+		want string
+	}{
+		{en, 850000000000, "850.00B"},
+		{en, 1.5e12, "1.50T"},
+		{en, 2500000, "2.50M"},
+		{en, 1500, "1.50K"},
+		{en, 999, "999.00"},
+		{en, -2500000, "-2.50M"},
+		{zh, 850000000000, "8,500.00亿"},
+		{zh, 1.5e12, "1.50万亿"},
+		{zh, 25000, "2.50万"},
+		{zh, -3e8, "-3.00亿"},
+		{zh, 9999, "9,999.00"},
+		// 舍入后进位到下一个单位
+		{en, 999999, "1.00M"},
+		{en, 999999999, "1.00B"},
+		{en, -999999, "-1.00M"},
+		{en, 999994, "999.99K"},
+		{en, 999.999, "1.00K"},
+		{zh, 99999999, "1.00亿"},
+		{zh, 9999.999, "1.00万"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.f.FormatCompact(tt.in); got != tt.want {
+			t.Errorf("FormatCompact(%v) = %q，期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestFormatCurrency 测试货币符号和正负号的位置
+func TestFormatCurrency(t *testing.T) {
+	f := locales["en"].Numbers
+
+	tests := []struct {
+		got  string
+		want string
+	}{
+		{f.FormatCurrency(45000, "USD"), "$45,000.00"},
+		{f.FormatCurrency(-50, "usd"), "-$50.00"},
+		{f.FormatSignedCurrency(1000, "USD"), "+$1,000.00"},
+		{f.FormatSignedCurrency(-1000, "USD"), "-$1,000.00"},
+		{f.FormatCompactCurrency(-2.5e9, "EUR"), "-€2.50B"},
+		{f.FormatCurrency(10, "XYZ"), "XYZ 10.00"},
+		{f.FormatSignedPercent(2.27), "+2.27%"},
+		{f.FormatSignedPercent(-1.75), "-1.75%"},
+		{f.FormatDecimal(-0.001, 2), "0.00"},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("格式化结果 %q，期望 %q", tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// 邮件认证方式
+const (
+	authPlain   = "plain"
+	authXOAuth2 = "xoauth2"
+)
+
+// 内置的 OAuth2 服务商
+const (
+	oauth2ProviderGoogle    = "google"
+	oauth2ProviderMicrosoft = "microsoft"
+)
+
+// tokenExpiryMargin 是访问令牌到期前提前刷新的时间，避免发送过程中令牌过期
+const tokenExpiryMargin = time.Minute
+
+// OAuth2Config 是刷新访问令牌所需的配置
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string // 公共客户端可以为空
+	RefreshToken string
+	Scope        string // 可选，Microsoft 365 需要指定
+}
+
+// oauth2Defaults 返回服务商的令牌地址和默认 scope，tenant 仅用于 Microsoft
+func oauth2Defaults(provider, tenant string) (tokenURL, scope string, err error) {
+	switch provider {
+	case "":
+		return "", "", nil
+	case oauth2ProviderGoogle:
+		return "https://oauth2.googleapis.com/token", "", nil
+	case oauth2ProviderMicrosoft:
+		if tenant == "" {
+			tenant = "common"
+		}
+		return "https://login.microsoftonline.com/" + url.PathEscape(tenant) + "/oauth2/v2.0/token",
+			"https://outlook.office.com/SMTP.Send offline_access", nil
+	default:
+		return "", "", fmt.Errorf("unsupported OAuth2 provider %q (supported: google, microsoft)", provider)
+	}
+}
+
+// OAuth2TokenSource 使用刷新令牌获取访问令牌，并缓存到过期前
+type OAuth2TokenSource struct {
+	config OAuth2Config
+	client *http.Client
+	now    func() time.Time
+
+	mu           sync.Mutex
+	accessToken  string
+	expiry       time.Time
+	refreshToken string // 服务商可能在刷新时轮换刷新令牌
+}
+
+// NewOAuth2TokenSource 创建访问令牌来源，client 用于请求令牌地址（可以经过代理）
+func NewOAuth2TokenSource(config OAuth2Config, client *http.Client) *OAuth2TokenSource {
+	return &OAuth2TokenSource{
+		config:       config,
+		client:       client,
+		now:          time.Now,
+		refreshToken: config.RefreshToken,
+	}
+}
+
+// tokenResponse 是令牌地址的响应（RFC 6749 5.1、5.2）
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Token 返回有效的访问令牌，缓存的令牌即将过期时自动刷新
+func (s *OAuth2TokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && s.now().Add(tokenExpiryMargin).Before(s.expiry) {
+		return s.accessToken, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {s.refreshToken},
+		"client_id":     {s.config.ClientID},
+	}
+	if s.config.ClientSecret != "" {
+		form.Set("client_secret", s.config.ClientSecret)
+	}
+	if s.config.Scope != "" {
+		form.Set("scope", s.config.Scope)
+	}
+
+	resp, err := s.client.PostForm(s.config.TokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh OAuth2 token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read OAuth2 token response: %w", err)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("invalid OAuth2 token response (status %d): %w", resp.StatusCode, err)
+	}
+	if token.Error != "" {
+		return "", fmt.Errorf("OAuth2 token refresh failed: %s: %s", token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("OAuth2 token refresh failed: status %d", resp.StatusCode)
+	}
+
+	s.accessToken = token.AccessToken
+	s.expiry = s.now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	if token.RefreshToken != "" {
+		s.refreshToken = token.RefreshToken
+	}
+	return s.accessToken, nil
+}
+
+// Invalidate 丢弃缓存的访问令牌，下次调用 Token 时重新获取
+// 服务器拒绝令牌时调用（令牌可能已被撤销）
+func (s *OAuth2TokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessToken = ""
+}
+
+// xoauth2Auth 实现 XOAUTH2 SASL 认证机制（Gmail、Microsoft 365）
+type xoauth2Auth struct {
+	username string
+	token    string
+	host     string
+}
+
+// XOAuth2Auth 返回使用访问令牌认证的 smtp.Auth
+// 与 smtp.PlainAuth 相同，只在 TLS 连接或本机服务器上发送令牌
+func XOAuth2Auth(username, token, host string) smtp.Auth {
+	return &xoauth2Auth{username: username, token: token, host: host}
+}
+
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	resp := "user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01"
+	return "XOAUTH2", []byte(resp), nil
+}
+
+// Next 处理服务器的错误质询：服务器以 JSON 说明失败原因，客户端需要回复空行后才会收到最终错误
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return []byte{}, nil
+	}
+	return nil, nil
+}
+
+// isLocalhost 判断是否为本机地址
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testTokenServer 是用于测试的 OAuth2 令牌地址，依次返回 tokens 中的访问令牌
+type testTokenServer struct {
+	tokens    []string
+	expiresIn int64
+	rotate    bool // 每次刷新时返回新的刷新令牌
+
+	mu       sync.Mutex
+	requests []map[string]string // 每次请求的表单参数
+}
+
+func (s *testTokenServer) start(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		form := make(map[string]string)
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		s.requests = append(s.requests, form)
+
+		w.Header().Set("Content-Type", "application/json")
+		if form["refresh_token"] == "revoked" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "Token has been expired or revoked."})
+			return
+		}
+
+		n := len(s.requests)
+		token := s.tokens[len(s.tokens)-1]
+		if n <= len(s.tokens) {
+			token = s.tokens[n-1]
+		}
+		resp := map[string]interface{}{"access_token": token, "token_type": "Bearer", "expires_in": s.expiresIn}
+		if s.rotate {
+			resp["refresh_token"] = "refresh-" + token
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func (s *testTokenServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+// TestOAuth2TokenSourceCaching 测试访问令牌缓存到过期前，并在过期后刷新
+func TestOAuth2TokenSourceCaching(t *testing.T) {
+	server := &testTokenServer{tokens: []string{"token-1", "token-2"}, expiresIn: 3600, rotate: true}
+	source := NewOAuth2TokenSource(OAuth2Config{
+		TokenURL:     server.start(t),
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RefreshToken: "refresh-0",
+		Scope:        "https://outlook.office.com/SMTP.Send offline_access",
+	}, http.DefaultClient)
+
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	source.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		token, err := source.Token()
+		if err != nil {
+			t.Fatalf("获取令牌失败: %v", err)
+		}
+		if token != "token-1" {
+			t.Errorf("缓存期内应该返回同一个令牌，实际为 %s", token)
+		}
+	}
+	if n := server.requestCount(); n != 1 {
+		t.Errorf("缓存期内应该只请求一次令牌地址，实际为 %d", n)
+	}
+
+	// 到期前一分钟内视为过期
+	now = now.Add(time.Hour - 30*time.Second)
+	if token, _ := source.Token(); token != "token-2" {
+		t.Errorf("令牌即将过期时应该刷新，实际为 %s", token)
+	}
+
+	first, second := server.requests[0], server.requests[1]
+	if first["grant_type"] != "refresh_token" || first["client_id"] != "client" || first["client_secret"] != "secret" {
+		t.Errorf("刷新请求参数不正确: %v", first)
+	}
+	if first["scope"] != "https://outlook.office.com/SMTP.Send offline_access" {
+		t.Errorf("scope 不正确: %s", first["scope"])
+	}
+	if first["refresh_token"] != "refresh-0" || second["refresh_token"] != "refresh-token-1" {
+		t.Errorf("应该使用服务商轮换后的刷新令牌: %s, %s", first["refresh_token"], second["refresh_token"])
+	}
+
+	source.Invalidate()
+	source.Token()
+	if n := server.requestCount(); n != 3 {
+		t.Errorf("Invalidate 后应该重新获取令牌，请求次数为 %d", n)
+	}
+}
+
+// TestOAuth2TokenSourceError 测试令牌地址返回错误
+func TestOAuth2TokenSourceError(t *testing.T) {
+	server := &testTokenServer{tokens: []string{"token"}}
+	source := NewOAuth2TokenSource(OAuth2Config{TokenURL: server.start(t), ClientID: "client", RefreshToken: "revoked"}, http.DefaultClient)
+
+	_, err := source.Token()
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("应该返回服务商的错误说明，实际为 %v", err)
+	}
+}
+
+// TestSendReportXOAuth2 测试使用 XOAUTH2 认证发送邮件，服务器拒绝令牌后下次发送重新获取
+func TestSendReportXOAuth2(t *testing.T) {
+	tokens := &testTokenServer{tokens: []string{"revoked-token", "good-token"}, expiresIn: 3600}
+	tokenURL := tokens.start(t)
+
+	server := &fakeSMTPServer{auth: true, validToken: "good-token"}
+	server.start(t)
+
+	config := testEmailConfig(server)
+	config.TLS = tlsModeNone
+	config.Password = ""
+	config.Auth = authXOAuth2
+	config.OAuth2 = OAuth2Config{TokenURL: tokenURL, ClientID: "client", RefreshToken: "refresh"}
+	sender := NewEmailSender(config)
+	if !sender.IsConfigured() {
+		t.Fatal("使用 XOAUTH2 时不需要密码")
+	}
+
+	if err := sender.SendReport("Report", "<p>x</p>"); err == nil {
+		t.Fatal("服务器拒绝令牌时应该返回错误")
+	}
+	if err := sender.SendReport("Report", "<p>x</p>"); err != nil {
+		t.Fatalf("重新获取令牌后应该发送成功: %v", err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("服务器应该收到一封邮件，实际为 %d", len(messages))
+	}
+	if want := "XOAUTH2 user=bot@test.com\x01auth=Bearer good-token\x01\x01"; messages[0].SASL != want {
+		t.Errorf("XOAUTH2 初始响应不正确: %q", messages[0].SASL)
+	}
+	if n := tokens.requestCount(); n != 2 {
+		t.Errorf("应该请求两次令牌，实际为 %d", n)
+	}
+}
+
+// TestXOAuth2AuthRequiresTLS 测试不在明文连接上向远程服务器发送令牌
+func TestXOAuth2AuthRequiresTLS(t *testing.T) {
+	auth := XOAuth2Auth("bot@test.com", "token", "smtp.test.com")
+	if _, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.test.com", TLS: false}); err == nil {
+		t.Error("明文连接应该拒绝发送令牌")
+	}
+	if mech, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.test.com", TLS: true}); err != nil || mech != "XOAUTH2" {
+		t.Errorf("TLS 连接应该使用 XOAUTH2: %s %v", mech, err)
+	}
+}
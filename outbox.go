@@ -0,0 +1,316 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RetryPolicy 描述发送失败后的重试策略
+type RetryPolicy struct {
+	MaxAttempts    int           // 包含首次发送在内的最大尝试次数
+	InitialBackoff time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff     time.Duration // 等待时间上限
+}
+
+// 未配置时使用的重试策略
+const (
+	defaultRetryMaxAttempts    = 8
+	defaultRetryInitialBackoff = time.Minute
+	defaultRetryMaxBackoff     = time.Hour
+)
+
+// OutgoingEmail 是已经构建好、等待投递的邮件
+type OutgoingEmail struct {
+	From    string   `json:"from"`
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Message []byte   `json:"message"` // 完整的邮件内容，重试时原样发送，Message-ID 保持不变
+}
+
+// OutboxEntry 是发件箱中的一封邮件
+type OutboxEntry struct {
+	ID string `json:"id"`
+	OutgoingEmail
+	CreatedAt   time.Time `json:"created_at"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error"`
+	NextAttempt time.Time `json:"next_attempt"`
+	Permanent   bool      `json:"permanent"` // 服务器返回 5xx，不再自动重试
+}
+
+// Outbox 是磁盘上的发件箱：等待重试的邮件保存在 dir 下，
+// 永久失败或超过重试次数的邮件移到 dir/failed 下，不再自动重试
+// 记录中包含完整的邮件内容和收件人，目录和文件只允许当前用户访问
+type Outbox struct {
+	dir    string
+	policy RetryPolicy
+	now    func() time.Time
+}
+
+// NewOutbox 创建发件箱
+func NewOutbox(dir string, policy RetryPolicy) *Outbox {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaultRetryInitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultRetryMaxBackoff
+	}
+	return &Outbox{
+		dir:    dir,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// Enqueue 将首次发送失败的邮件加入发件箱
+// 永久性错误直接放入 failed 目录，其余错误按重试策略安排下一次发送
+func (o *Outbox) Enqueue(email *OutgoingEmail, sendErr error) (*OutboxEntry, error) {
+	id, err := newOutboxID(o.now())
+	if err != nil {
+		return nil, err
+	}
+	entry := &OutboxEntry{
+		ID:            id,
+		OutgoingEmail: *email,
+		CreatedAt:     o.now(),
+	}
+	_, err = o.recordFailure(entry, sendErr)
+	return entry, err
+}
+
+// Pending 返回等待重试的邮件，按加入时间排序
+func (o *Outbox) Pending() ([]OutboxEntry, error) {
+	return readOutboxDir(o.dir)
+}
+
+// Failed 返回不再自动重试的邮件，按加入时间排序
+func (o *Outbox) Failed() ([]OutboxEntry, error) {
+	return readOutboxDir(o.failedDir())
+}
+
+// OutboxResult 是一次重试的统计结果
+type OutboxResult struct {
+	Delivered int // 发送成功并从发件箱删除
+	Retrying  int // 再次失败，等待下一次重试
+	Failed    int // 永久失败或超过重试次数
+}
+
+// Retry 重新发送到期的邮件，force 为 true 时忽略等待时间发送全部邮件
+func (o *Outbox) Retry(deliver func(*OutgoingEmail) error, force bool) (OutboxResult, error) {
+	var result OutboxResult
+	entries, err := o.Pending()
+	if err != nil {
+		return result, err
+	}
+
+	now := o.now()
+	for i := range entries {
+		entry := &entries[i]
+		if !force && entry.NextAttempt.After(now) {
+			continue
+		}
+
+		sendErr := deliver(&entry.OutgoingEmail)
+		if sendErr == nil {
+			if err := os.Remove(o.entryPath(o.dir, entry.ID)); err != nil && !os.IsNotExist(err) {
+				return result, fmt.Errorf("failed to remove outbox entry: %w", err)
+			}
+			result.Delivered++
+			continue
+		}
+
+		split, err := o.recordFailure(entry, sendErr)
+		if err != nil {
+			return result, err
+		}
+		if split {
+			result.Failed++ // 永久拒绝的收件人单独记为失败
+		}
+		if entry.Permanent || entry.Attempts >= o.policy.MaxAttempts {
+			result.Failed++
+		} else {
+			result.Retrying++
+		}
+	}
+	return result, nil
+}
+
+// recordFailure 记录一次失败的发送，并保存到对应的目录
+// 部分收件人被永久拒绝、其余收件人为临时错误时，永久拒绝的收件人拆分为单独的失败记录，返回 split 为 true
+func (o *Outbox) recordFailure(entry *OutboxEntry, sendErr error) (split bool, err error) {
+	entry.Attempts++
+	entry.LastError = sendErr.Error()
+	entry.Permanent = isPermanentSMTPError(sendErr)
+
+	// 部分收件人已经收到邮件，之后只重试被拒绝的收件人；每个收件人单独判断是否为永久错误
+	var recipientErr *RecipientError
+	if errors.As(sendErr, &recipientErr) {
+		var temporary, permanent []string
+		for _, result := range recipientErr.Rejected() {
+			if isPermanentSMTPError(result.Err) {
+				permanent = append(permanent, result.Address)
+			} else {
+				temporary = append(temporary, result.Address)
+			}
+		}
+		entry.To = append(temporary, permanent...)
+		if len(temporary) > 0 && len(permanent) > 0 {
+			if err := o.recordRejected(entry, permanent); err != nil {
+				return false, err
+			}
+			entry.To = temporary
+			split = true
+		}
+	}
+
+	failed := entry.Permanent || entry.Attempts >= o.policy.MaxAttempts
+	if failed {
+		entry.NextAttempt = time.Time{}
+	} else {
+		entry.NextAttempt = o.now().Add(o.backoff(entry.Attempts))
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return split, fmt.Errorf("failed to encode outbox entry: %w", err)
+	}
+	if !failed {
+		return split, writeFileAtomic(o.entryPath(o.dir, entry.ID), data, 0600)
+	}
+
+	if err := writeFileAtomic(o.entryPath(o.failedDir(), entry.ID), data, 0600); err != nil {
+		return split, err
+	}
+	if err := os.Remove(o.entryPath(o.dir, entry.ID)); err != nil && !os.IsNotExist(err) {
+		return split, fmt.Errorf("failed to remove outbox entry: %w", err)
+	}
+	return split, nil
+}
+
+// recordRejected 将被永久拒绝的收件人保存为 failed 目录中的一条新记录，不再自动重试
+func (o *Outbox) recordRejected(entry *OutboxEntry, to []string) error {
+	id, err := newOutboxID(o.now())
+	if err != nil {
+		return err
+	}
+	rejected := *entry
+	rejected.ID = id
+	rejected.To = to
+	rejected.Permanent = true
+	rejected.NextAttempt = time.Time{}
+
+	data, err := json.MarshalIndent(&rejected, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox entry: %w", err)
+	}
+	return writeFileAtomic(o.entryPath(o.failedDir(), rejected.ID), data, 0600)
+}
+
+// backoff 返回第 attempts 次失败后的等待时间：InitialBackoff、2 倍、4 倍……直到 MaxBackoff
+func (o *Outbox) backoff(attempts int) time.Duration {
+	wait := o.policy.InitialBackoff
+	for i := 1; i < attempts && wait < o.policy.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > o.policy.MaxBackoff {
+		wait = o.policy.MaxBackoff
+	}
+	return wait
+}
+
+func (o *Outbox) failedDir() string {
+	return filepath.Join(o.dir, "failed")
+}
+
+func (o *Outbox) entryPath(dir, id string) string {
+	return filepath.Join(dir, id+".json")
+}
+
+// readOutboxDir 读取目录中的全部邮件，目录不存在时返回空列表
+func readOutboxDir(dir string) ([]OutboxEntry, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	var entries []OutboxEntry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read outbox entry: %w", err)
+		}
+		var entry OutboxEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse outbox entry %s: %w", file.Name(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// newOutboxID 生成按时间排序的唯一 ID
+func newOutboxID(at time.Time) (string, error) {
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate outbox ID: %w", err)
+	}
+	return at.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(random), nil
+}
+
+// SMTP 认证失败的响应码，说明的是发件账号的问题而不是邮件本身，修正配置后重试即可
+var smtpAuthCodes = map[int]bool{530: true, 534: true, 535: true}
+
+// isPermanentSMTPError 判断发送错误是否为永久性错误
+// 服务器返回的 5xx（认证失败除外）是永久性错误，4xx、网络错误等都视为临时错误；
+// 收件人被拒绝时，只有全部被拒绝的收件人都是永久性错误才不再重试；
+// HTTP API 的 4xx（认证失败、超时和限流除外）是永久性错误，5xx 视为临时错误；
+// sendmail 只有在报告邮件内容或收件人有问题时才是永久性错误
+func isPermanentSMTPError(err error) bool {
+	var sendmailErr *SendmailError
+	if errors.As(err, &sendmailErr) {
+		return sendmailPermanentCodes[sendmailErr.ExitCode]
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 && !httpTemporaryCodes[statusErr.StatusCode]
+	}
+
+	var recipientErr *RecipientError
+	if errors.As(err, &recipientErr) {
+		rejected := recipientErr.Rejected()
+		for _, result := range rejected {
+			if !isPermanentSMTPError(result.Err) {
+				return false
+			}
+		}
+		return len(rejected) > 0
+	}
+
+	var protoErr *textproto.Error
+	if !errors.As(err, &protoErr) {
+		return false
+	}
+	return protoErr.Code >= 500 && protoErr.Code < 600 && !smtpAuthCodes[protoErr.Code]
+}
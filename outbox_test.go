@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testOutbox 返回使用临时目录和可控时钟的发件箱
+func testOutbox(t *testing.T, policy RetryPolicy) (*Outbox, *time.Time) {
+	t.Helper()
+	outbox := NewOutbox(filepath.Join(t.TempDir(), "outbox"), policy)
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	outbox.now = func() time.Time { return now }
+	return outbox, &now
+}
+
+func testOutgoingEmail() *OutgoingEmail {
+	return &OutgoingEmail{
+		From:    "bot@test.com",
+		To:      []string{"a@test.com"},
+		Subject: "每日报表",
+		Message: []byte("Subject: test\r\n\r\nbody\r\n"),
+	}
+}
+
+// smtpError 模拟 SMTP 服务器返回的错误
+func smtpError(code int, msg string) error {
+	return fmt.Errorf("failed to send email: RCPT TO failed: %w", &textproto.Error{Code: code, Msg: msg})
+}
+
+// TestOutboxRetryWithBackoff 测试临时错误按退避时间重试，成功后从发件箱删除
+func TestOutboxRetryWithBackoff(t *testing.T) {
+	outbox, now := testOutbox(t, RetryPolicy{InitialBackoff: time.Minute, MaxBackoff: time.Hour})
+
+	entry, err := outbox.Enqueue(testOutgoingEmail(), smtpError(451, "4.7.1 try again later"))
+	if err != nil {
+		t.Fatalf("加入发件箱失败: %v", err)
+	}
+	if entry.Permanent || entry.Attempts != 1 || !entry.NextAttempt.Equal(now.Add(time.Minute)) {
+		t.Errorf("临时错误的发件箱记录不正确: %+v", entry)
+	}
+
+	attempts := 0
+	deliver := func(email *OutgoingEmail) error {
+		attempts++
+		if string(email.Message) != string(testOutgoingEmail().Message) {
+			t.Errorf("重试时应该原样发送邮件内容: %q", email.Message)
+		}
+		if attempts == 1 {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	}
+
+	// 未到重试时间
+	if result, _ := outbox.Retry(deliver, false); attempts != 0 || result != (OutboxResult{}) {
+		t.Errorf("未到重试时间不应该发送: %+v", result)
+	}
+
+	*now = now.Add(time.Minute)
+	result, err := outbox.Retry(deliver, false)
+	if err != nil || result.Retrying != 1 {
+		t.Fatalf("第二次失败后应该继续等待重试: %+v %v", result, err)
+	}
+	pending, _ := outbox.Pending()
+	if len(pending) != 1 || pending[0].Attempts != 2 || !pending[0].NextAttempt.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("第二次重试的等待时间应该翻倍: %+v", pending)
+	}
+
+	// flush 忽略等待时间
+	result, err = outbox.Retry(deliver, true)
+	if err != nil || result.Delivered != 1 {
+		t.Fatalf("重试成功后应该从发件箱删除: %+v %v", result, err)
+	}
+	if pending, _ := outbox.Pending(); len(pending) != 0 {
+		t.Errorf("发件箱应该为空: %+v", pending)
+	}
+}
+
+// TestOutboxPermanentFailure 测试 5xx 错误不再重试，与待重试的邮件分开保存
+func TestOutboxPermanentFailure(t *testing.T) {
+	outbox, _ := testOutbox(t, RetryPolicy{})
+
+	entry, err := outbox.Enqueue(testOutgoingEmail(), smtpError(550, "5.1.1 no such user"))
+	if err != nil {
+		t.Fatalf("加入发件箱失败: %v", err)
+	}
+	if !entry.Permanent || !entry.NextAttempt.IsZero() {
+		t.Errorf("5xx 错误应该标记为永久失败: %+v", entry)
+	}
+
+	pending, _ := outbox.Pending()
+	failed, _ := outbox.Failed()
+	if len(pending) != 0 || len(failed) != 1 || failed[0].LastError == "" {
+		t.Errorf("永久失败的邮件应该放入 failed 目录: pending=%d failed=%+v", len(pending), failed)
+	}
+
+	called := false
+	outbox.Retry(func(*OutgoingEmail) error { called = true; return nil }, true)
+	if called {
+		t.Error("永久失败的邮件不应该自动重试")
+	}
+}
+
+// TestOutboxPermissions 测试发件箱目录和记录文件只允许当前用户访问
+func TestOutboxPermissions(t *testing.T) {
+	outbox, _ := testOutbox(t, RetryPolicy{})
+	pending, err := outbox.Enqueue(testOutgoingEmail(), smtpError(451, "4.7.1 try again later"))
+	if err != nil {
+		t.Fatalf("加入发件箱失败: %v", err)
+	}
+	failed, err := outbox.Enqueue(testOutgoingEmail(), smtpError(550, "5.1.1 no such user"))
+	if err != nil {
+		t.Fatalf("加入发件箱失败: %v", err)
+	}
+
+	paths := map[string]os.FileMode{
+		outbox.dir:                                      0700,
+		outbox.failedDir():                              0700,
+		outbox.entryPath(outbox.dir, pending.ID):        0600,
+		outbox.entryPath(outbox.failedDir(), failed.ID): 0600,
+	}
+	for path, want := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("读取 %s 失败: %v", path, err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s 的权限为 %v，期望 %v", path, got, want)
+		}
+	}
+}
+
+// TestOutboxMixedRecipientErrors 测试部分收件人被永久拒绝、部分为临时错误时，只重试临时错误的收件人
+func TestOutboxMixedRecipientErrors(t *testing.T) {
+	outbox, now := testOutbox(t, RetryPolicy{})
+	email := testOutgoingEmail()
+	email.To = []string{"ok@test.com", "gone@test.com", "busy@test.com"}
+	sendErr := &RecipientError{Results: []RecipientResult{
+		{Address: "ok@test.com"},
+		{Address: "gone@test.com", Err: &textproto.Error{Code: 550, Msg: "5.1.1 no such user"}},
+		{Address: "busy@test.com", Err: &textproto.Error{Code: 452, Msg: "4.2.2 mailbox full"}},
+	}}
+
+	entry, err := outbox.Enqueue(email, sendErr)
+	if err != nil {
+		t.Fatalf("加入发件箱失败: %v", err)
+	}
+	if entry.Permanent || strings.Join(entry.To, ",") != "busy@test.com" {
+		t.Errorf("只应该重试临时错误的收件人: permanent=%v to=%v", entry.Permanent, entry.To)
+	}
+	failed, _ := outbox.Failed()
+	if len(failed) != 1 || strings.Join(failed[0].To, ",") != "gone@test.com" || !failed[0].Permanent {
+		t.Errorf("永久拒绝的收件人应该记录为失败: %+v", failed)
+	}
+
+	// 之后只重试临时错误的收件人
+	*now = now.Add(time.Hour)
+	var retried []string
+	result, err := outbox.Retry(func(e *OutgoingEmail) error {
+		retried = e.To
+		return &RecipientError{Results: []RecipientResult{
+			{Address: "busy@test.com", Err: &textproto.Error{Code: 451, Msg: "4.3.0 try later"}},
+		}}
+	}, false)
+	if err != nil {
+		t.Fatalf("重试失败: %v", err)
+	}
+	if strings.Join(retried, ",") != "busy@test.com" {
+		t.Errorf("重试时应该只发送给 busy@test.com，实际为 %v", retried)
+	}
+	if result.Retrying != 1 || result.Failed != 0 {
+		t.Errorf("重试结果不正确: %+v", result)
+	}
+
+	// 全部永久拒绝时整封邮件记录为失败
+	all := &RecipientError{Results: []RecipientResult{
+		{Address: "a@test.com", Err: &textproto.Error{Code: 550, Msg: "no such user"}},
+		{Address: "b@test.com", Err: &textproto.Error{Code: 553, Msg: "bad address"}},
+	}}
+	entry, _ = outbox.Enqueue(testOutgoingEmail(), all)
+	if !entry.Permanent || len(entry.To) != 2 {
+		t.Errorf("全部永久拒绝时应该标记为永久失败: %+v", entry)
+	}
+}
+
+// TestOutboxMaxAttempts 测试超过最大尝试次数后放弃
+func TestOutboxMaxAttempts(t *testing.T) {
+	outbox, _ := testOutbox(t, RetryPolicy{MaxAttempts: 3})
+	sendErr := smtpError(421, "4.3.2 service not available")
+
+	if _, err := outbox.Enqueue(testOutgoingEmail(), sendErr); err != nil {
+		t.Fatalf("加入发件箱失败: %v", err)
+	}
+	fail := func(*OutgoingEmail) error { return sendErr }
+
+	if result, _ := outbox.Retry(fail, true); result.Retrying != 1 {
+		t.Errorf("第二次尝试后应该继续重试: %+v", result)
+	}
+	if result, _ := outbox.Retry(fail, true); result.Failed != 1 {
+		t.Errorf("第三次尝试后应该放弃: %+v", result)
+	}
+
+	failed, _ := outbox.Failed()
+	if len(failed) != 1 || failed[0].Attempts != 3 || failed[0].Permanent {
+		t.Errorf("超过重试次数的邮件应该放入 failed 目录: %+v", failed)
+	}
+}
+
+// TestOutboxBackoff 测试退避时间翻倍并受上限限制
+func TestOutboxBackoff(t *testing.T) {
+	outbox, _ := testOutbox(t, RetryPolicy{InitialBackoff: time.Minute, MaxBackoff: 10 * time.Minute})
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for i, w := range want {
+		if got := outbox.backoff(i + 1); got != w {
+			t.Errorf("第 %d 次失败后的等待时间为 %v，期望 %v", i+1, got, w)
+		}
+	}
+}
+
+// TestIsPermanentSMTPError 测试错误分类
+func TestIsPermanentSMTPError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{smtpError(550, "mailbox unavailable"), true},
+		{smtpError(554, "transaction failed"), true},
+		{smtpError(451, "try again later"), false},
+		{smtpError(421, "service not available"), false},
+		{smtpError(535, "authentication failed"), false},
+		{errors.New("dial tcp: connection refused"), false},
+		{&HTTPStatusError{Provider: "SES", StatusCode: 400}, true},
+		{&HTTPStatusError{Provider: "Mailgun", StatusCode: 401}, false},
+		{&HTTPStatusError{Provider: "SES", StatusCode: 429}, false},
+		{&HTTPStatusError{Provider: "HTTP", StatusCode: 503}, false},
+	}
+	for _, tt := range tests {
+		if got := isPermanentSMTPError(tt.err); got != tt.want {
+			t.Errorf("isPermanentSMTPError(%v) = %v, 期望 %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestOutboxWithSMTPServer 测试服务器返回的 4xx、5xx 错误被正确区分
+func TestOutboxWithSMTPServer(t *testing.T) {
+	server := &fakeSMTPServer{}
+	server.start(t)
+	config := testEmailConfig(server)
+	config.TLS = tlsModeNone
+	sender := NewEmailSender(config)
+	outbox, _ := testOutbox(t, RetryPolicy{})
+
+	server.setRejectRcpt("a@test.com", "451 4.2.1 mailbox busy")
+	email, err := sender.Compose([]string{"a@test.com"}, "Report", "<p>x</p>", "x")
+	if err != nil {
+		t.Fatalf("构建邮件失败: %v", err)
+	}
+	sendErr := sender.Deliver(email)
+	if sendErr == nil {
+		t.Fatal("服务器返回 451 时应该发送失败")
+	}
+	if entry, _ := outbox.Enqueue(email, sendErr); entry.Permanent {
+		t.Error("451 应该视为临时错误")
+	}
+
+	// 服务器恢复后重试成功，收到的邮件与首次构建的完全相同
+	server.setRejectRcpt("a@test.com", "")
+	if result, err := outbox.Retry(sender.Deliver, true); err != nil || result.Delivered != 1 {
+		t.Fatalf("重试应该成功: %+v %v", result, err)
+	}
+	if messages := server.received(); len(messages) != 1 || messages[0].Data != strings.ReplaceAll(string(email.Message), "\r\n", "\n") {
+		t.Error("重试时应该发送原始邮件")
+	}
+
+	server.setRejectRcpt("b@test.com", "550 5.1.1 no such user")
+	email, _ = sender.Compose([]string{"b@test.com"}, "Report", "<p>x</p>", "x")
+	if entry, _ := outbox.Enqueue(email, sender.Deliver(email)); !entry.Permanent {
+		t.Error("550 应该视为永久错误")
+	}
+	if _, err := os.Stat(filepath.Join(outbox.dir, "failed")); err != nil {
+		t.Errorf("永久失败的邮件应该保存在 failed 目录: %v", err)
+	}
+}
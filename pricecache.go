@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultPriceCacheTTL 是未配置 discord.commands.cache_ttl 时的价格缓存时间
+const defaultPriceCacheTTL = 5 * time.Minute
+
+// PriceCache 按币种缓存 CoinGecko 的价格数据，斜杠命令频繁查询时避免耗尽 API 配额
+// 同一时间只有一个请求访问 CoinGecko，并发的相同查询会直接使用前一个请求的结果
+type PriceCache struct {
+	client  *CoinGeckoClient
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]cachedPrice
+}
+
+// cachedPrice 是一个币种的缓存数据及获取时间
+type cachedPrice struct {
+	coin      CoinPrice
+	fetchedAt time.Time
+	missing   bool // CoinGecko 没有返回该币种，同样缓存，避免反复查询不存在的币种
+}
+
+// NewPriceCache 创建价格缓存，ttl 为 0 时使用默认的 5 分钟
+func NewPriceCache(client *CoinGeckoClient, ttl time.Duration) *PriceCache {
+	if ttl <= 0 {
+		ttl = defaultPriceCacheTTL
+	}
+	return &PriceCache{
+		client:  client,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]cachedPrice{},
+	}
+}
+
+// Prices 按 ids 的顺序返回币种价格，CoinGecko 不认识的币种不会出现在结果中
+// 缓存中没有或已过期的币种合并为一次请求
+func (c *PriceCache) Prices(ids []string) ([]CoinPrice, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	var stale []string
+	for _, id := range ids {
+		if entry, ok := c.entries[id]; !ok || now.Sub(entry.fetchedAt) >= c.ttl {
+			stale = append(stale, id)
+		}
+	}
+	if len(stale) > 0 {
+		coins, err := c.client.GetCoinPrices(stale)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range stale {
+			c.entries[id] = cachedPrice{fetchedAt: now, missing: true}
+		}
+		for _, coin := range coins {
+			c.entries[coin.ID] = cachedPrice{coin: coin, fetchedAt: now}
+		}
+	}
+
+	var coins []CoinPrice
+	for _, id := range ids {
+		if entry, ok := c.entries[id]; ok && !entry.missing {
+			coins = append(coins, entry.coin)
+		}
+	}
+	return coins, nil
+}
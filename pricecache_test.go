@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCoinGecko 启动一个返回 coins 中被请求币种的 CoinGecko 测试服务器，返回客户端和请求过的 ids 参数
+func fakeCoinGecko(t *testing.T, coins []CoinPrice) (*CoinGeckoClient, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids := r.URL.Query().Get("ids")
+		mu.Lock()
+		requests = append(requests, ids)
+		mu.Unlock()
+
+		var result []CoinPrice
+		for _, coin := range coins {
+			if containsString(strings.Split(ids, ","), coin.ID) {
+				result = append(result, coin)
+			}
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewCoinGeckoClient("test-key", false, "")
+	client.baseURL = server.URL
+	return client, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+// TestPriceCache 测试缓存有效期内不重复请求，只请求缺少或过期的币种，结果按请求顺序返回
+func TestPriceCache(t *testing.T) {
+	client, requests := fakeCoinGecko(t, sampleCoins)
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	cache := NewPriceCache(client, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	coins, err := cache.Prices([]string{"ethereum", "bitcoin"})
+	if err != nil {
+		t.Fatalf("获取价格失败: %v", err)
+	}
+	if len(coins) != 2 || coins[0].ID != "ethereum" || coins[1].ID != "bitcoin" {
+		t.Errorf("结果应该按请求顺序返回: %+v", coins)
+	}
+
+	now = now.Add(30 * time.Second)
+	cache.Prices([]string{"bitcoin"})
+	cache.Prices([]string{"bitcoin", "dogecoin"})
+	cache.Prices([]string{"dogecoin"})
+	if got := requests(); len(got) != 2 || got[1] != "dogecoin" {
+		t.Errorf("缓存有效期内只应该请求缺少的币种，不存在的币种也应该缓存: %v", got)
+	}
+
+	now = now.Add(time.Minute)
+	if coins, _ := cache.Prices([]string{"bitcoin"}); len(coins) != 1 {
+		t.Errorf("过期后应该重新获取: %+v", coins)
+	}
+	if got := requests(); len(got) != 3 || got[2] != "bitcoin" {
+		t.Errorf("过期后应该重新请求: %v", got)
+	}
+}
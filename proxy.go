@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// proxyDialTimeout 是连接代理并完成握手的超时时间
+const proxyDialTimeout = 30 * time.Second
+
+// directProxy 是单个服务的代理覆盖值，表示该服务不使用代理
+const directProxy = "direct"
+
+// ProxyDialer 建立 TCP 连接，配置了代理时通过 HTTP CONNECT、HTTPS CONNECT 或 SOCKS5 隧道连接
+// CoinGecko、Discord 和 SMTP 使用同一个实现
+type ProxyDialer struct {
+	proxy     *url.URL    // 为 nil 时直连
+	tlsConfig *tls.Config // 连接 https:// 代理时使用，为 nil 时使用系统证书
+}
+
+// NewProxyDialer 根据代理 URL 创建拨号器，rawURL 为空时直连
+// 支持 http://、https://、socks5:// 和 socks5h://，URL 中可以包含 user:pass 认证信息
+func NewProxyDialer(rawURL string) (*ProxyDialer, error) {
+	if rawURL == "" {
+		return &ProxyDialer{}, nil
+	}
+
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (supported: http, https, socks5)", proxyURL.Scheme)
+	}
+	if proxyURL.Hostname() == "" {
+		return nil, fmt.Errorf("invalid proxy URL: missing host")
+	}
+	return &ProxyDialer{proxy: proxyURL}, nil
+}
+
+// proxyAddr 返回代理服务器地址，未指定端口时使用协议的默认端口
+func (d *ProxyDialer) proxyAddr() string {
+	port := d.proxy.Port()
+	if port == "" {
+		switch d.proxy.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(d.proxy.Hostname(), port)
+}
+
+// DialContext 连接到目标地址，签名与 http.Transport.DialContext 一致
+func (d *ProxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: proxyDialTimeout}
+	if d.proxy == nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", d.proxyAddr())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy: %w", err)
+	}
+
+	// 握手阶段使用超时，完成后清除，由调用方自行设置读写超时
+	deadline := time.Now().Add(proxyDialTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+
+	tunnel := conn
+	switch d.proxy.Scheme {
+	case "socks5", "socks5h":
+		err = d.socks5Connect(conn, addr)
+	case "https":
+		tlsConfig := d.tlsConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		tlsConfig = tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = d.proxy.Hostname()
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			err = fmt.Errorf("TLS handshake with proxy failed: %w", err)
+			break
+		}
+		tunnel, err = d.httpConnect(tlsConn, addr)
+	default:
+		tunnel, err = d.httpConnect(conn, addr)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	tunnel.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+// Dial 连接到目标地址
+func (d *ProxyDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// HTTPClient 返回通过该拨号器连接的 HTTP 客户端，其余设置沿用 http.DefaultTransport
+func (d *ProxyDialer) HTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = d.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// httpConnect 发送 HTTP CONNECT 请求建立隧道
+func (d *ProxyDialer) httpConnect(conn net.Conn, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := d.proxy.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send CONNECT request: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proxy response: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy CONNECT failed: %s", resp.Status)
+	}
+
+	// 目标服务器的数据可能和代理响应一起到达，已读入缓冲区的数据需要保留
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先从缓冲区读取数据的连接
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// SOCKS5 协议常量（RFC 1928、RFC 1929）
+const (
+	socks5Version        = 0x05
+	socks5AuthNone       = 0x00
+	socks5AuthPassword   = 0x02
+	socks5AuthNoMatch    = 0xff
+	socks5CommandConnect = 0x01
+	socks5AddrIPv4       = 0x01
+	socks5AddrDomain     = 0x03
+	socks5AddrIPv6       = 0x04
+)
+
+// socks5Errors 是 SOCKS5 CONNECT 失败时的错误说明
+var socks5Errors = map[byte]string{
+	0x01: "general failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// socks5Connect 完成 SOCKS5 握手并请求连接目标地址
+// 域名交给代理服务器解析，避免本地 DNS 查询绕过代理
+func (d *ProxyDialer) socks5Connect(conn net.Conn, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid port in address %q", addr)
+	}
+
+	// 协商认证方式
+	methods := []byte{socks5AuthNone}
+	if d.proxy.User != nil {
+		methods = []byte{socks5AuthNone, socks5AuthPassword}
+	}
+	if _, err := conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...)); err != nil {
+		return fmt.Errorf("failed to send SOCKS5 greeting: %w", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("failed to read SOCKS5 greeting: %w", err)
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("unexpected SOCKS version %d", reply[0])
+	}
+
+	switch reply[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if d.proxy.User == nil {
+			return errors.New("SOCKS5 proxy requires authentication")
+		}
+		username := d.proxy.User.Username()
+		password, _ := d.proxy.User.Password()
+		if len(username) > 255 || len(password) > 255 {
+			return errors.New("SOCKS5 username or password is too long")
+		}
+		auth := []byte{0x01, byte(len(username))}
+		auth = append(auth, username...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if _, err := conn.Write(auth); err != nil {
+			return fmt.Errorf("failed to send SOCKS5 credentials: %w", err)
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return fmt.Errorf("failed to read SOCKS5 authentication reply: %w", err)
+		}
+		if reply[1] != 0x00 {
+			return errors.New("SOCKS5 authentication failed")
+		}
+	case socks5AuthNoMatch:
+		return errors.New("SOCKS5 proxy rejected all authentication methods")
+	default:
+		return fmt.Errorf("unsupported SOCKS5 authentication method %d", reply[1])
+	}
+
+	// 发送 CONNECT 请求
+	req := []byte{socks5Version, socks5CommandConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socks5AddrIPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socks5AddrIPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("host name %q is too long", host)
+		}
+		req = append(req, socks5AddrDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send SOCKS5 CONNECT request: %w", err)
+	}
+
+	// 读取响应，跳过服务器返回的绑定地址
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("failed to read SOCKS5 CONNECT reply: %w", err)
+	}
+	if header[1] != 0x00 {
+		reason, ok := socks5Errors[header[1]]
+		if !ok {
+			reason = fmt.Sprintf("error code %d", header[1])
+		}
+		return fmt.Errorf("SOCKS5 CONNECT failed: %s", reason)
+	}
+
+	var skip int
+	switch header[3] {
+	case socks5AddrIPv4:
+		skip = net.IPv4len
+	case socks5AddrIPv6:
+		skip = net.IPv6len
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return fmt.Errorf("failed to read SOCKS5 CONNECT reply: %w", err)
+		}
+		skip = int(length[0])
+	default:
+		return fmt.Errorf("unexpected SOCKS5 address type %d", header[3])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, skip+2)); err != nil {
+		return fmt.Errorf("failed to read SOCKS5 CONNECT reply: %w", err)
+	}
+
+	return nil
+}
+
+// newProxyHTTPClient 返回使用指定代理的 HTTP 客户端，代理 URL 无效时记录错误并直连
+// （配置文件中的代理 URL 已在 LoadConfig 时校验）
+func newProxyHTTPClient(proxyEnabled bool, proxyURL string, timeout time.Duration) *http.Client {
+	if !proxyEnabled {
+		proxyURL = ""
+	}
+	dialer, err := NewProxyDialer(proxyURL)
+	if err != nil {
+		log.Printf("代理配置无效，使用直连: %v", err)
+		dialer = &ProxyDialer{}
+	}
+	return dialer.HTTPClient(timeout)
+}
+
+// serviceProxyURL 返回单个服务实际使用的代理 URL，空字符串表示直连
+// override 为该服务的代理覆盖值：为空时使用全局代理，为 "direct" 时不使用代理
+func serviceProxyURL(config *Config, override string) string {
+	switch {
+	case override == directProxy:
+		return ""
+	case override != "":
+		return override
+	case config.Proxy.Enabled:
+		return config.Proxy.URL
+	}
+	return ""
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testHTTPProxy 是用于测试的 HTTP CONNECT 代理
+type testHTTPProxy struct {
+	credentials string      // 非空时要求 Proxy-Authorization 为该值的 Basic 认证
+	tlsConfig   *tls.Config // 非空时作为 https:// 代理
+	response    string      // 非空时直接返回该状态行而不建立隧道
+}
+
+// start 启动代理，返回代理 URL
+func (p *testHTTPProxy) start(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("启动代理失败: %v", err)
+	}
+	scheme := "http"
+	if p.tlsConfig != nil {
+		listener = tls.NewListener(listener, p.tlsConfig)
+		scheme = "https"
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+
+	return scheme + "://" + listener.Addr().String()
+}
+
+func (p *testHTTPProxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	req, err := http.ReadRequest(reader)
+	if err != nil {
+		return
+	}
+	if p.response != "" {
+		io.WriteString(conn, p.response+"\r\n\r\n")
+		return
+	}
+	if req.Method != http.MethodConnect {
+		io.WriteString(conn, "HTTP/1.1 405 Method Not Allowed\r\n\r\n")
+		return
+	}
+	if p.credentials != "" {
+		user, pass, _ := strings.Cut(p.credentials, ":")
+		check := &http.Request{Header: http.Header{"Authorization": req.Header["Proxy-Authorization"]}}
+		gotUser, gotPass, ok := check.BasicAuth()
+		if !ok || gotUser != user || gotPass != pass {
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return
+		}
+	}
+
+	target, err := net.Dial("tcp", req.Host)
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer target.Close()
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go io.Copy(target, reader)
+	io.Copy(conn, target)
+}
+
+// testSOCKS5Proxy 是用于测试的 SOCKS5 代理
+type testSOCKS5Proxy struct {
+	username, password string // 非空时要求用户名密码认证
+
+	mu      sync.Mutex
+	targets []string // 客户端请求连接的目标地址
+}
+
+// start 启动代理，返回代理地址
+func (p *testSOCKS5Proxy) start(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("启动代理失败: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func (p *testSOCKS5Proxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+
+	if p.username == "" {
+		conn.Write([]byte{socks5Version, socks5AuthNone})
+	} else {
+		if !strings.ContainsRune(string(methods), socks5AuthPassword) {
+			conn.Write([]byte{socks5Version, socks5AuthNoMatch})
+			return
+		}
+		conn.Write([]byte{socks5Version, socks5AuthPassword})
+		version := make([]byte, 2)
+		if _, err := io.ReadFull(conn, version); err != nil {
+			return
+		}
+		user := make([]byte, version[1])
+		io.ReadFull(conn, user)
+		length := make([]byte, 1)
+		io.ReadFull(conn, length)
+		pass := make([]byte, length[0])
+		io.ReadFull(conn, pass)
+		if string(user) != p.username || string(pass) != p.password {
+			conn.Write([]byte{0x01, 0x01})
+			return
+		}
+		conn.Write([]byte{0x01, 0x00})
+	}
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case socks5AddrIPv4:
+		ip := make([]byte, net.IPv4len)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		io.ReadFull(conn, length)
+		name := make([]byte, length[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return
+	}
+	portBytes := make([]byte, 2)
+	io.ReadFull(conn, portBytes)
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(portBytes))))
+
+	p.mu.Lock()
+	p.targets = append(p.targets, addr)
+	p.mu.Unlock()
+
+	target, err := net.Dial("tcp", addr)
+	if err != nil {
+		conn.Write([]byte{socks5Version, 0x05, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{socks5Version, 0x00, 0x00, socks5AddrIPv4, 127, 0, 0, 1, 0, 0})
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+// startEchoServer 启动一个先发送问候语、再逐行回显的 TCP 服务器，返回监听地址
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				text := textproto.NewConn(conn)
+				text.PrintfLine("hello")
+				for {
+					line, err := text.ReadLine()
+					if err != nil {
+						return
+					}
+					text.PrintfLine("echo %s", line)
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// checkEcho 通过连接完成一次问候和回显
+func checkEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	text := textproto.NewConn(conn)
+	if line, err := text.ReadLine(); err != nil || line != "hello" {
+		t.Fatalf("问候语不正确: %q %v", line, err)
+	}
+	text.PrintfLine("ping")
+	if line, err := text.ReadLine(); err != nil || line != "echo ping" {
+		t.Fatalf("回显不正确: %q %v", line, err)
+	}
+}
+
+// TestProxyDialerHTTPConnect 测试带认证的 HTTP CONNECT 代理
+func TestProxyDialerHTTPConnect(t *testing.T) {
+	target := startEchoServer(t)
+	proxyURL := (&testHTTPProxy{credentials: "user:p@ss"}).start(t)
+	host := strings.TrimPrefix(proxyURL, "http://")
+
+	dialer, err := NewProxyDialer("http://user:p%40ss@" + host)
+	if err != nil {
+		t.Fatalf("创建拨号器失败: %v", err)
+	}
+	conn, err := dialer.Dial("tcp", target)
+	if err != nil {
+		t.Fatalf("通过代理连接失败: %v", err)
+	}
+	checkEcho(t, conn)
+
+	dialer, _ = NewProxyDialer("http://user:wrong@" + host)
+	if _, err := dialer.Dial("tcp", target); err == nil || !strings.Contains(err.Error(), "407") {
+		t.Errorf("认证失败时应该返回 407 错误，实际为 %v", err)
+	}
+}
+
+// TestProxyDialerStatusLine 测试按状态码而不是子串判断 CONNECT 结果
+func TestProxyDialerStatusLine(t *testing.T) {
+	target := startEchoServer(t)
+	for _, response := range []string{
+		"HTTP/1.1 502 Bad Gateway (upstream returned 200)",
+		"HTTP/1.1 2000 OK",
+		"SSH-2.0-OpenSSH_8.9 200",
+	} {
+		proxyURL := (&testHTTPProxy{response: response}).start(t)
+		dialer, _ := NewProxyDialer(proxyURL)
+		if conn, err := dialer.Dial("tcp", target); err == nil {
+			conn.Close()
+			t.Errorf("代理响应 %q 不应该视为成功", response)
+		}
+	}
+}
+
+// TestProxyDialerHTTPS 测试通过 TLS 连接代理服务器
+func TestProxyDialerHTTPS(t *testing.T) {
+	cert, caFile := testCertificate(t)
+	target := startEchoServer(t)
+	proxyURL := (&testHTTPProxy{tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}}}).start(t)
+
+	dialer, err := NewProxyDialer(proxyURL)
+	if err != nil {
+		t.Fatalf("创建拨号器失败: %v", err)
+	}
+	if _, err := dialer.Dial("tcp", target); err == nil {
+		t.Error("未信任的代理证书应该返回错误")
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		t.Fatalf("读取 CA 文件失败: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(pem)
+	dialer.tlsConfig = &tls.Config{RootCAs: pool}
+
+	conn, err := dialer.Dial("tcp", target)
+	if err != nil {
+		t.Fatalf("通过 HTTPS 代理连接失败: %v", err)
+	}
+	checkEcho(t, conn)
+}
+
+// TestProxyDialerSOCKS5 测试 SOCKS5 代理的认证和域名转发
+func TestProxyDialerSOCKS5(t *testing.T) {
+	target := startEchoServer(t)
+	_, port, _ := net.SplitHostPort(target)
+	proxy := &testSOCKS5Proxy{username: "user", password: "secret"}
+	addr := proxy.start(t)
+
+	dialer, err := NewProxyDialer("socks5://user:secret@" + addr)
+	if err != nil {
+		t.Fatalf("创建拨号器失败: %v", err)
+	}
+
+	// 域名应该原样交给代理解析
+	conn, err := dialer.Dial("tcp", net.JoinHostPort("localhost", port))
+	if err != nil {
+		t.Fatalf("通过 SOCKS5 代理连接失败: %v", err)
+	}
+	checkEcho(t, conn)
+
+	conn, err = dialer.Dial("tcp", target)
+	if err != nil {
+		t.Fatalf("通过 SOCKS5 代理连接 IP 地址失败: %v", err)
+	}
+	checkEcho(t, conn)
+
+	proxy.mu.Lock()
+	targets := append([]string(nil), proxy.targets...)
+	proxy.mu.Unlock()
+	if len(targets) != 2 || targets[0] != "localhost:"+port || targets[1] != target {
+		t.Errorf("代理收到的目标地址不正确: %v", targets)
+	}
+
+	for _, rawURL := range []string{"socks5://user:wrong@" + addr, "socks5://" + addr} {
+		dialer, _ := NewProxyDialer(rawURL)
+		if _, err := dialer.Dial("tcp", target); err == nil {
+			t.Errorf("%s 认证失败时应该返回错误", rawURL)
+		}
+	}
+}
+
+// TestProxyDialerHTTPClient 测试 HTTP 客户端通过代理访问并保留默认 Transport 设置
+func TestProxyDialerHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	proxy := &testSOCKS5Proxy{}
+	client := newProxyHTTPClient(true, "socks5://"+proxy.start(t), 5*time.Second)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("通过代理请求失败: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("响应内容不正确: %s", body)
+	}
+	proxy.mu.Lock()
+	if len(proxy.targets) != 1 {
+		t.Errorf("请求应该经过代理，代理记录: %v", proxy.targets)
+	}
+	proxy.mu.Unlock()
+
+	transport := client.Transport.(*http.Transport)
+	if transport.TLSHandshakeTimeout == 0 || transport.IdleConnTimeout == 0 || !transport.ForceAttemptHTTP2 {
+		t.Error("HTTP 客户端应该沿用 http.DefaultTransport 的默认设置")
+	}
+}
+
+// TestNewProxyDialerInvalid 测试不支持的代理 URL
+func TestNewProxyDialerInvalid(t *testing.T) {
+	for _, rawURL := range []string{"ftp://127.0.0.1:21", "socks4://127.0.0.1", "http://", "://bad"} {
+		if _, err := NewProxyDialer(rawURL); err == nil {
+			t.Errorf("代理 URL %q 应该返回错误", rawURL)
+		}
+	}
+}
+
+// TestServiceProxyURL 测试单个服务的代理覆盖
+func TestServiceProxyURL(t *testing.T) {
+	config := &Config{}
+	config.Proxy.URL = "http://global:8080"
+
+	if got := serviceProxyURL(config, ""); got != "" {
+		t.Errorf("未启用全局代理时应该直连，实际为 %s", got)
+	}
+	if got := serviceProxyURL(config, "socks5://only-this:1080"); got != "socks5://only-this:1080" {
+		t.Errorf("服务代理应该在未启用全局代理时生效，实际为 %s", got)
+	}
+
+	config.Proxy.Enabled = true
+	if got := serviceProxyURL(config, ""); got != "http://global:8080" {
+		t.Errorf("未覆盖时应该使用全局代理，实际为 %s", got)
+	}
+	if got := serviceProxyURL(config, "direct"); got != "" {
+		t.Errorf("direct 应该直连，实际为 %s", got)
+	}
+}
@@ -2,233 +2,449 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"strings"
+	texttemplate "text/template"
 	"time"
 )
 
-type ReportGenerator struct{}
+// ReportData 是渲染报表模板时使用的数据模型
+type ReportData struct {
+	Locale      string            // 语言区域代码，如 zh-CN、en
+	Date        string            // 按语言区域格式化后的日期
+	GeneratedAt time.Time         // 报表生成时间
+	Coins       []CoinPrice       // 本次报表的币种数据（已按布局排序）
+	Sections    []ReportSection   // 按分组拆分的币种，未分组时只有一个无名区块
+	Columns     []string          // 需要显示的列
+	Totals      ReportTotals      // 汇总数据
+	Summary     MarketSummary     // 报表顶部的市场概要
+	Meta        ReportMeta        // 报表元信息
+	History     []HistorySnapshot // 历史快照（按时间升序，未启用时为空）
+	Recipient   string            // 收件人称呼（逐个发送且配置了 name 时），其余情况为空
+	Charts      []ReportChart     // 内嵌在 HTML 邮件中的图表（未启用时为空）
+}
+
+// reportCurrency 是报表的计价货币，与 CoinGecko 请求中的 vs_currency 一致
+const reportCurrency = "USD"
+
+// ReportTotals 表示报表中所有币种的汇总数据
+type ReportTotals struct {
+	Count              int
+	Gainers            int
+	Losers             int
+	Unchanged          int // 24h 涨跌幅为 0 的币种数
+	MarketCap          float64
+	Volume24h          float64
+	WeightedChangePerc float64 // 按市值加权的 24h 变化率
+}
+
+// ReportMeta 表示报表的元信息
+type ReportMeta struct {
+	Source    string
+	Generator string
+	Currency  string
+}
+
+type ReportGenerator struct {
+	locale    *Locale
+	templates map[string]*reportTemplates // 按语言区域代码索引
+	layout    ReportLayout
+	history   []HistorySnapshot
+	recipient string
+	charts    []ReportChart
+	// Discord Embed 样式：full（默认）或 compact
+	discordStyle string
+}
+
+// CoinRow 是 Discord 字段模板接收的数据：单个币种及需要显示的列
+type CoinRow struct {
+	CoinPrice
+	Columns []string
+}
+
+// builtinTemplates 是内置的默认模板，自定义模板渲染失败时回退使用
+var builtinTemplates = mustLoadBuiltinTemplates()
+
+func mustLoadBuiltinTemplates() map[string]*reportTemplates {
+	templates, err := loadLocalizedTemplates(TemplateConfig{})
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
 
 func NewReportGenerator() *ReportGenerator {
-	return &ReportGenerator{}
+	return &ReportGenerator{
+		locale:    locales[defaultLocale],
+		templates: builtinTemplates,
+	}
 }
 
-func (r *ReportGenerator) GenerateHTMLReport(coins []CoinPrice) string {
-	now := time.Now()
-	dateStr := now.Format("2006年01月02日")
-	
-	html := fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <title>每日加密货币价格报表 - %s</title>
-    <style>
-        body { 
-            font-family: Arial, sans-serif; 
-            margin: 20px; 
-            background-color: #f5f5f5;
-        }
-        .header { 
-            text-align: center; 
-            color: #2c3e50; 
-            margin-bottom: 30px;
-            padding: 20px;
-            background-color: white;
-            border-radius: 8px;
-            box-shadow: 0 2px 10px rgba(0,0,0,0.1);
-        }
-        .report-date { 
-            color: #7f8c8d; 
-            font-size: 16px; 
-            margin-top: 10px;
-        }
-        table { 
-            width: 100%%; 
-            border-collapse: collapse; 
-            background-color: white;
-            border-radius: 8px;
-            overflow: hidden;
-            box-shadow: 0 2px 10px rgba(0,0,0,0.1);
-        }
-        th, td { 
-            padding: 12px; 
-            text-align: left; 
-            border-bottom: 1px solid #ecf0f1;
-        }
-        th { 
-            background-color: #34495e; 
-            color: white; 
-            font-weight: bold;
-        }
-        tr:hover { 
-            background-color: #f8f9fa; 
-        }
-        .positive { 
-            color: #27ae60; 
-            font-weight: bold;
-        }
-        .negative { 
-            color: #e74c3c; 
-            font-weight: bold;
-        }
-        .price { 
-            font-weight: bold; 
-            font-size: 16px;
-        }
-        .footer { 
-            text-align: center; 
-            margin-top: 30px; 
-            color: #7f8c8d; 
-            font-size: 14px;
-            padding: 15px;
-            background-color: white;
-            border-radius: 8px;
-            box-shadow: 0 2px 10px rgba(0,0,0,0.1);
-        }
-    </style>
-</head>
-<body>
-    <div class="header">
-        <h1>🚀 每日加密货币价格报表</h1>
-        <div class="report-date">%s</div>
-    </div>
-    
-    <table>
-        <thead>
-            <tr>
-                <th>币种</th>
-                <th>符号</th>
-                <th>当前价格 (USD)</th>
-                <th>24h 变化</th>
-                <th>24h 变化率</th>
-                <th>市值</th>
-                <th>24h 交易量</th>
-            </tr>
-        </thead>
-        <tbody>`, dateStr, dateStr)
-
-	for _, coin := range coins {
-		changeClass := "positive"
-		changeSymbol := "+"
-		if coin.PriceChange24h < 0 {
-			changeClass = "negative"
-			changeSymbol = ""
-		}
-		
-		percChangeClass := "positive"
-		percChangeSymbol := "+"
-		if coin.PriceChangePerc24h < 0 {
-			percChangeClass = "negative"
-			percChangeSymbol = ""
+// NewReportGeneratorFromConfig 根据配置中的模板路径创建报表生成器
+// 创建时会使用示例数据试渲染一次，尽早暴露模板错误
+func NewReportGeneratorFromConfig(config *Config) (*ReportGenerator, error) {
+	locale, err := LookupLocale(config.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := loadLocalizedTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	gen := &ReportGenerator{
+		locale:    locale,
+		templates: templates,
+		layout:    newReportLayout(config),
+	}
+	for _, code := range localeCodes() {
+		if err := gen.ForLocale(code).validate(); err != nil {
+			return nil, fmt.Errorf("locale %s: %w", code, err)
 		}
+	}
+	return gen, nil
+}
+
+// ForLocale 返回使用指定语言区域渲染的报表生成器，模板和当前的历史快照与原生成器相同
+// code 为空或不支持时沿用当前语言区域
+func (r *ReportGenerator) ForLocale(code string) *ReportGenerator {
+	gen := *r
+	if locale, ok := locales[code]; ok {
+		gen.locale = locale
+	}
+	return &gen
+}
 
-		html += fmt.Sprintf(`
-            <tr>
-                <td><strong>%s</strong></td>
-                <td>%s</td>
-                <td class="price">$%s</td>
-                <td class="%s">%s$%s</td>
-                <td class="%s">%s%.2f%%</td>
-                <td>$%s</td>
-                <td>$%s</td>
-            </tr>`,
-			coin.Name,
-			strings.ToUpper(coin.Symbol),
-			formatNumber(coin.CurrentPrice),
-			changeClass, changeSymbol, formatNumber(coin.PriceChange24h),
-			percChangeClass, percChangeSymbol, coin.PriceChangePerc24h,
-			formatLargeNumber(coin.MarketCap),
-			formatLargeNumber(coin.Volume24h),
-		)
-	}
-
-	html += `
-        </tbody>
-    </table>
-    
-    <div class="footer">
-        <p>数据来源: CoinGecko API</p>
-        <p>此报表由 CoinDaily 自动生成</p>
-    </div>
-</body>
-</html>`
+// ForRecipient 返回为指定收件人渲染的报表生成器，name 会出现在报表的问候语中
+func (r *ReportGenerator) ForRecipient(name string) *ReportGenerator {
+	gen := *r
+	gen.recipient = name
+	return &gen
+}
+
+// WithCharts 返回在 HTML 报表中引用指定图表的报表生成器，图表作为内嵌图片随邮件发送
+func (r *ReportGenerator) WithCharts(charts []ReportChart) *ReportGenerator {
+	gen := *r
+	gen.charts = charts
+	return &gen
+}
+
+// WithDiscordStyle 返回使用指定 Embed 样式的报表生成器，style 为 full 或 compact
+func (r *ReportGenerator) WithDiscordStyle(style string) *ReportGenerator {
+	gen := *r
+	gen.discordStyle = style
+	return &gen
+}
+
+// Locale 返回报表生成器当前使用的语言区域
+func (r *ReportGenerator) Locale() *Locale {
+	return r.locale
+}
+
+// current 返回当前语言区域的模板，builtin 返回对应的内置模板
+func (r *ReportGenerator) current() *reportTemplates {
+	return r.templates[r.locale.Code]
+}
+
+func (r *ReportGenerator) builtin() *reportTemplates {
+	return builtinTemplates[r.locale.Code]
+}
+
+// validate 使用示例数据渲染全部模板
+func (r *ReportGenerator) validate() error {
+	data := r.BuildReportData(sampleCoins)
+	if _, err := r.current().executeHTML(data); err != nil {
+		return err
+	}
+	if _, err := executeText(r.current().text, "text", data); err != nil {
+		return err
+	}
+	if _, err := executeText(r.current().subject, "subject", data); err != nil {
+		return err
+	}
+	if _, err := r.renderDiscordEmbed(r.current(), data); err != nil {
+		return err
+	}
+	_, err := r.renderDiscordPriceEmbed(r.current(), data)
+	return err
+}
+
+// sampleCoins 是校验模板时使用的示例数据
+var sampleCoins = []CoinPrice{
+	{ID: "bitcoin", Symbol: "btc", Name: "Bitcoin", CurrentPrice: 45000, MarketCap: 850000000000,
+		PriceChange24h: 1000, PriceChangePerc24h: 2.27, Volume24h: 25000000000},
+	{ID: "ethereum", Symbol: "eth", Name: "Ethereum", CurrentPrice: 2800, MarketCap: 320000000000,
+		PriceChange24h: -50, PriceChangePerc24h: -1.75, Volume24h: 15000000000},
+}
+
+// SetHistory 设置渲染报表时提供给模板的历史快照
+func (r *ReportGenerator) SetHistory(history []HistorySnapshot) {
+	r.history = history
+}
+
+// BuildReportData 根据币种数据构建模板数据模型
+func (r *ReportGenerator) BuildReportData(coins []CoinPrice) *ReportData {
+	now := time.Now()
+	sorted := r.layout.sortCoins(coins)
+
+	return &ReportData{
+		Locale:      r.locale.Code,
+		Date:        r.locale.FormatDate(now),
+		GeneratedAt: now,
+		Coins:       sorted,
+		Sections:    r.layout.buildSections(sorted, r.locale.T("report.others")),
+		Columns:     r.layout.columnsOr(defaultHTMLColumns),
+		Totals:      computeTotals(sorted),
+		Summary:     computeMarketSummary(sorted, r.history, now.Format("2006-01-02")),
+		Meta: ReportMeta{
+			Source:    "CoinGecko API",
+			Generator: "CoinDaily",
+			Currency:  reportCurrency,
+		},
+		History:   r.history,
+		Recipient: r.recipient,
+		Charts:    r.charts,
+	}
+}
+
+func (r *ReportGenerator) GenerateHTMLReport(coins []CoinPrice) string {
+	data := r.BuildReportData(coins)
 
+	html, err := r.current().executeHTML(data)
+	if err != nil {
+		log.Printf("渲染自定义 HTML 模板失败，使用内置模板: %v", err)
+		html, _ = r.builtin().executeHTML(data)
+	}
 	return html
 }
 
-func formatNumber(num float64) string {
-	if num >= 1 {
-		return fmt.Sprintf("%.2f", num)
-	} else if num >= 0.01 {
-		return fmt.Sprintf("%.4f", num)
-	} else {
-		return fmt.Sprintf("%.8f", num)
+// GenerateTextReport 生成纯文本格式的报表，作为邮件 HTML 正文的替代版本
+func (r *ReportGenerator) GenerateTextReport(coins []CoinPrice) string {
+	data := r.BuildReportData(coins)
+
+	text, err := executeText(r.current().text, "text", data)
+	if err != nil {
+		log.Printf("渲染自定义纯文本模板失败，使用内置模板: %v", err)
+		text, _ = executeText(r.builtin().text, "text", data)
 	}
+	return text + "\n"
 }
 
-func formatLargeNumber(num float64) string {
-	if num >= 1e12 {
-		return fmt.Sprintf("%.2fT", num/1e12)
-	} else if num >= 1e9 {
-		return fmt.Sprintf("%.2fB", num/1e9)
-	} else if num >= 1e6 {
-		return fmt.Sprintf("%.2fM", num/1e6)
-	} else if num >= 1e3 {
-		return fmt.Sprintf("%.2fK", num/1e3)
+// GenerateSubject 生成邮件主题
+// 主题会写入邮件头，因此其中的控制字符（包括换行）会被替换为空格
+func (r *ReportGenerator) GenerateSubject(coins []CoinPrice) string {
+	data := r.BuildReportData(coins)
+
+	subject, err := executeText(r.current().subject, "subject", data)
+	if err != nil {
+		log.Printf("渲染自定义主题模板失败，使用内置模板: %v", err)
+		subject, _ = executeText(r.builtin().subject, "subject", data)
 	}
-	return fmt.Sprintf("%.2f", num)
+	return stripControlChars(subject)
 }
 
 // GenerateDiscordEmbed 生成 Discord Embed 格式的报表
 func (r *ReportGenerator) GenerateDiscordEmbed(coins []CoinPrice) *DiscordEmbed {
-	now := time.Now()
-	dateStr := now.Format("2006年01月02日")
+	data := r.BuildReportData(coins)
+
+	embed, err := r.renderDiscordEmbed(r.current(), data)
+	if err != nil {
+		log.Printf("渲染自定义 Discord 模板失败，使用内置模板: %v", err)
+		embed, _ = r.renderDiscordEmbed(r.builtin(), data)
+	}
+	return embed
+}
+
+// GenerateDiscordPriceEmbed 生成单个币种的 Discord Embed，用于 /price 命令
+func (r *ReportGenerator) GenerateDiscordPriceEmbed(coin CoinPrice) *DiscordEmbed {
+	data := r.BuildReportData([]CoinPrice{coin})
+
+	embed, err := r.renderDiscordPriceEmbed(r.current(), data)
+	if err != nil {
+		log.Printf("渲染自定义 Discord 模板失败，使用内置模板: %v", err)
+		embed, _ = r.renderDiscordPriceEmbed(r.builtin(), data)
+	}
+	return embed
+}
+
+// renderDiscordPriceEmbed 使用 price_title 和 price_description 模板块渲染 data 中第一个币种
+func (r *ReportGenerator) renderDiscordPriceEmbed(templates *reportTemplates, data *ReportData) (*DiscordEmbed, error) {
+	tmpl := templates.discord
+	data = escapeReportData(data)
+	coin := data.Coins[0]
+
+	color := 0x27AE60 // 绿色 - 上涨
+	if coin.PriceChangePerc24h < 0 {
+		color = 0xE74C3C // 红色 - 下跌
+	}
+
+	title, err := executeText(tmpl, "price_title", coin)
+	if err != nil {
+		return nil, err
+	}
+	description, err := executeText(tmpl, "price_description", coin)
+	if err != nil {
+		return nil, err
+	}
+	footer, err := executeText(tmpl, "footer", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiscordEmbed{
+		Title:       title,
+		Description: description,
+		Color:       color,
+		Fields:      []EmbedField{},
+		Footer:      &EmbedFooter{Text: footer},
+		Timestamp:   data.GeneratedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// renderDiscordEmbed 使用 Discord 模板渲染 Embed
+// Discord 模板中的币种名称、符号等来自 API 的字符串已经过 Markdown 转义
+func (r *ReportGenerator) renderDiscordEmbed(templates *reportTemplates, data *ReportData) (*DiscordEmbed, error) {
+	tmpl := templates.discord
+
+	data = escapeReportData(data)
+	data.Columns = r.layout.columnsOr(defaultDiscordColumns)
 
-	// 根据整体涨跌情况确定颜色
+	// 根据市值加权的整体涨跌情况确定颜色
 	color := 0xFFD700 // 默认金色
-	if len(coins) > 0 {
-		totalChange := 0.0
-		for _, coin := range coins {
-			totalChange += coin.PriceChangePerc24h
-		}
-		avgChange := totalChange / float64(len(coins))
-		if avgChange >= 0 {
+	if len(data.Coins) > 0 {
+		if data.Summary.WeightedChangePerc >= 0 {
 			color = 0x27AE60 // 绿色 - 整体上涨
 		} else {
 			color = 0xE74C3C // 红色 - 整体下跌
 		}
 	}
 
-	// 构建字段
-	fields := make([]EmbedField, 0, len(coins))
-	for _, coin := range coins {
-		// 格式化价格变化符号
-		changeSymbol := ""
-		if coin.PriceChangePerc24h >= 0 {
-			changeSymbol = "+"
-		}
-
-		// 构建字段值
-		value := fmt.Sprintf("**$%s**\n24h: %s%.2f%% | 市值: $%s",
-			formatNumber(coin.CurrentPrice),
-			changeSymbol,
-			coin.PriceChangePerc24h,
-			formatLargeNumber(coin.MarketCap),
-		)
+	title, err := executeText(tmpl, "title", data)
+	if err != nil {
+		return nil, err
+	}
+	description, err := executeText(tmpl, "description", data)
+	if err != nil {
+		return nil, err
+	}
+	footer, err := executeText(tmpl, "footer", data)
+	if err != nil {
+		return nil, err
+	}
 
-		fields = append(fields, EmbedField{
-			Name:   fmt.Sprintf("%s (%s)", coin.Name, strings.ToUpper(coin.Symbol)),
-			Value:  value,
-			Inline: true,
-		})
+	// compact 样式不使用字段，每个币种在描述中占一行，适合币种较多的频道
+	var fields []EmbedField
+	if r.discordStyle == discordStyleCompact {
+		description, err = compactDiscordDescription(tmpl, data)
+		fields = []EmbedField{}
+	} else {
+		fields, err = discordFields(tmpl, data)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	return &DiscordEmbed{
-		Title:       "🚀 每日加密货币价格报表",
-		Description: dateStr,
+		Title:       title,
+		Description: description,
 		Color:       color,
 		Fields:      fields,
-		Footer:      &EmbedFooter{Text: "数据来源: CoinGecko API | CoinDaily 自动生成"},
-		Timestamp:   now.Format(time.RFC3339),
+		Footer:      &EmbedFooter{Text: footer},
+		Timestamp:   data.GeneratedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// discordFields 为每个币种构建一个字段，分组时每个分组前插入一个小计字段
+func discordFields(tmpl *texttemplate.Template, data *ReportData) ([]EmbedField, error) {
+	fields := make([]EmbedField, 0, len(data.Coins)+len(data.Sections))
+	for _, section := range data.Sections {
+		if section.Name != "" {
+			name, err := executeText(tmpl, "section_name", section)
+			if err != nil {
+				return nil, err
+			}
+			value, err := executeText(tmpl, "section_value", section)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, EmbedField{Name: name, Value: value})
+		}
+
+		for _, coin := range section.Coins {
+			row := CoinRow{CoinPrice: coin, Columns: data.Columns}
+			name, err := executeText(tmpl, "field_name", row)
+			if err != nil {
+				return nil, err
+			}
+			value, err := executeText(tmpl, "field_value", row)
+			if err != nil {
+				return nil, err
+			}
+
+			fields = append(fields, EmbedField{
+				Name:   name,
+				Value:  value,
+				Inline: true,
+			})
+		}
+	}
+	return fields, nil
+}
+
+// compactDiscordDescription 构建 compact 样式的描述：日期和一行市场概要之后每个币种一行
+func compactDiscordDescription(tmpl *texttemplate.Template, data *ReportData) (string, error) {
+	summary, err := executeText(tmpl, "compact_summary", data)
+	if err != nil {
+		return "", err
 	}
-}
\ No newline at end of file
+	lines := []string{data.Date}
+	if summary != "" {
+		lines = append(lines, summary)
+	}
+	lines = append(lines, "")
+	for _, coin := range data.Coins {
+		line, err := executeText(tmpl, "compact_line", CoinRow{CoinPrice: coin, Columns: data.Columns})
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// escapeReportData 返回币种文本字段经过 Discord Markdown 转义的数据副本
+func escapeReportData(data *ReportData) *ReportData {
+	escaped := *data
+	escaped.Coins = escapeCoinsForDiscord(data.Coins)
+	escaped.Sections = make([]ReportSection, len(data.Sections))
+	for i, section := range data.Sections {
+		section.Coins = escapeCoinsForDiscord(section.Coins)
+		escaped.Sections[i] = section
+	}
+
+	summary := data.Summary
+	if summary.TopGainer != nil {
+		coin := escapeCoinForDiscord(*summary.TopGainer)
+		summary.TopGainer = &coin
+	}
+	if summary.TopLoser != nil {
+		coin := escapeCoinForDiscord(*summary.TopLoser)
+		summary.TopLoser = &coin
+	}
+	summary.Events = make([]MarketEvent, len(data.Summary.Events))
+	for i, event := range data.Summary.Events {
+		event.Coin = escapeCoinForDiscord(event.Coin)
+		summary.Events[i] = event
+	}
+	escaped.Summary = summary
+	return &escaped
+}
+
+func escapeCoinsForDiscord(coins []CoinPrice) []CoinPrice {
+	escaped := make([]CoinPrice, len(coins))
+	for i, coin := range coins {
+		escaped[i] = escapeCoinForDiscord(coin)
+	}
+	return escaped
+}
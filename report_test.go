@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -149,3 +151,433 @@ func TestGenerateDiscordEmbedEmpty(t *testing.T) {
 		t.Errorf("空列表应该生成 0 个字段，实际为 %d", len(embed.Fields))
 	}
 }
+
+// TestGenerateHTMLReportDefaultTemplate 测试内置模板渲染出币种数据
+func TestGenerateHTMLReportDefaultTemplate(t *testing.T) {
+	gen := NewReportGenerator()
+	html := gen.GenerateHTMLReport(sampleCoins)
+
+	for _, want := range []string{"<table>", "Bitcoin", "ETH", "$45,000.00", "数据来源: CoinGecko API"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML 报表应该包含 %q", want)
+		}
+	}
+}
+
+// TestReportGeneratorCustomTemplates 测试配置中的自定义模板覆盖内置模板
+func TestReportGeneratorCustomTemplates(t *testing.T) {
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "report.html.tmpl")
+	subjectPath := filepath.Join(dir, "subject.tmpl")
+	discordPath := filepath.Join(dir, "discord.tmpl")
+
+	writeTestFile(t, htmlPath, `<ul>{{range .Coins}}<li>{{.Name}}</li>{{end}}</ul><p>{{.Totals.Count}}</p>`)
+	writeTestFile(t, subjectPath, `Crypto report {{.Date}}`)
+	// 只覆盖部分模板块，其余沿用内置模板
+	writeTestFile(t, discordPath, `{{define "title"}}Daily {{.Totals.Count}} coins{{end}}`)
+
+	config := &Config{}
+	config.Templates.EmailHTML = htmlPath
+	config.Templates.EmailSubject = subjectPath
+	config.Templates.Discord = discordPath
+
+	gen, err := NewReportGeneratorFromConfig(config)
+	if err != nil {
+		t.Fatalf("加载自定义模板失败: %v", err)
+	}
+
+	html := gen.GenerateHTMLReport(sampleCoins)
+	if html != "<ul><li>Bitcoin</li><li>Ethereum</li></ul><p>2</p>" {
+		t.Errorf("自定义 HTML 模板渲染结果不正确: %s", html)
+	}
+
+	if subject := gen.GenerateSubject(sampleCoins); !strings.HasPrefix(subject, "Crypto report ") {
+		t.Errorf("自定义主题模板渲染结果不正确: %s", subject)
+	}
+
+	embed := gen.GenerateDiscordEmbed(sampleCoins)
+	if embed.Title != "Daily 2 coins" {
+		t.Errorf("自定义 Discord 标题不正确: %s", embed.Title)
+	}
+	if !strings.Contains(embed.Fields[0].Name, "BTC") {
+		t.Errorf("未覆盖的模板块应该使用内置模板，实际为 %s", embed.Fields[0].Name)
+	}
+}
+
+// TestReportGeneratorInvalidTemplate 测试模板错误在加载时被发现
+func TestReportGeneratorInvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"语法错误", `{{range .Coins}}`},
+		{"未知字段", `{{.NoSuchField}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "bad.tmpl")
+			writeTestFile(t, path, tt.content)
+
+			config := &Config{}
+			config.Templates.EmailHTML = path
+			if _, err := NewReportGeneratorFromConfig(config); err == nil {
+				t.Error("无效模板应该返回错误")
+			}
+		})
+	}
+
+	config := &Config{}
+	config.Templates.Discord = filepath.Join(dir, "missing.tmpl")
+	if _, err := NewReportGeneratorFromConfig(config); err == nil {
+		t.Error("模板文件不存在时应该返回错误")
+	}
+}
+
+// TestBuildReportDataTotals 测试汇总数据计算
+func TestBuildReportDataTotals(t *testing.T) {
+	gen := NewReportGenerator()
+	data := gen.BuildReportData(sampleCoins)
+
+	if data.Totals.Count != 2 || data.Totals.Gainers != 1 || data.Totals.Losers != 1 {
+		t.Errorf("涨跌统计不正确: %+v", data.Totals)
+	}
+	if data.Totals.MarketCap != 1170000000000 {
+		t.Errorf("总市值不正确: %v", data.Totals.MarketCap)
+	}
+
+	// 涨跌幅为 0 的币种既不算上涨也不算下跌
+	flat := append([]CoinPrice{{ID: "tether", Symbol: "usdt", Name: "Tether", CurrentPrice: 1}}, sampleCoins...)
+	data = gen.BuildReportData(flat)
+	if data.Totals.Count != 3 || data.Totals.Gainers != 1 || data.Totals.Losers != 1 || data.Totals.Unchanged != 1 {
+		t.Errorf("涨跌幅为 0 的币种应该计入持平: %+v", data.Totals)
+	}
+}
+
+// writeTestFile 写入测试用文件
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+}
+
+// hostileCoins 返回名称中带有 HTML、Markdown 和控制字符的币种数据
+func hostileCoins() []CoinPrice {
+	return []CoinPrice{
+		{
+			ID:                 "evil",
+			Symbol:             `<img src=x onerror=alert(1)>`,
+			Name:               `<script>alert("x")</script>**Moon**`,
+			CurrentPrice:       1,
+			PriceChangePerc24h: 1,
+		},
+		{
+			ID:                 "spam",
+			Symbol:             "`x`",
+			Name:               "[Claim airdrop](https://evil.example) @everyone\n# Title __u__ ~~s~~ ||spoiler|| > quote",
+			CurrentPrice:       1,
+			PriceChangePerc24h: -1,
+		},
+	}
+}
+
+// TestGenerateHTMLReportEscapesHostileNames 测试 HTML 报表转义 API 返回的字符串
+func TestGenerateHTMLReportEscapesHostileNames(t *testing.T) {
+	gen := NewReportGenerator()
+	html := gen.GenerateHTMLReport(hostileCoins())
+
+	for _, bad := range []string{"<script>", "<img", `onerror=alert(1)>`} {
+		if strings.Contains(html, bad) {
+			t.Errorf("HTML 报表不应该包含未转义的 %q", bad)
+		}
+	}
+	if !strings.Contains(html, "&lt;script&gt;") {
+		t.Error("HTML 报表应该包含转义后的 <script>")
+	}
+	if !strings.Contains(html, "&lt;IMG SRC=X ONERROR=ALERT(1)&gt;") {
+		t.Error("HTML 报表应该包含转义后的符号")
+	}
+}
+
+// TestGenerateDiscordEmbedEscapesHostileNames 测试 Discord Embed 转义 Markdown
+func TestGenerateDiscordEmbedEscapesHostileNames(t *testing.T) {
+	gen := NewReportGenerator()
+	embed := gen.GenerateDiscordEmbed(hostileCoins())
+
+	first := embed.Fields[0].Name
+	if !strings.Contains(first, `\*\*Moon\*\*`) {
+		t.Errorf("Markdown 加粗应该被转义，实际为 %q", first)
+	}
+	if !strings.Contains(first, `\<script\>`) {
+		t.Errorf("尖括号应该被转义，实际为 %q", first)
+	}
+
+	second := embed.Fields[1].Name
+	for _, bad := range []string{"[Claim airdrop]", "@everyone", "\n", "__u__", "~~s~~", "||spoiler||", "`X`"} {
+		if strings.Contains(second, bad) {
+			t.Errorf("字段名不应该包含未转义的 %q，实际为 %q", bad, second)
+		}
+	}
+	if !strings.Contains(second, `\[Claim airdrop\]`) {
+		t.Errorf("链接语法应该被转义，实际为 %q", second)
+	}
+
+	// 模板中的格式化标记不受影响
+	if !strings.HasPrefix(embed.Fields[0].Value, "**$") {
+		t.Errorf("字段值中的价格应该保持加粗，实际为 %q", embed.Fields[0].Value)
+	}
+}
+
+// TestEscapeDiscordMarkdown 测试 Markdown 转义函数
+func TestEscapeDiscordMarkdown(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Bitcoin", "Bitcoin"},
+		{"Wrapped Bitcoin (WBTC)", "Wrapped Bitcoin (WBTC)"},
+		{`a\*b`, `a\\\*b`},
+		{"line1\r\nline2", "line1  line2"},
+		{"@here", "@\u200bhere"},
+		{":smile:", `\:smile\:`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeDiscordMarkdown(tt.in); got != tt.want {
+			t.Errorf("escapeDiscordMarkdown(%q) = %q，期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestGenerateSubjectStripsNewlines 测试邮件主题中不会出现换行
+func TestGenerateSubjectStripsNewlines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subject.tmpl")
+	writeTestFile(t, path, `{{range .Coins}}{{.Name}} {{end}}`)
+
+	config := &Config{}
+	config.Templates.EmailSubject = path
+	gen, err := NewReportGeneratorFromConfig(config)
+	if err != nil {
+		t.Fatalf("加载模板失败: %v", err)
+	}
+
+	subject := gen.GenerateSubject(hostileCoins())
+	if strings.ContainsAny(subject, "\r\n") {
+		t.Errorf("邮件主题不应该包含换行: %q", subject)
+	}
+}
+
+// TestReportGeneratorForLocale 测试按语言区域渲染报表
+func TestReportGeneratorForLocale(t *testing.T) {
+	gen := NewReportGenerator()
+	en := gen.ForLocale("en")
+
+	html := en.GenerateHTMLReport(sampleCoins)
+	for _, want := range []string{`lang="en"`, "Daily Crypto Price Report", "Market Cap", "Price (USD)"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("英文 HTML 报表应该包含 %q", want)
+		}
+	}
+	if strings.Contains(html, "市值") {
+		t.Error("英文 HTML 报表不应该包含中文列名")
+	}
+
+	if subject := en.GenerateSubject(sampleCoins); !strings.HasPrefix(subject, "Daily Crypto Price Report - ") {
+		t.Errorf("英文邮件主题不正确: %s", subject)
+	}
+
+	embed := en.GenerateDiscordEmbed(sampleCoins)
+	if !strings.Contains(embed.Fields[0].Value, "Market Cap") {
+		t.Errorf("英文 Embed 字段不正确: %s", embed.Fields[0].Value)
+	}
+
+	// 原生成器不受影响，不支持的代码沿用当前语言区域
+	if gen.Locale().Code != "zh-CN" || gen.ForLocale("fr").Locale().Code != "zh-CN" {
+		t.Error("ForLocale 不应该修改原生成器")
+	}
+}
+
+// TestReportGeneratorForRecipient 测试报表中的收件人问候语
+func TestReportGeneratorForRecipient(t *testing.T) {
+	gen := NewReportGenerator()
+
+	alice := gen.ForLocale("en").ForRecipient("Alice & Co")
+	if text := alice.GenerateTextReport(sampleCoins); !strings.HasPrefix(text, "Hi Alice & Co,\n\nDaily Crypto Price Report") {
+		t.Errorf("纯文本报表应该以问候语开头: %q", text[:40])
+	}
+	if html := alice.GenerateHTMLReport(sampleCoins); !strings.Contains(html, "Hi Alice &amp; Co,") {
+		t.Error("HTML 报表应该包含转义后的问候语")
+	}
+
+	if text := gen.ForRecipient("张三").GenerateTextReport(sampleCoins); !strings.HasPrefix(text, "张三，您好：") {
+		t.Errorf("中文问候语不正确: %q", text[:20])
+	}
+
+	// 未设置称呼时不输出问候语
+	if strings.Contains(gen.GenerateHTMLReport(sampleCoins), "greeting") {
+		t.Error("未设置称呼时不应该输出问候语")
+	}
+}
+
+// TestReportGeneratorWithCharts 测试 HTML 报表通过 cid: 引用内嵌图表
+func TestReportGeneratorWithCharts(t *testing.T) {
+	gen := NewReportGenerator()
+	if strings.Contains(gen.GenerateHTMLReport(sampleCoins), "<img") {
+		t.Error("未设置图表时不应该输出图片")
+	}
+
+	charts := gen.ForLocale("en").WithCharts([]ReportChart{{ContentID: "change-24h@coindaily", Title: "24h Change"}})
+	html := charts.GenerateHTMLReport(sampleCoins)
+	// html/template 默认会把未知协议的 URL 替换为 #ZgotmplZ
+	if !strings.Contains(html, `src="cid:change-24h@coindaily"`) {
+		t.Errorf("HTML 报表应该通过 cid: 引用图表: %s", html)
+	}
+	if !strings.Contains(html, `alt="24h Change"`) {
+		t.Error("图表应该带有标题作为替代文本")
+	}
+	if strings.Contains(charts.GenerateTextReport(sampleCoins), "cid:") {
+		t.Error("纯文本报表不应该引用图表")
+	}
+	if strings.Contains(gen.GenerateHTMLReport(sampleCoins), "<img") {
+		t.Error("WithCharts 不应该修改原生成器")
+	}
+}
+
+// TestReportLayoutAppliesToBothRenderers 测试列、排序和分组同时作用于 HTML 和 Discord
+func TestReportLayoutAppliesToBothRenderers(t *testing.T) {
+	config := &Config{Coins: []string{"ethereum", "bitcoin"}}
+	config.Report.Columns = []string{"name", "price", "volume"}
+	config.Report.Groups = []CoinGroup{{Name: "Majors", Coins: []string{"bitcoin"}}}
+
+	gen, err := NewReportGeneratorFromConfig(config)
+	if err != nil {
+		t.Fatalf("创建报表生成器失败: %v", err)
+	}
+
+	html := gen.GenerateHTMLReport(sampleCoins)
+	if strings.Contains(html, "<th>市值</th>") || strings.Contains(html, "<td>BTC</td>") {
+		t.Error("HTML 报表不应该包含未配置的列")
+	}
+	if !strings.Contains(html, "24h 交易量") || !strings.Contains(html, "Majors") || !strings.Contains(html, "小计") {
+		t.Error("HTML 报表应该包含配置的列、分组名和小计")
+	}
+	if strings.Index(html, "Bitcoin") > strings.Index(html, "Ethereum") {
+		t.Error("Majors 分组应该排在 其他 分组之前")
+	}
+
+	embed := gen.GenerateDiscordEmbed(sampleCoins)
+	if len(embed.Fields) != 4 {
+		t.Fatalf("期望 2 个分组字段和 2 个币种字段，实际为 %d", len(embed.Fields))
+	}
+	if !strings.Contains(embed.Fields[0].Name, "Majors") || embed.Fields[0].Inline {
+		t.Errorf("第一个字段应该是 Majors 分组标题: %+v", embed.Fields[0])
+	}
+	if embed.Fields[1].Name != "Bitcoin" {
+		t.Errorf("未配置 symbol 列时字段名只包含名称，实际为 %q", embed.Fields[1].Name)
+	}
+	if !strings.Contains(embed.Fields[1].Value, "24h 交易量") || strings.Contains(embed.Fields[1].Value, "市值") {
+		t.Errorf("Discord 字段值应该只包含配置的列: %q", embed.Fields[1].Value)
+	}
+	if !strings.Contains(embed.Fields[2].Name, "其他") || embed.Fields[3].Name != "Ethereum" {
+		t.Errorf("未分组的币种应该放在 其他 分组: %+v", embed.Fields[2:])
+	}
+}
+
+// TestGenerateDiscordEmbedWeightedColor 测试 Embed 颜色使用市值加权的涨跌幅
+func TestGenerateDiscordEmbedWeightedColor(t *testing.T) {
+	// 简单平均为上涨，但市值最大的币种下跌，加权后整体下跌
+	coins := []CoinPrice{
+		{ID: "bitcoin", Symbol: "btc", Name: "Bitcoin", MarketCap: 850e9, PriceChangePerc24h: -2},
+		{ID: "pepe", Symbol: "pepe", Name: "Pepe", MarketCap: 1e9, PriceChangePerc24h: 30},
+	}
+
+	gen := NewReportGenerator()
+	embed := gen.GenerateDiscordEmbed(coins)
+
+	if embed.Color != 0xE74C3C {
+		t.Errorf("加权整体下跌时应该使用红色，实际为 %#x", embed.Color)
+	}
+	if !strings.Contains(embed.Description, "领涨: Pepe +30.00%") || !strings.Contains(embed.Description, "领跌: Bitcoin -2.00%") {
+		t.Errorf("Embed 描述应该包含市场概要，实际为 %q", embed.Description)
+	}
+
+	html := gen.GenerateHTMLReport(coins)
+	if !strings.Contains(html, `class="summary"`) || !strings.Contains(html, "上涨 1 · 下跌 1 · 持平 0") {
+		t.Error("HTML 报表顶部应该包含市场概要")
+	}
+}
+
+// TestGenerateTextReportAlignment 测试纯文本报表的表格对齐
+func TestGenerateTextReportAlignment(t *testing.T) {
+	for _, code := range []string{"zh-CN", "en"} {
+		gen := NewReportGenerator().ForLocale(code)
+		text := gen.GenerateTextReport(sampleCoins)
+
+		if strings.ContainsAny(text, "<>") {
+			t.Errorf("%s 纯文本报表不应该包含 HTML 标签:\n%s", code, text)
+		}
+
+		// 表头、分隔线和每个币种的行显示宽度一致（最后一列右对齐）
+		var widths []int
+		for _, line := range strings.Split(text, "\n") {
+			if strings.HasPrefix(line, "---") {
+				widths = append(widths, displayWidth(line))
+				continue
+			}
+			for _, coin := range sampleCoins {
+				if strings.HasPrefix(line, coin.Name+" ") {
+					widths = append(widths, displayWidth(line))
+				}
+			}
+		}
+		if len(widths) != len(sampleCoins)+1 {
+			t.Fatalf("%s 纯文本报表缺少表格行:\n%s", code, text)
+		}
+		for _, w := range widths[1:] {
+			if w != widths[0] {
+				t.Errorf("%s 纯文本表格未对齐: %v\n%s", code, widths, text)
+				break
+			}
+		}
+	}
+}
+
+// TestDisplayWidth 测试中文和全角字符按两列计算宽度
+func TestDisplayWidth(t *testing.T) {
+	tests := map[string]int{
+		"Bitcoin": 7,
+		"比特币":     6,
+		"市值 BTC":  8,
+		"":        0,
+	}
+	for s, want := range tests {
+		if got := displayWidth(s); got != want {
+			t.Errorf("displayWidth(%q) = %d, 期望 %d", s, got, want)
+		}
+	}
+}
+
+// TestGenerateDiscordEmbedCompact 测试 compact 样式每个币种一行且不使用字段
+func TestGenerateDiscordEmbedCompact(t *testing.T) {
+	embed := NewReportGenerator().ForLocale("en").WithDiscordStyle(discordStyleCompact).GenerateDiscordEmbed(sampleCoins)
+
+	if len(embed.Fields) != 0 {
+		t.Errorf("compact 样式不应该有字段，实际为 %d 个", len(embed.Fields))
+	}
+	for _, want := range []string{"**BTC** $", "**ETH** $", "📊 "} {
+		if !strings.Contains(embed.Description, want) {
+			t.Errorf("描述应该包含 %q，实际为:\n%s", want, embed.Description)
+		}
+	}
+	if lines := strings.Split(embed.Description, "\n"); len(lines) < 2 || !strings.HasPrefix(lines[1], "📊 ") {
+		t.Errorf("compact 样式应该在日期下方显示一行市场概要，实际为:\n%s", embed.Description)
+	}
+
+	// 默认样式不受影响
+	if len(NewReportGenerator().GenerateDiscordEmbed(sampleCoins).Fields) != len(sampleCoins) {
+		t.Error("默认样式应该每个币种一个字段")
+	}
+}
@@ -1,59 +1,133 @@
 package main
 
 import (
-	"fmt"
+	"errors"
 	"log"
+	"net/mail"
 	"time"
 )
 
 type Scheduler struct {
-	config        *Config
-	coinClient    *CoinGeckoClient
-	emailSender   *EmailSender
-	discordSender *DiscordSender
-	reportGen     *ReportGenerator
-	stopChan      chan bool
+	config         *Config
+	coinClient     *CoinGeckoClient
+	emailSender    *EmailSender
+	outbox         *Outbox        // 发送失败的邮件，由定时任务按退避策略重试
+	discordSender  *DiscordSender // 第一个 Discord 目标的发送器
+	discordTargets []discordTargetSender
+	board          *LiveBoard // Discord 实时看板，未启用时为 nil
+	boardUpdatedAt time.Time
+	reportGen      *ReportGenerator
+	history        *HistoryStore
+	stopChan       chan bool
 }
 
 func NewScheduler(config *Config) *Scheduler {
+	// 各服务可以单独配置代理，未配置时使用全局代理
+	coinGeckoProxy := serviceProxyURL(config, config.Proxy.CoinGecko)
+	discordProxy := serviceProxyURL(config, config.Proxy.Discord)
+	emailProxy := serviceProxyURL(config, config.Proxy.Email)
+
 	scheduler := &Scheduler{
 		config:     config,
-		coinClient: NewCoinGeckoClient(config.CoinGecko.APIKey, config.Proxy.Enabled, config.Proxy.URL),
-		reportGen:  NewReportGenerator(),
+		coinClient: NewCoinGeckoClient(config.CoinGecko.APIKey, coinGeckoProxy != "", coinGeckoProxy),
 		stopChan:   make(chan bool),
 	}
 
+	// 加载自定义模板，失败时回退到内置模板（LoadConfig 已校验过模板）
+	reportGen, err := NewReportGeneratorFromConfig(config)
+	if err != nil {
+		log.Printf("加载报表模板失败，使用内置模板: %v", err)
+		reportGen = NewReportGenerator()
+	}
+	scheduler.reportGen = reportGen
+
+	if config.History.Enabled {
+		scheduler.history = NewHistoryStore(historyPath(config), config.History.Days)
+	}
+
 	// 如果配置了邮件，初始化邮件发送器
 	if isEmailConfigured(config) {
 		emailConfig := EmailConfig{
-			SMTPServer:   config.Email.SMTPServer,
-			SMTPPort:     config.Email.SMTPPort,
-			Username:     config.Email.Username,
-			Password:     config.Email.Password,
-			To:           config.Email.To,
-			ProxyEnabled: config.Proxy.Enabled,
-			ProxyURL:     config.Proxy.URL,
+			SMTPServer:       config.Email.SMTPServer,
+			SMTPPort:         config.Email.SMTPPort,
+			Username:         config.Email.Username,
+			Password:         config.Email.Password,
+			Auth:             config.Email.Auth,
+			To:               emailAddresses(config),
+			From:             config.Email.From,
+			FromName:         config.Email.FromName,
+			ReplyTo:          config.Email.ReplyTo,
+			ListID:           config.Email.ListID,
+			TransferEncoding: config.Email.TransferEncoding,
+			TLS:              config.Email.TLS,
+			TLSCAFile:        config.Email.TLSCAFile,
+			TLSMinVersion:    config.Email.TLSMinVersion,
+			TLSSkipVerify:    config.Email.TLSInsecureSkipVerify,
+			DKIMDomain:       config.Email.DKIM.Domain,
+			DKIMSelector:     config.Email.DKIM.Selector,
+			DKIMKeyFile:      config.Email.DKIM.PrivateKeyFile,
+			ProxyEnabled:     emailProxy != "",
+			ProxyURL:         emailProxy,
+		}
+		// LoadConfig 已校验过投递方式，HTTP API 与 SMTP 使用相同的代理
+		emailConfig.Transport, _ = emailTransport(config, newProxyHTTPClient(emailProxy != "", emailProxy, httpTransportTimeout))
+		if config.Email.Auth == authXOAuth2 {
+			// LoadConfig 已校验过 OAuth2 配置
+			emailConfig.OAuth2, _ = emailOAuth2Config(config)
 		}
 		scheduler.emailSender = NewEmailSender(emailConfig)
+		scheduler.outbox = newEmailOutbox(config)
 	}
 
-	// 如果配置了 Discord，初始化 Discord 发送器
-	if isDiscordConfigured(config) {
-		scheduler.discordSender = NewDiscordSender(
-			config.Discord.BotToken,
-			config.Discord.ChannelID,
-			config.Proxy.Enabled,
-			config.Proxy.URL,
-		)
+	// 如果配置了 Discord，为每个目标初始化发送器，顶层配置了 webhook 时优先使用 webhook
+	for _, target := range discordTargets(config) {
+		scheduler.discordTargets = append(scheduler.discordTargets, discordTargetSender{
+			target: target,
+			sender: newDiscordTargetSender(config, target, discordProxy),
+		})
+	}
+	if len(scheduler.discordTargets) > 0 {
+		scheduler.discordSender = scheduler.discordTargets[0].sender
+	}
+
+	if config.Discord.Board.Enabled {
+		channelID := boardChannelID(config)
+		sender := NewDiscordSender(config.Discord.BotToken, channelID, discordProxy != "", discordProxy)
+		scheduler.board = NewLiveBoard(sender, channelID, boardStatePath(config), config.Discord.Board.Pin)
 	}
 
 	return scheduler
 }
 
+// discordTargetSender 是一个 Discord 目标及其发送器
+type discordTargetSender struct {
+	target DiscordTarget
+	sender *DiscordSender
+}
+
+// newDiscordTargetSender 创建目标的发送器，webhook 目标不需要 Bot Token
+func newDiscordTargetSender(config *Config, target DiscordTarget, proxy string) *DiscordSender {
+	var sender *DiscordSender
+	if target.WebhookURL != "" {
+		sender = NewDiscordWebhookSender(DiscordWebhook{
+			URL:       target.WebhookURL,
+			Username:  target.Username,
+			AvatarURL: target.AvatarURL,
+			ThreadID:  target.ThreadID,
+		}, proxy != "", proxy)
+	} else {
+		sender = NewDiscordSender(config.Discord.BotToken, target.ChannelID, proxy != "", proxy)
+	}
+	sender.SetPostOptions(discordPostOptions(config, target))
+	return sender
+}
+
 func (s *Scheduler) Start() {
 	log.Println("启动定时任务调度器...")
 
+	s.retryOutbox(false)
 	s.runOnceNow()
+	s.updateBoard(time.Now())
 
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
@@ -61,10 +135,15 @@ func (s *Scheduler) Start() {
 	for {
 		select {
 		case <-ticker.C:
+			// 邮件和未单独设置时间的 Discord 目标按全局时间发送，其余目标按各自的时间发送
 			now := time.Now()
-			if now.Hour() == s.config.Schedule.Hour && now.Minute() == s.config.Schedule.Minute {
-				s.runDailyReport()
+			daily := now.Hour() == s.config.Schedule.Hour && now.Minute() == s.config.Schedule.Minute
+			targets := s.dueDiscordTargets(now)
+			if daily || len(targets) > 0 {
+				s.runReport(daily, targets)
 			}
+			s.retryOutbox(false)
+			s.updateBoard(now)
 		case <-s.stopChan:
 			log.Println("定时任务调度器已停止")
 			return
@@ -78,58 +157,121 @@ func (s *Scheduler) Stop() {
 
 func (s *Scheduler) runOnceNow() {
 	log.Println("立即执行一次报表生成...")
-	s.runDailyReport()
+	s.runReport(true, s.discordTargets)
+}
+
+// dueDiscordTargets 返回在 now 这一分钟需要发送的 Discord 目标
+func (s *Scheduler) dueDiscordTargets(now time.Time) []discordTargetSender {
+	var due []discordTargetSender
+	for _, t := range s.discordTargets {
+		schedule := ScheduleTime{Hour: s.config.Schedule.Hour, Minute: s.config.Schedule.Minute}
+		if t.target.Schedule != nil {
+			schedule = *t.target.Schedule
+		}
+		if now.Hour() == schedule.Hour && now.Minute() == schedule.Minute {
+			due = append(due, t)
+		}
+	}
+	return due
+}
+
+// updateBoard 在距上次更新超过 discord.board.interval 时更新实时看板
+func (s *Scheduler) updateBoard(now time.Time) {
+	if s.board == nil {
+		return
+	}
+	interval := s.config.Discord.Board.Interval
+	if interval <= 0 {
+		interval = defaultBoardInterval
+	}
+	// 定时器按分钟触发，留出几秒余量，避免间隔为整分钟时每次都晚一分钟
+	if !s.boardUpdatedAt.IsZero() && now.Sub(s.boardUpdatedAt) < interval-5*time.Second {
+		return
+	}
+	s.boardUpdatedAt = now
+
+	ids := s.config.Discord.Board.Coins
+	if len(ids) == 0 {
+		ids = s.config.Coins
+	}
+	coins, err := s.coinClient.GetCoinPrices(ids)
+	if err != nil {
+		log.Printf("更新看板时获取价格失败: %v", err)
+		return
+	}
+	if len(coins) == 0 {
+		log.Println("看板没有可显示的币种数据")
+		return
+	}
+
+	style := firstNonEmpty(s.config.Discord.Board.Style, discordStyleCompact)
+	gen := s.reportGen.ForLocale(discordLocale(s.config)).WithDiscordStyle(style)
+	if _, err := s.board.Update(boardEmbeds(gen, coins)); err != nil {
+		log.Printf("更新看板失败: %v", err)
+	}
 }
 
-func (s *Scheduler) runDailyReport() {
+// runReport 获取价格并发送报表，sendEmail 为 false 时只发送到 targets 中的 Discord 目标
+func (s *Scheduler) runReport(sendEmail bool, targets []discordTargetSender) {
 	log.Println("开始生成每日加密货币价格报表...")
 
-	coins, err := s.coinClient.GetCoinPrices(s.config.Coins)
+	// 同时获取收件人单独关注的币种，发送时再按收件人筛选
+	allCoins, err := s.coinClient.GetCoinPrices(reportCoinIDs(s.config))
 	if err != nil {
 		log.Printf("获取加密货币价格失败: %v", err)
 		return
 	}
 
-	if len(coins) == 0 {
+	if len(allCoins) == 0 {
 		log.Println("未获取到任何加密货币数据")
 		return
 	}
 
-	log.Printf("成功获取到 %d 个加密货币的价格数据", len(coins))
+	log.Printf("成功获取到 %d 个加密货币的价格数据", len(allCoins))
+
+	// 将历史快照提供给模板，并记录本次数据
+	if s.history != nil {
+		history, err := s.history.Load()
+		if err != nil {
+			log.Printf("读取历史快照失败: %v", err)
+		}
+		s.reportGen.SetHistory(history)
+		if err := s.history.Record(allCoins, time.Now()); err != nil {
+			log.Printf("保存历史快照失败: %v", err)
+		}
+	}
+
+	// 检查是否有任何通知渠道配置
+	hasEmail := sendEmail && s.emailSender != nil && s.emailSender.IsConfigured()
+	hasDiscord := len(targets) > 0
 
 	// 记录发送结果
 	emailSuccess := false
 	discordSuccess := false
 
-	// 发送邮件报表（如果配置了邮件）
-	if s.emailSender != nil && s.emailSender.IsConfigured() {
-		htmlReport := s.reportGen.GenerateHTMLReport(coins)
-		subject := fmt.Sprintf("每日加密货币价格报表 - %s", time.Now().Format("2006年01月02日"))
-
-		err = s.emailSender.SendReport(subject, htmlReport)
-		if err != nil {
-			log.Printf("发送邮件失败: %v", err)
-		} else {
-			log.Println("每日报表已成功发送到邮箱")
-			emailSuccess = true
+	// 发送邮件报表（如果配置了邮件），语言区域或币种不同的收件人分别发送
+	if hasEmail {
+		emailSuccess = true
+		for _, group := range emailRecipientGroups(s.config) {
+			if !s.sendEmailReport(group, allCoins) {
+				emailSuccess = false
+			}
 		}
 	}
 
-	// 发送 Discord 报表（如果配置了 Discord）
-	if s.discordSender != nil && s.discordSender.IsConfigured() {
-		err = s.discordSender.SendReport(coins)
-		if err != nil {
-			log.Printf("发送 Discord 消息失败: %v", err)
-		} else {
-			log.Println("每日报表已成功发送到 Discord")
-			discordSuccess = true
+	// 发送 Discord 报表（如果配置了 Discord），每个目标使用各自的币种、语言区域和样式
+	if hasDiscord {
+		discordSuccess = true
+		for _, t := range targets {
+			if !s.sendDiscordReport(t, allCoins) {
+				discordSuccess = false
+			}
 		}
 	}
 
-	// 检查是否有任何通知渠道配置
-	hasEmail := s.emailSender != nil && s.emailSender.IsConfigured()
-	hasDiscord := s.discordSender != nil && s.discordSender.IsConfigured()
-
+	if !sendEmail && !hasDiscord {
+		return
+	}
 	if !hasEmail && !hasDiscord {
 		log.Println("警告: 没有配置任何通知渠道（邮件或 Discord）")
 		return
@@ -148,3 +290,165 @@ func (s *Scheduler) runDailyReport() {
 		}
 	}
 }
+
+// sendDiscordReport 向一个 Discord 目标发送报表，返回是否发送成功
+func (s *Scheduler) sendDiscordReport(t discordTargetSender, allCoins []CoinPrice) bool {
+	ids := t.target.Coins
+	if len(ids) == 0 {
+		ids = s.config.Coins
+	}
+	coins := selectCoins(allCoins, ids)
+	if len(coins) == 0 {
+		log.Printf("Discord 目标 %s 没有可发送的币种数据", t.target.label())
+		return false
+	}
+
+	gen := s.reportGen.ForLocale(t.target.Locale).WithDiscordStyle(t.target.Style)
+	if err := t.sender.SendReport(gen, coins); err != nil {
+		log.Printf("发送 Discord 消息到 %s 失败: %v", t.target.label(), err)
+		return false
+	}
+	log.Printf("每日报表已成功发送到 Discord（%s）", t.target.label())
+	return true
+}
+
+// sendEmailReport 向一组收件人发送邮件报表，返回是否所有收件人都已送达
+// 被拒绝的收件人放入发件箱，其余收件人正常发送
+func (s *Scheduler) sendEmailReport(group emailRecipientGroup, allCoins []CoinPrice) bool {
+	ids := group.Coins
+	if len(ids) == 0 {
+		ids = s.config.Coins
+	}
+	coins := selectCoins(allCoins, ids)
+	if len(coins) == 0 {
+		log.Printf("未获取到收件人关注的币种数据，跳过发送: %v", group.To)
+		return false
+	}
+
+	gen, attachments := s.emailAttachments(s.reportGen.ForLocale(group.Locale).ForRecipient(group.Name), coins)
+	htmlReport := gen.GenerateHTMLReport(coins)
+	textReport := gen.GenerateTextReport(coins)
+	subject := gen.GenerateSubject(coins)
+
+	var email *OutgoingEmail
+	var err error
+	switch {
+	case s.config.Email.Delivery == deliveryBcc:
+		email, err = s.emailSender.ComposeBcc(group.To, subject, htmlReport, textReport, attachments...)
+	case group.Name != "":
+		email, err = s.emailSender.Compose(namedAddresses(group.Name, group.To), subject, htmlReport, textReport, attachments...)
+	default:
+		email, err = s.emailSender.Compose(group.To, subject, htmlReport, textReport, attachments...)
+	}
+	if err != nil {
+		log.Printf("生成邮件失败 (%s): %v", group.Locale, err)
+		return false
+	}
+
+	err = s.emailSender.Deliver(email)
+	var recipientErr *RecipientError
+	switch {
+	case err == nil:
+		log.Printf("每日报表已成功发送到邮箱 (%s): %v", group.Locale, email.To)
+		return true
+	case errors.As(err, &recipientErr):
+		if accepted := recipientErr.Accepted(); len(accepted) > 0 {
+			log.Printf("每日报表已成功发送到邮箱 (%s): %v", group.Locale, accepted)
+		}
+		for _, result := range recipientErr.Rejected() {
+			log.Printf("收件人 %s 被拒绝 (%s): %v", result.Address, group.Locale, result.Err)
+		}
+	default:
+		log.Printf("发送邮件失败 (%s): %v", group.Locale, err)
+	}
+	s.enqueueEmail(email, err)
+	return false
+}
+
+// changeChartContentID 是 24h 涨跌幅图表的 Content-ID
+const changeChartContentID = "change-24h@coindaily"
+
+// emailAttachments 根据配置生成数据附件和内嵌图表，图表登记到返回的报表生成器中供 HTML 模板引用
+// 生成失败时记录日志并跳过该附件，不影响报表发送
+func (s *Scheduler) emailAttachments(gen *ReportGenerator, coins []CoinPrice) (*ReportGenerator, []Attachment) {
+	var attachments []Attachment
+	options := s.config.Email.Attachments
+
+	if options.Data != "" {
+		data, err := ExportCoins(options.Data, coins)
+		if err != nil {
+			log.Printf("导出币种数据失败: %v", err)
+		} else {
+			attachments = append(attachments, Attachment{
+				Filename:    "coindaily-" + time.Now().Format("2006-01-02") + "." + options.Data,
+				ContentType: exportContentTypes[options.Data],
+				Data:        data,
+			})
+		}
+	}
+
+	if options.Chart {
+		// 图表与报表表格的顺序一致
+		chart, err := RenderChangeChart(gen.BuildReportData(coins).Coins)
+		if err != nil {
+			log.Printf("生成图表失败: %v", err)
+		} else {
+			gen = gen.WithCharts([]ReportChart{{ContentID: changeChartContentID, Title: gen.Locale().T("chart.change_24h")}})
+			attachments = append(attachments, Attachment{
+				Filename:    "change-24h.png",
+				ContentType: "image/png",
+				ContentID:   changeChartContentID,
+				Data:        chart,
+			})
+		}
+	}
+
+	return gen, attachments
+}
+
+// namedAddresses 为收件人地址加上显示名称，地址中已有的名称会被替换
+func namedAddresses(name string, addresses []string) []string {
+	named := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			// 保留原地址，由 Compose 报告错误
+			named = append(named, address)
+			continue
+		}
+		parsed.Name = name
+		named = append(named, parsed.String())
+	}
+	return named
+}
+
+// enqueueEmail 将发送失败的邮件放入发件箱
+func (s *Scheduler) enqueueEmail(email *OutgoingEmail, sendErr error) {
+	if s.outbox == nil {
+		return
+	}
+	entry, err := s.outbox.Enqueue(email, sendErr)
+	if err != nil {
+		log.Printf("保存到发件箱失败: %v", err)
+		return
+	}
+	if entry.NextAttempt.IsZero() {
+		log.Printf("邮件被服务器拒绝，不再重试，已保存到发件箱: %s", entry.ID)
+	} else {
+		log.Printf("邮件已加入发件箱，将于 %s 重试: %s", entry.NextAttempt.Format("15:04:05"), entry.ID)
+	}
+}
+
+// retryOutbox 重新发送发件箱中到期的邮件
+func (s *Scheduler) retryOutbox(force bool) {
+	if s.outbox == nil || s.emailSender == nil {
+		return
+	}
+	result, err := s.outbox.Retry(s.emailSender.Deliver, force)
+	if err != nil {
+		log.Printf("处理发件箱失败: %v", err)
+	}
+	if result.Delivered+result.Retrying+result.Failed > 0 {
+		log.Printf("发件箱重试完成: 成功 %d，等待重试 %d，放弃 %d", result.Delivered, result.Retrying, result.Failed)
+	}
+}
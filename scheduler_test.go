@@ -1,7 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestSchedulerInitWithBothChannels 测试 Scheduler 同时初始化 EmailSender 和 DiscordSender
@@ -89,3 +97,97 @@ type EmailSenderInterface interface {
 	SendReport(subject, htmlContent string) error
 	IsConfigured() bool
 }
+
+// TestSchedulerEmailAttachments 测试配置附件后，邮件同时带有数据文件和 HTML 引用的内嵌图表
+func TestSchedulerEmailAttachments(t *testing.T) {
+	server := &fakeSMTPServer{}
+	server.start(t)
+	emailConfig := testEmailConfig(server)
+	emailConfig.TLS = tlsModeNone
+
+	config := &Config{Coins: []string{"bitcoin", "ethereum"}}
+	config.Email.Attachments.Data = exportCSV
+	config.Email.Attachments.Chart = true
+	scheduler := &Scheduler{
+		config:      config,
+		emailSender: NewEmailSender(emailConfig),
+		reportGen:   NewReportGenerator(),
+	}
+	if !scheduler.sendEmailReport(emailRecipientGroup{Locale: "en", To: []string{"a@test.com"}}, sampleCoins) {
+		t.Fatal("发送邮件报表失败")
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("期望收到 1 封邮件，实际为 %d", len(messages))
+	}
+	// 测试服务器按行读取 DATA，换行被转换为 LF
+	msg, err := mail.ReadMessage(strings.NewReader(strings.ReplaceAll(messages[0].Data, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("解析邮件失败: %v", err)
+	}
+	mixed := readMultipart(t, msg.Header.Get("Content-Type"), msg.Body, "multipart/mixed")
+	if len(mixed) != 2 {
+		t.Fatalf("multipart/mixed 应该有 2 个部分，实际 %d 个", len(mixed))
+	}
+	_, params, _ := mime.ParseMediaType(mixed[1].header.Get("Content-Disposition"))
+	if !strings.HasPrefix(params["filename"], "coindaily-") || !strings.HasSuffix(params["filename"], ".csv") {
+		t.Errorf("数据附件的文件名不正确: %q", params["filename"])
+	}
+
+	alternative := readMultipart(t, mixed[0].header.Get("Content-Type"), bytes.NewReader(mixed[0].body), "multipart/alternative")
+	related := readMultipart(t, alternative[1].header.Get("Content-Type"), bytes.NewReader(alternative[1].body), "multipart/related")
+	if len(related) != 2 || related[1].header.Get("Content-ID") != "<"+changeChartContentID+">" {
+		t.Fatalf("图表应该作为内嵌图片放在 multipart/related 中")
+	}
+	if !strings.Contains(string(related[0].body), "cid:"+changeChartContentID) {
+		t.Error("HTML 正文应该通过 cid: 引用图表")
+	}
+}
+
+// TestSchedulerDiscordTargets 测试每个 Discord 目标使用各自的币种和样式，并按各自的时间发送
+func TestSchedulerDiscordTargets(t *testing.T) {
+	received := map[string]discordMessage{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message discordMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		received[r.URL.Path] = message
+		w.Write([]byte(`{"id": "1"}`))
+	}))
+	defer server.Close()
+
+	config := &Config{Coins: []string{"bitcoin", "ethereum"}}
+	config.Schedule.Hour = 8
+	config.Discord.WebhookURL = server.URL + "/main"
+	config.Discord.Targets = []DiscordTarget{{
+		Name:       "eth",
+		WebhookURL: server.URL + "/eth",
+		Coins:      []string{"ethereum"},
+		Style:      discordStyleCompact,
+		Schedule:   &ScheduleTime{Hour: 20},
+	}}
+	scheduler := NewScheduler(config)
+	if len(scheduler.discordTargets) != 2 || scheduler.discordSender != scheduler.discordTargets[0].sender {
+		t.Fatalf("应该初始化 2 个 Discord 目标，第一个为顶层 webhook")
+	}
+
+	due := scheduler.dueDiscordTargets(time.Date(2024, 1, 1, 20, 0, 0, 0, time.Local))
+	if len(due) != 1 || due[0].target.Name != "eth" {
+		t.Fatalf("20:00 应该只发送 eth 目标，实际为 %d 个", len(due))
+	}
+
+	for _, target := range scheduler.discordTargets {
+		if !scheduler.sendDiscordReport(target, sampleCoins) {
+			t.Fatalf("发送到 %s 失败", target.target.label())
+		}
+	}
+	if embeds := received["/main"].Embeds; len(embeds) != 1 || len(embeds[0].Fields) != 2 {
+		t.Errorf("顶层 webhook 应该收到包含 2 个币种字段的报表: %+v", embeds)
+	}
+	eth := received["/eth"].Embeds
+	if len(eth) != 1 || len(eth[0].Fields) != 0 || !strings.Contains(eth[0].Description, "**ETH**") || strings.Contains(eth[0].Description, "**BTC**") {
+		t.Errorf("eth 目标应该收到只包含 ETH 的 compact 报表: %+v", eth)
+	}
+}
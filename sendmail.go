@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// sendmail 的默认路径和参数：-t 从邮件头读取收件人，-i 不把单独的 "." 行当作结束
+var (
+	defaultSendmailPath = "/usr/sbin/sendmail"
+	defaultSendmailArgs = []string{"-t", "-i"}
+)
+
+// sendmailTimeout 是等待 sendmail 退出的时间
+const sendmailTimeout = 2 * time.Minute
+
+// sendmailPermanentCodes 是表示邮件本身有问题、重试也不会成功的退出码（sysexits.h）
+var sendmailPermanentCodes = map[int]bool{
+	65: true, // EX_DATAERR
+	67: true, // EX_NOUSER
+	68: true, // EX_NOHOST
+}
+
+// SendmailTransport 将邮件通过管道交给本机的 sendmail（Postfix、Exim 等 MTA 都提供兼容命令）
+type SendmailTransport struct {
+	path string
+	args []string
+}
+
+// NewSendmailTransport 创建 sendmail 投递方式，path 和 args 为空时使用 /usr/sbin/sendmail -t -i
+func NewSendmailTransport(path string, args []string) *SendmailTransport {
+	if path == "" {
+		path = defaultSendmailPath
+	}
+	if len(args) == 0 {
+		args = defaultSendmailArgs
+	}
+	return &SendmailTransport{path: path, args: args}
+}
+
+// SendmailError 表示 sendmail 以非零状态退出
+type SendmailError struct {
+	ExitCode int
+	Stderr   string
+}
+
+func (e *SendmailError) Error() string {
+	return fmt.Sprintf("sendmail exited with status %d: %s", e.ExitCode, e.Stderr)
+}
+
+// Deliver 投递邮件
+// 使用 -t 时 sendmail 从邮件头读取收件人，邮件头中没有的信封收件人（如密送）通过 Bcc 邮件头传递，
+// sendmail 会在投递前删除 Bcc；不使用 -t 时信封收件人作为命令行参数传递
+func (t *SendmailTransport) Deliver(email *OutgoingEmail) error {
+	args := append([]string{}, t.args...)
+	message := email.Message
+	if containsString(args, "-t") {
+		if missing := missingHeaderRecipients(email); len(missing) > 0 {
+			message = append([]byte("Bcc: "+strings.Join(missing, ", ")+"\r\n"), message...)
+		}
+	} else {
+		args = append(append(args, "--"), email.To...)
+	}
+
+	cmd := exec.Command(t.path, args...)
+	cmd.Stdin = bytes.NewReader(message)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run sendmail: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &SendmailError{ExitCode: exitErr.ExitCode(), Stderr: strings.TrimSpace(stderr.String())}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to run sendmail: %w", err)
+		}
+		return nil
+	case <-time.After(sendmailTimeout):
+		cmd.Process.Kill()
+		<-done
+		return fmt.Errorf("sendmail did not exit within %s", sendmailTimeout)
+	}
+}
+
+// missingHeaderRecipients 返回没有出现在 To、Cc 邮件头中的信封收件人
+func missingHeaderRecipients(email *OutgoingEmail) []string {
+	present := map[string]bool{}
+	if msg, err := mail.ReadMessage(bytes.NewReader(email.Message)); err == nil {
+		for _, name := range []string{"To", "Cc"} {
+			addresses, _ := msg.Header.AddressList(name)
+			for _, address := range addresses {
+				present[strings.ToLower(address.Address)] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, to := range email.To {
+		if !present[strings.ToLower(to)] {
+			missing = append(missing, to)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeSendmail 在临时目录中创建模拟的 sendmail 脚本，记录参数和标准输入后以 exitCode 退出
+func fakeSendmail(t *testing.T, exitCode int) (path, argsFile, stdinFile string) {
+	t.Helper()
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("需要 /bin/sh")
+	}
+	dir := t.TempDir()
+	path = filepath.Join(dir, "sendmail")
+	argsFile = filepath.Join(dir, "args")
+	stdinFile = filepath.Join(dir, "stdin")
+	script := "#!/bin/sh\n" +
+		"printf '%s\\n' \"$@\" > '" + argsFile + "'\n" +
+		"cat > '" + stdinFile + "'\n" +
+		"echo 'sendmail: fake failure' >&2\n" +
+		"exit " + strconv.Itoa(exitCode) + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("写入脚本失败: %v", err)
+	}
+	return path, argsFile, stdinFile
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %v", path, err)
+	}
+	return string(data)
+}
+
+// TestSendmailTransport 测试 -t 模式下邮件原样写入标准输入，密送收件人通过 Bcc 邮件头传递
+func TestSendmailTransport(t *testing.T) {
+	path, argsFile, stdinFile := fakeSendmail(t, 0)
+	transport := NewSendmailTransport(path, nil)
+
+	message := "To: a@test.com\r\nSubject: Report\r\n\r\nbody\r\n"
+	email := &OutgoingEmail{From: "bot@example.com", To: []string{"a@test.com", "hidden@test.com"}, Message: []byte(message)}
+	if err := transport.Deliver(email); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+
+	if args := readTestFile(t, argsFile); args != "-t\n-i\n" {
+		t.Errorf("默认参数应该是 -t -i，实际为 %q", args)
+	}
+	if stdin := readTestFile(t, stdinFile); stdin != "Bcc: hidden@test.com\r\n"+message {
+		t.Errorf("标准输入不正确: %q", stdin)
+	}
+}
+
+// TestSendmailTransportEnvelope 测试不使用 -t 时信封收件人作为参数传递，邮件保持不变
+func TestSendmailTransportEnvelope(t *testing.T) {
+	path, argsFile, stdinFile := fakeSendmail(t, 0)
+	transport := NewSendmailTransport(path, []string{"-i", "-f", "bot@example.com"})
+
+	message := "To: undisclosed-recipients:;\r\nSubject: Report\r\n\r\nbody\r\n"
+	email := &OutgoingEmail{From: "bot@example.com", To: []string{"a@test.com", "b@test.com"}, Message: []byte(message)}
+	if err := transport.Deliver(email); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+
+	if args := readTestFile(t, argsFile); args != "-i\n-f\nbot@example.com\n--\na@test.com\nb@test.com\n" {
+		t.Errorf("参数不正确: %q", args)
+	}
+	if stdin := readTestFile(t, stdinFile); stdin != message {
+		t.Errorf("邮件内容不应该被修改: %q", stdin)
+	}
+}
+
+// TestSendmailTransportExitCode 测试退出码和错误输出，以及永久、临时错误的区分
+func TestSendmailTransportExitCode(t *testing.T) {
+	tests := []struct {
+		code      int
+		permanent bool
+	}{
+		{67, true},  // EX_NOUSER
+		{75, false}, // EX_TEMPFAIL
+	}
+	for _, tt := range tests {
+		path, _, _ := fakeSendmail(t, tt.code)
+		err := NewSendmailTransport(path, nil).Deliver(&OutgoingEmail{To: []string{"a@test.com"}, Message: []byte("\r\n")})
+		sendmailErr, ok := err.(*SendmailError)
+		if !ok {
+			t.Fatalf("应该返回 *SendmailError，实际为 %T: %v", err, err)
+		}
+		if sendmailErr.ExitCode != tt.code || !strings.Contains(err.Error(), "fake failure") {
+			t.Errorf("错误内容不正确: %v", err)
+		}
+		if got := isPermanentSMTPError(err); got != tt.permanent {
+			t.Errorf("退出码 %d: isPermanentSMTPError = %v, 期望 %v", tt.code, got, tt.permanent)
+		}
+	}
+
+	if err := NewSendmailTransport(filepath.Join(t.TempDir(), "missing"), nil).Deliver(&OutgoingEmail{}); err == nil {
+		t.Error("sendmail 不存在时应该返回错误")
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SESConfig 是 AWS SES v2 API 的配置
+type SESConfig struct {
+	Region           string
+	AccessKeyID      string
+	SecretAccessKey  string
+	SessionToken     string // 使用临时凭据时需要（可选）
+	ConfigurationSet string // 用于事件发布的配置集（可选）
+	Endpoint         string // 为空时使用 https://email.<region>.amazonaws.com，测试中指向本地服务器
+}
+
+// SESTransport 通过 SES v2 SendEmail API 投递原始邮件，请求使用 SigV4 签名
+// 邮件以原始 MIME 格式发送，DKIM 签名、附件和内嵌图片都会原样保留
+type SESTransport struct {
+	config   SESConfig
+	endpoint string
+	client   *http.Client
+	signer   *sigV4Signer
+}
+
+// NewSESTransport 创建 SES 投递方式
+func NewSESTransport(config SESConfig, client *http.Client) *SESTransport {
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://email." + config.Region + ".amazonaws.com"
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &SESTransport{
+		config:   config,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   client,
+		signer: &sigV4Signer{
+			accessKeyID:     config.AccessKeyID,
+			secretAccessKey: config.SecretAccessKey,
+			sessionToken:    config.SessionToken,
+			region:          region,
+			service:         "ses",
+			now:             time.Now,
+		},
+	}
+}
+
+// sesSendEmailRequest 是 SES v2 SendEmail 的请求体（只使用原始邮件）
+type sesSendEmailRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Raw struct {
+			Data []byte `json:"Data"` // encoding/json 按 base64 编码
+		} `json:"Raw"`
+	} `json:"Content"`
+	ConfigurationSetName string `json:"ConfigurationSetName,omitempty"`
+}
+
+// Deliver 投递邮件，Destination 使用信封收件人，因此密送邮件也能正确投递
+func (t *SESTransport) Deliver(email *OutgoingEmail) error {
+	var request sesSendEmailRequest
+	request.FromEmailAddress = email.From
+	request.Destination.ToAddresses = email.To
+	request.Content.Raw.Data = email.Message
+	request.ConfigurationSetName = t.config.ConfigurationSet
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.signer.sign(req, body)
+	_, err = doTransportRequest(t.client, req, "SES")
+	return err
+}
+
+// sigV4Signer 使用 AWS Signature Version 4 对请求签名
+// 参考 https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+type sigV4Signer struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+	service         string
+	now             func() time.Time
+}
+
+// sign 为请求添加 X-Amz-Date、X-Amz-Security-Token 和 Authorization 请求头
+// 签名包含 Host 和请求中已有的全部请求头，body 为请求体
+func (s *sigV4Signer) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	canonicalHeaders, signedHeaders := sigV4CanonicalHeaders(req)
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigV4CanonicalURI(req.URL),
+		sigV4CanonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := now.Format("20060102") + "/" + s.region + "/" + s.service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKeyID, scope, signedHeaders, signature))
+}
+
+// sigV4CanonicalHeaders 返回规范化的请求头和签名的请求头列表，名称小写并按字母排序
+func sigV4CanonicalHeaders(req *http.Request) (canonical, signed string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values := map[string]string{"host": host}
+	for name, value := range req.Header {
+		// 同名请求头用逗号连接，值中的连续空白合并为一个空格
+		trimmed := make([]string, len(value))
+		for i, v := range value {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		values[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + values[name] + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// sigV4CanonicalURI 返回规范化的路径，除 S3 外每个路径段需要编码两次
+func sigV4CanonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = sigV4Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// sigV4CanonicalQuery 返回按参数名和值排序的查询字符串
+func sigV4CanonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, sigV4Escape(name)+"="+sigV4Escape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape 按 RFC 3986 编码，只保留非保留字符 A-Z a-z 0-9 - _ . ~
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSigV4Signer 返回使用 AWS 文档示例凭据、时间固定的签名器
+func testSigV4Signer(region, service string) *sigV4Signer {
+	return &sigV4Signer{
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		region:          region,
+		service:         service,
+		now:             func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+}
+
+// TestSigV4Sign 测试 AWS SigV4 官方测试用例
+func TestSigV4Sign(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		contentType string
+		service     string
+		want        string
+	}{
+		{
+			name:    "get-vanilla",
+			method:  http.MethodGet,
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:    "post-vanilla",
+			method:  http.MethodPost,
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:        "iam-list-users",
+			method:      http.MethodGet,
+			url:         "https://iam.amazonaws.com/?Version=2010-05-08&Action=ListUsers",
+			contentType: "application/x-www-form-urlencoded; charset=utf-8",
+			service:     "iam",
+			want:        "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatalf("%s: 创建请求失败: %v", tt.name, err)
+		}
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		testSigV4Signer("us-east-1", tt.service).sign(req, nil)
+		if got := req.Header.Get("Authorization"); got != tt.want {
+			t.Errorf("%s: Authorization = %s\n期望 %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestSESTransport 测试 SES 请求的地址、签名和请求体
+func TestSESTransport(t *testing.T) {
+	var got struct {
+		path, authorization, token string
+		request                    sesSendEmailRequest
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path = r.URL.Path
+		got.authorization = r.Header.Get("Authorization")
+		got.token = r.Header.Get("X-Amz-Security-Token")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got.request); err != nil {
+			t.Errorf("请求体不是有效的 JSON: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"MessageId":"0100018b-example"}`)
+	}))
+	defer server.Close()
+
+	transport := NewSESTransport(SESConfig{
+		Region:           "eu-west-1",
+		AccessKeyID:      "AKIDEXAMPLE",
+		SecretAccessKey:  "secret",
+		SessionToken:     "session",
+		ConfigurationSet: "coindaily",
+		Endpoint:         server.URL,
+	}, server.Client())
+	email := &OutgoingEmail{From: "bot@example.com", To: []string{"a@test.com", "b@test.com"}, Subject: "Report", Message: []byte("Subject: Report\r\n\r\nbody\r\n")}
+	if err := transport.Deliver(email); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+
+	if got.path != "/v2/email/outbound-emails" {
+		t.Errorf("请求路径 = %s", got.path)
+	}
+	if !strings.HasPrefix(got.authorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(got.authorization, "/eu-west-1/ses/aws4_request") {
+		t.Errorf("Authorization 不正确: %s", got.authorization)
+	}
+	if !strings.Contains(got.authorization, "x-amz-security-token") || got.token != "session" {
+		t.Error("使用临时凭据时应该发送并签名 X-Amz-Security-Token")
+	}
+	request := got.request
+	if request.FromEmailAddress != "bot@example.com" || strings.Join(request.Destination.ToAddresses, ",") != "a@test.com,b@test.com" {
+		t.Errorf("发件人或收件人不正确: %+v", request)
+	}
+	if string(request.Content.Raw.Data) != string(email.Message) {
+		t.Errorf("原始邮件内容不正确: %q", request.Content.Raw.Data)
+	}
+	if request.ConfigurationSetName != "coindaily" {
+		t.Errorf("配置集 = %q", request.ConfigurationSetName)
+	}
+}
+
+// TestSESTransportError 测试 SES 返回错误时的状态码和错误信息
+func TestSESTransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"message":"Email address is not verified."}`)
+	}))
+	defer server.Close()
+
+	transport := NewSESTransport(SESConfig{AccessKeyID: "id", SecretAccessKey: "secret", Endpoint: server.URL}, server.Client())
+	err := transport.Deliver(&OutgoingEmail{From: "bot@example.com", To: []string{"a@test.com"}, Message: []byte("x")})
+	if err == nil || !strings.Contains(err.Error(), "not verified") {
+		t.Fatalf("应该返回包含服务商说明的错误，实际为 %v", err)
+	}
+	if !isPermanentSMTPError(err) {
+		t.Error("400 错误应该是永久性错误")
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer 是用于测试的最小 SMTP 服务器
+type fakeSMTPServer struct {
+	Addr string
+
+	implicitTLS bool              // 连接建立后立即进行 TLS 握手
+	starttls    bool              // 声明 STARTTLS 扩展
+	auth        bool              // 声明 AUTH 扩展
+	validToken  string            // 非空时只接受该 XOAUTH2 访问令牌
+	rejectRcpt  map[string]string // 收件人对应的拒绝响应，如 "550 5.1.1 no such user"
+	tlsConfig   *tls.Config
+
+	mu       sync.Mutex
+	messages []fakeSMTPMessage
+}
+
+// fakeSMTPMessage 是服务器收到的一封邮件
+type fakeSMTPMessage struct {
+	From string
+	To   []string
+	Data string
+	TLS  bool   // 是否通过 TLS 连接收到
+	Auth bool   // 发送前是否完成认证
+	SASL string // 认证时的机制和解码后的初始响应，如 "XOAUTH2 user=...\x01auth=Bearer ...\x01\x01"
+}
+
+// testCertificate 生成 127.0.0.1 的自签名证书，返回服务器证书和 PEM 格式的 CA 文件路径
+func testCertificate(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "coindaily test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("生成证书失败: %v", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeTestFile(t, caFile, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
+
+// start 在随机端口上启动服务器，测试结束时自动关闭
+func (s *fakeSMTPServer) start(t *testing.T) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("启动 SMTP 服务器失败: %v", err)
+	}
+	if s.implicitTLS {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+	s.Addr = listener.Addr().String()
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+}
+
+// port 返回服务器监听的端口
+func (s *fakeSMTPServer) port() int {
+	_, port, _ := net.SplitHostPort(s.Addr)
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// setRejectRcpt 设置收件人的拒绝响应，reply 为空时取消拒绝
+func (s *fakeSMTPServer) setRejectRcpt(rcpt, reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rejectRcpt == nil {
+		s.rejectRcpt = make(map[string]string)
+	}
+	if reply == "" {
+		delete(s.rejectRcpt, rcpt)
+	} else {
+		s.rejectRcpt[rcpt] = reply
+	}
+}
+
+// received 返回服务器收到的所有邮件
+func (s *fakeSMTPServer) received() []fakeSMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeSMTPMessage(nil), s.messages...)
+}
+
+// serve 处理一个 SMTP 会话
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	_, secure := conn.(*tls.Conn)
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 fake ESMTP ready")
+
+	var msg fakeSMTPMessage
+	authenticated, sasl := false, ""
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			lines := []string{"fake"}
+			if s.starttls && !secure {
+				lines = append(lines, "STARTTLS")
+			}
+			if s.auth {
+				lines = append(lines, "AUTH PLAIN XOAUTH2")
+			}
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				text.PrintfLine("250%s%s", sep, l)
+			}
+		case "STARTTLS":
+			text.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, secure = tlsConn, true
+			text = textproto.NewConn(conn)
+		case "AUTH":
+			fields := strings.Fields(line)
+			initial := ""
+			if len(fields) > 2 {
+				decoded, _ := base64.StdEncoding.DecodeString(fields[2])
+				initial = string(decoded)
+			}
+			if strings.EqualFold(fields[1], "XOAUTH2") && s.validToken != "" &&
+				!strings.Contains(initial, "auth=Bearer "+s.validToken+"\x01") {
+				// 与 Gmail 相同：先以 JSON 质询说明原因，客户端回复空行后返回 535
+				text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(`{"status":"401","schemes":"bearer"}`)))
+				text.ReadLine()
+				text.PrintfLine("535 5.7.8 invalid credentials")
+				continue
+			}
+			authenticated = true
+			sasl = strings.ToUpper(fields[1]) + " " + initial
+			text.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg = fakeSMTPMessage{From: smtpPath(line), TLS: secure, Auth: authenticated, SASL: sasl}
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			reply, rejected := s.rejectRcpt[smtpPath(line)]
+			s.mu.Unlock()
+			if rejected {
+				text.PrintfLine("%s", reply)
+				continue
+			}
+			msg.To = append(msg.To, smtpPath(line))
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			text.PrintfLine("250 OK queued")
+		case "RSET", "NOOP":
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// smtpPath 提取 MAIL FROM / RCPT TO 命令中尖括号内的地址
+func smtpPath(line string) string {
+	start, end := strings.Index(line, "<"), strings.LastIndex(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 市场事件类型
+const (
+	eventHigh24h     = "high_24h"
+	eventLow24h      = "low_24h"
+	eventAllTimeHigh = "ath"
+	eventVolumeSpike = "volume_spike"
+)
+
+// 判断市场事件时使用的阈值
+const (
+	extremeTolerance = 0.001 // 距离 24h 最高/最低价 0.1% 以内视为触及
+	volumeSpikeRatio = 2.0   // 交易量达到上一次快照的 2 倍视为放量
+)
+
+// MarketSummary 是报表顶部的市场概要
+type MarketSummary struct {
+	TopGainer          *CoinPrice // 24h 涨幅最大的币种，没有上涨的币种时为 nil
+	TopLoser           *CoinPrice // 24h 跌幅最大的币种，没有下跌的币种时为 nil
+	Up                 int
+	Down               int
+	Unchanged          int
+	WeightedChangePerc float64 // 按市值加权的 24h 变化率，缺少市值数据时为简单平均
+	Events             []MarketEvent
+}
+
+// MarketEvent 表示值得关注的单个币种事件
+type MarketEvent struct {
+	Kind  string // high_24h、low_24h、ath、volume_spike
+	Coin  CoinPrice
+	Ratio float64 // 放量事件中当前交易量与上一次快照的比值
+}
+
+// computeMarketSummary 根据当前数据和历史快照计算市场概要
+func computeMarketSummary(coins []CoinPrice, history []HistorySnapshot, today string) MarketSummary {
+	var summary MarketSummary
+	if len(coins) == 0 {
+		return summary
+	}
+
+	gainer, loser := 0, 0
+	totalCap, weighted, simple := 0.0, 0.0, 0.0
+	for i, coin := range coins {
+		switch {
+		case coin.PriceChangePerc24h > 0:
+			summary.Up++
+		case coin.PriceChangePerc24h < 0:
+			summary.Down++
+		default:
+			summary.Unchanged++
+		}
+
+		if coin.PriceChangePerc24h > coins[gainer].PriceChangePerc24h {
+			gainer = i
+		}
+		if coin.PriceChangePerc24h < coins[loser].PriceChangePerc24h {
+			loser = i
+		}
+
+		totalCap += coin.MarketCap
+		weighted += coin.PriceChangePerc24h * coin.MarketCap
+		simple += coin.PriceChangePerc24h
+	}
+
+	// 全部下跌时没有领涨币种，全部上涨时没有领跌币种
+	if coins[gainer].PriceChangePerc24h > 0 {
+		summary.TopGainer = &coins[gainer]
+	}
+	if coins[loser].PriceChangePerc24h < 0 {
+		summary.TopLoser = &coins[loser]
+	}
+
+	if totalCap > 0 {
+		summary.WeightedChangePerc = weighted / totalCap
+	} else {
+		summary.WeightedChangePerc = simple / float64(len(coins))
+	}
+
+	summary.Events = detectMarketEvents(coins, previousSnapshot(history, today))
+	return summary
+}
+
+// detectMarketEvents 找出触及 24h 高低点、创历史新高或明显放量的币种
+func detectMarketEvents(coins []CoinPrice, previous *HistorySnapshot) []MarketEvent {
+	var events []MarketEvent
+	for _, coin := range coins {
+		switch {
+		case coin.ATH > 0 && coin.CurrentPrice >= coin.ATH*(1-extremeTolerance):
+			events = append(events, MarketEvent{Kind: eventAllTimeHigh, Coin: coin})
+		case coin.High24h > 0 && coin.CurrentPrice >= coin.High24h*(1-extremeTolerance):
+			events = append(events, MarketEvent{Kind: eventHigh24h, Coin: coin})
+		case coin.Low24h > 0 && coin.CurrentPrice <= coin.Low24h*(1+extremeTolerance):
+			events = append(events, MarketEvent{Kind: eventLow24h, Coin: coin})
+		}
+
+		if previous == nil {
+			continue
+		}
+		if prev, ok := previous.Coin(coin.ID); ok && prev.Volume24h > 0 {
+			ratio := coin.Volume24h / prev.Volume24h
+			if ratio >= volumeSpikeRatio && !math.IsInf(ratio, 0) {
+				events = append(events, MarketEvent{Kind: eventVolumeSpike, Coin: coin, Ratio: ratio})
+			}
+		}
+	}
+	return events
+}
+
+// previousSnapshot 返回今天之前最近的一次快照，没有时返回 nil
+func previousSnapshot(history []HistorySnapshot, today string) *HistorySnapshot {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Date != today {
+			return &history[i]
+		}
+	}
+	return nil
+}
+
+// describeEvent 返回市场事件的本地化描述
+func describeEvent(locale *Locale, event MarketEvent) string {
+	name := fmt.Sprintf("%s (%s)", event.Coin.Name, strings.ToUpper(event.Coin.Symbol))
+	price := locale.Numbers.FormatCurrency(event.Coin.CurrentPrice, reportCurrency)
+
+	switch event.Kind {
+	case eventVolumeSpike:
+		return locale.T("event.volume_spike", name, event.Ratio)
+	case eventHigh24h, eventLow24h, eventAllTimeHigh:
+		return locale.T("event."+event.Kind, name, price)
+	default:
+		return name
+	}
+}
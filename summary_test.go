@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestComputeMarketSummary 测试领涨领跌、涨跌家数和市值加权涨跌幅
+func TestComputeMarketSummary(t *testing.T) {
+	coins := []CoinPrice{
+		{ID: "bitcoin", MarketCap: 900, PriceChangePerc24h: -1},
+		{ID: "ethereum", MarketCap: 90, PriceChangePerc24h: 3},
+		{ID: "pepe", MarketCap: 10, PriceChangePerc24h: 40},
+		{ID: "tether", MarketCap: 100, PriceChangePerc24h: 0},
+	}
+
+	summary := computeMarketSummary(coins, nil, "2026-02-07")
+
+	if summary.TopGainer == nil || summary.TopGainer.ID != "pepe" {
+		t.Errorf("领涨币种应该是 pepe，实际为 %+v", summary.TopGainer)
+	}
+	if summary.TopLoser == nil || summary.TopLoser.ID != "bitcoin" {
+		t.Errorf("领跌币种应该是 bitcoin，实际为 %+v", summary.TopLoser)
+	}
+	if summary.Up != 2 || summary.Down != 1 || summary.Unchanged != 1 {
+		t.Errorf("涨跌家数不正确: up=%d down=%d unchanged=%d", summary.Up, summary.Down, summary.Unchanged)
+	}
+
+	// 简单平均为 +10.5%，按市值加权为 (-900 + 270 + 400) / 1100
+	want := -230.0 / 1100.0
+	if diff := summary.WeightedChangePerc - want; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("加权涨跌幅为 %v，期望 %v", summary.WeightedChangePerc, want)
+	}
+}
+
+// TestComputeMarketSummaryWithoutMarketCap 测试缺少市值数据时回退为简单平均
+func TestComputeMarketSummaryWithoutMarketCap(t *testing.T) {
+	coins := []CoinPrice{{ID: "a", PriceChangePerc24h: 2}, {ID: "b", PriceChangePerc24h: -4}}
+
+	summary := computeMarketSummary(coins, nil, "2026-02-07")
+	if summary.WeightedChangePerc != -1 {
+		t.Errorf("期望简单平均 -1，实际为 %v", summary.WeightedChangePerc)
+	}
+
+	empty := computeMarketSummary(nil, nil, "2026-02-07")
+	if empty.TopGainer != nil || empty.TopLoser != nil {
+		t.Error("没有数据时不应该有领涨领跌币种")
+	}
+
+	single := computeMarketSummary(coins[:1], nil, "2026-02-07")
+	if single.TopGainer == nil || single.TopLoser != nil {
+		t.Error("只有一个上涨的币种时只有领涨币种")
+	}
+}
+
+// TestComputeMarketSummaryOneDirection 测试全部下跌时没有领涨币种，全部上涨时没有领跌币种
+func TestComputeMarketSummaryOneDirection(t *testing.T) {
+	down := []CoinPrice{{ID: "a", PriceChangePerc24h: -1}, {ID: "b", PriceChangePerc24h: -5}, {ID: "c", PriceChangePerc24h: 0}}
+	summary := computeMarketSummary(down, nil, "2026-02-07")
+	if summary.TopGainer != nil {
+		t.Errorf("全部下跌或持平时不应该有领涨币种，实际为 %+v", summary.TopGainer)
+	}
+	if summary.TopLoser == nil || summary.TopLoser.ID != "b" {
+		t.Errorf("领跌币种应该是 b，实际为 %+v", summary.TopLoser)
+	}
+
+	up := []CoinPrice{{ID: "a", PriceChangePerc24h: 1}, {ID: "b", PriceChangePerc24h: 5}, {ID: "c", PriceChangePerc24h: 0}}
+	summary = computeMarketSummary(up, nil, "2026-02-07")
+	if summary.TopLoser != nil {
+		t.Errorf("全部上涨或持平时不应该有领跌币种，实际为 %+v", summary.TopLoser)
+	}
+	if summary.TopGainer == nil || summary.TopGainer.ID != "b" {
+		t.Errorf("领涨币种应该是 b，实际为 %+v", summary.TopGainer)
+	}
+
+	// 没有领涨币种时报表仍然显示概要的其余部分
+	gen := NewReportGenerator()
+	text := gen.GenerateTextReport(down)
+	if !strings.Contains(text, "今日概要") || strings.Contains(text, "领涨") || !strings.Contains(text, "领跌") {
+		t.Errorf("全部下跌时应该显示概要但没有领涨币种:\n%s", text)
+	}
+	embed := gen.GenerateDiscordEmbed(down)
+	if strings.Contains(embed.Description, "📈") || !strings.Contains(embed.Description, "📉") {
+		t.Errorf("全部下跌时 Discord 概要应该只有领跌币种:\n%s", embed.Description)
+	}
+}
+
+// TestDetectMarketEvents 测试 24h 高低点、历史新高和放量事件
+func TestDetectMarketEvents(t *testing.T) {
+	coins := []CoinPrice{
+		{ID: "bitcoin", CurrentPrice: 100, High24h: 100, Low24h: 90, ATH: 120, Volume24h: 50},
+		{ID: "ethereum", CurrentPrice: 90.05, High24h: 100, Low24h: 90, ATH: 200, Volume24h: 10},
+		{ID: "solana", CurrentPrice: 300, High24h: 300, Low24h: 250, ATH: 300, Volume24h: 10},
+		{ID: "cardano", CurrentPrice: 95, High24h: 100, Low24h: 90, Volume24h: 10},
+	}
+	history := []HistorySnapshot{
+		{Date: "2026-02-06", Coins: []CoinPrice{{ID: "bitcoin", Volume24h: 20}, {ID: "cardano", Volume24h: 9}}},
+		{Date: "2026-02-07", Coins: []CoinPrice{{ID: "bitcoin", Volume24h: 50}}},
+	}
+
+	events := computeMarketSummary(coins, history, "2026-02-07").Events
+
+	got := make([]string, len(events))
+	for i, event := range events {
+		got[i] = event.Coin.ID + ":" + event.Kind
+	}
+	want := "bitcoin:high_24h,bitcoin:volume_spike,ethereum:low_24h,solana:ath"
+	if strings.Join(got, ",") != want {
+		t.Errorf("事件为 %s，期望 %s", strings.Join(got, ","), want)
+	}
+
+	// 放量倍数与今天之前最近的一次快照比较
+	if events[1].Ratio != 2.5 {
+		t.Errorf("放量倍数应该为 2.5，实际为 %v", events[1].Ratio)
+	}
+}
+
+// TestDescribeEvent 测试事件描述的本地化
+func TestDescribeEvent(t *testing.T) {
+	event := MarketEvent{
+		Kind:  eventVolumeSpike,
+		Coin:  CoinPrice{Name: "Bitcoin", Symbol: "btc", CurrentPrice: 45000},
+		Ratio: 2.5,
+	}
+
+	if got := describeEvent(locales["en"], event); got != "Bitcoin (BTC) volume is 2.5x the previous report" {
+		t.Errorf("英文事件描述不正确: %s", got)
+	}
+
+	event.Kind = eventAllTimeHigh
+	if got := describeEvent(locales["zh-CN"], event); got != "Bitcoin (BTC) 创历史新高 $45,000.00" {
+		t.Errorf("中文事件描述不正确: %s", got)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	"os"
+	"strings"
+	texttemplate "text/template"
+	"unicode"
+)
+
+// 内置的默认模板，用户未在配置中指定模板文件时使用
+//
+//go:embed templates/*.tmpl
+var defaultTemplateFS embed.FS
+
+const (
+	defaultHTMLTemplate    = "templates/report.html.tmpl"
+	defaultTextTemplate    = "templates/report.txt.tmpl"
+	defaultSubjectTemplate = "templates/subject.tmpl"
+	defaultDiscordTemplate = "templates/discord.tmpl"
+)
+
+// Discord 模板中需要定义的模板块
+var discordTemplateBlocks = []string{
+	"title", "description", "section_name", "section_value", "field_name", "field_value", "footer",
+}
+
+// reportTemplates 保存报表渲染所需的全部模板
+type reportTemplates struct {
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+	subject *texttemplate.Template
+	discord *texttemplate.Template
+}
+
+// templateFuncs 返回模板中可用的辅助函数，文本、日期和数字格式绑定到指定的语言区域
+func templateFuncs(locale *Locale) map[string]interface{} {
+	numbers := locale.Numbers
+	return map[string]interface{}{
+		"T":           locale.T,
+		"date":        locale.FormatDate,
+		"upper":       strings.ToUpper,
+		"price":       numbers.FormatPrice,
+		"large":       numbers.FormatCompact,
+		"percent":     numbers.FormatPercent,
+		"number":      numbers.FormatDecimal,
+		"sign":        changeSign,
+		"changeClass": changeClass,
+		"has":         containsString,
+		"cell":        newTableCell,
+		"cid":         contentIDURL,
+		"table": func(data *ReportData) string {
+			return renderTextTable(locale, data)
+		},
+		"event": func(event MarketEvent) string {
+			return describeEvent(locale, event)
+		},
+		"columnTitle": func(column string) string {
+			if column == columnPrice {
+				return locale.T("column.price", reportCurrency)
+			}
+			return locale.T("column." + column)
+		},
+		"signedPercent": numbers.FormatSignedPercent,
+		"currency": func(v float64) string {
+			return numbers.FormatCurrency(v, reportCurrency)
+		},
+		"signedCurrency": func(v float64) string {
+			return numbers.FormatSignedCurrency(v, reportCurrency)
+		},
+		"compactCurrency": func(v float64) string {
+			return numbers.FormatCompactCurrency(v, reportCurrency)
+		},
+	}
+}
+
+// contentIDURL 返回引用内嵌图片的 cid: URL（RFC 2392）
+// html/template 默认会过滤 http、https、mailto 以外的协议，因此需要标记为安全的 URL
+func contentIDURL(contentID string) htmltemplate.URL {
+	return htmltemplate.URL("cid:" + url.PathEscape(contentID))
+}
+
+// tableCell 是 HTML 模板中 "cell" 块接收的数据
+type tableCell struct {
+	Column string
+	Coin   CoinPrice
+}
+
+func newTableCell(column string, coin CoinPrice) tableCell {
+	return tableCell{Column: column, Coin: coin}
+}
+
+// changeSign 返回非负数值前需要显示的 "+" 号
+func changeSign(v float64) string {
+	if v < 0 {
+		return ""
+	}
+	return "+"
+}
+
+// changeClass 返回涨跌对应的 CSS 类名
+func changeClass(v float64) string {
+	if v < 0 {
+		return "negative"
+	}
+	return "positive"
+}
+
+// discordMarkdownReplacer 转义 Discord Markdown 中有特殊含义的字符
+var discordMarkdownReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`*`, `\*`,
+	`_`, `\_`,
+	`~`, `\~`,
+	"`", "\\`",
+	`|`, `\|`,
+	`>`, `\>`,
+	`<`, `\<`,
+	`#`, `\#`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`:`, `\:`,
+	`@`, "@\u200b", // 零宽空格，阻止 @everyone / @here
+)
+
+// escapeDiscordMarkdown 转义来自 API 的字符串，使其在 Discord 中按原样显示
+// 控制字符（包括换行）会被替换为空格，避免破坏 Embed 的排版
+func escapeDiscordMarkdown(s string) string {
+	return discordMarkdownReplacer.Replace(stripControlChars(s))
+}
+
+// stripControlChars 将控制字符替换为空格
+func stripControlChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// escapeCoinForDiscord 返回转义了 API 提供的文本字段的币种副本
+func escapeCoinForDiscord(coin CoinPrice) CoinPrice {
+	coin.ID = escapeDiscordMarkdown(coin.ID)
+	coin.Name = escapeDiscordMarkdown(coin.Name)
+	coin.Symbol = escapeDiscordMarkdown(coin.Symbol)
+	return coin
+}
+
+// readTemplateSource 读取模板内容，path 为空时使用内置模板
+func readTemplateSource(defaultName, path string) (string, error) {
+	if path == "" {
+		data, err := defaultTemplateFS.ReadFile(defaultName)
+		if err != nil {
+			return "", fmt.Errorf("failed to read built-in template %s: %w", defaultName, err)
+		}
+		return string(data), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template file: %w", err)
+	}
+	return string(data), nil
+}
+
+// loadLocalizedTemplates 为每个内置语言区域加载一套报表模板
+func loadLocalizedTemplates(paths TemplateConfig) (map[string]*reportTemplates, error) {
+	sets := make(map[string]*reportTemplates, len(locales))
+	for code, locale := range locales {
+		templates, err := loadReportTemplates(locale, paths)
+		if err != nil {
+			return nil, err
+		}
+		sets[code] = templates
+	}
+	return sets, nil
+}
+
+// loadReportTemplates 加载报表模板
+// 自定义模板在内置模板的基础上解析，因此可以只覆盖部分 define 块
+func loadReportTemplates(locale *Locale, paths TemplateConfig) (*reportTemplates, error) {
+	funcs := templateFuncs(locale)
+
+	htmlTmpl := htmltemplate.New("html").Funcs(funcs)
+	for _, path := range templatePaths(paths.EmailHTML) {
+		src, err := readTemplateSource(defaultHTMLTemplate, path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := htmlTmpl.Parse(src); err != nil {
+			return nil, fmt.Errorf("failed to parse html template: %w", err)
+		}
+	}
+
+	textTmpl := texttemplate.New("text").Funcs(funcs)
+	subjectTmpl := texttemplate.New("subject").Funcs(funcs)
+	discordTmpl := texttemplate.New("discord").Funcs(funcs)
+	for _, t := range []struct {
+		tmpl        *texttemplate.Template
+		defaultName string
+		path        string
+	}{
+		{textTmpl, defaultTextTemplate, paths.EmailText},
+		{subjectTmpl, defaultSubjectTemplate, paths.EmailSubject},
+		{discordTmpl, defaultDiscordTemplate, paths.Discord},
+	} {
+		for _, path := range templatePaths(t.path) {
+			src, err := readTemplateSource(t.defaultName, path)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := t.tmpl.Parse(src); err != nil {
+				return nil, fmt.Errorf("failed to parse %s template: %w", t.tmpl.Name(), err)
+			}
+		}
+	}
+
+	for _, block := range discordTemplateBlocks {
+		if discordTmpl.Lookup(block) == nil {
+			return nil, fmt.Errorf("discord template is missing block %q", block)
+		}
+	}
+
+	return &reportTemplates{
+		html:    htmlTmpl,
+		text:    textTmpl,
+		subject: subjectTmpl,
+		discord: discordTmpl,
+	}, nil
+}
+
+// templatePaths 返回需要依次解析的模板路径：先内置模板，再用户模板
+func templatePaths(path string) []string {
+	if path == "" {
+		return []string{""}
+	}
+	return []string{"", path}
+}
+
+// executeHTML 渲染 HTML 模板
+func (t *reportTemplates) executeHTML(data *ReportData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.html.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render html template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// executeText 渲染文本模板中的指定模板块
+func executeText(tmpl *texttemplate.Template, name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
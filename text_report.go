@@ -0,0 +1,165 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// numericColumns 是纯文本表格中需要右对齐的列
+var numericColumns = []string{columnPrice, columnChange, columnChangePerc, columnMarketCap, columnVolume}
+
+// renderTextTable 将报表数据渲染为等宽字体下对齐的纯文本表格
+func renderTextTable(locale *Locale, data *ReportData) string {
+	columns := data.Columns
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = textColumnTitle(locale, column)
+	}
+
+	// 先收集所有行，再计算每列宽度
+	type row struct {
+		cells   []string
+		section string // 非空时表示分组标题行
+	}
+	var rows []row
+	for _, section := range data.Sections {
+		if section.Name != "" {
+			rows = append(rows, row{section: section.Name})
+		}
+		for _, coin := range section.Coins {
+			cells := make([]string, len(columns))
+			for i, column := range columns {
+				cells[i] = textCell(locale, column, coin)
+			}
+			rows = append(rows, row{cells: cells})
+		}
+		if section.Name != "" {
+			rows = append(rows, row{cells: subtotalCells(locale, columns, section.Subtotal)})
+		}
+	}
+
+	widths := make([]int, len(columns))
+	for i, title := range header {
+		widths[i] = displayWidth(title)
+	}
+	for _, r := range rows {
+		for i, cell := range r.cells {
+			if w := displayWidth(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	var b strings.Builder
+	writeTextRow(&b, columns, header, widths)
+	separator := make([]string, len(columns))
+	for i, w := range widths {
+		separator[i] = strings.Repeat("-", w)
+	}
+	writeTextRow(&b, columns, separator, widths)
+
+	for _, r := range rows {
+		if r.section != "" {
+			b.WriteString("\n[" + r.section + "]\n")
+			continue
+		}
+		writeTextRow(&b, columns, r.cells, widths)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writeTextRow 写入一行，数字列右对齐，其余列左对齐
+func writeTextRow(b *strings.Builder, columns, cells []string, widths []int) {
+	var line strings.Builder
+	for i, cell := range cells {
+		if i > 0 {
+			line.WriteString("  ")
+		}
+		padding := strings.Repeat(" ", widths[i]-displayWidth(cell))
+		if containsString(numericColumns, columns[i]) {
+			line.WriteString(padding + cell)
+		} else {
+			line.WriteString(cell + padding)
+		}
+	}
+	b.WriteString(strings.TrimRight(line.String(), " "))
+	b.WriteByte('\n')
+}
+
+// textColumnTitle 返回纯文本表格的列名
+func textColumnTitle(locale *Locale, column string) string {
+	if column == columnPrice {
+		return locale.T("column.price", reportCurrency)
+	}
+	return locale.T("column." + column)
+}
+
+// textCell 返回币种在指定列的纯文本值
+func textCell(locale *Locale, column string, coin CoinPrice) string {
+	numbers := locale.Numbers
+	switch column {
+	case columnName:
+		return stripControlChars(coin.Name)
+	case columnSymbol:
+		return strings.ToUpper(stripControlChars(coin.Symbol))
+	case columnPrice:
+		return numbers.FormatCurrency(coin.CurrentPrice, reportCurrency)
+	case columnChange:
+		return numbers.FormatSignedCurrency(coin.PriceChange24h, reportCurrency)
+	case columnChangePerc:
+		return numbers.FormatSignedPercent(coin.PriceChangePerc24h)
+	case columnMarketCap:
+		return numbers.FormatCompactCurrency(coin.MarketCap, reportCurrency)
+	case columnVolume:
+		return numbers.FormatCompactCurrency(coin.Volume24h, reportCurrency)
+	}
+	return ""
+}
+
+// subtotalCells 返回分组小计行的各列内容
+func subtotalCells(locale *Locale, columns []string, subtotal ReportTotals) []string {
+	numbers := locale.Numbers
+	cells := make([]string, len(columns))
+	for i, column := range columns {
+		switch column {
+		case columnChangePerc:
+			cells[i] = numbers.FormatSignedPercent(subtotal.WeightedChangePerc)
+		case columnMarketCap:
+			cells[i] = numbers.FormatCompactCurrency(subtotal.MarketCap, reportCurrency)
+		case columnVolume:
+			cells[i] = numbers.FormatCompactCurrency(subtotal.Volume24h, reportCurrency)
+		default:
+			if i == 0 {
+				cells[i] = locale.T("report.subtotal")
+			}
+		}
+	}
+	return cells
+}
+
+// displayWidth 返回字符串在等宽字体下的显示宽度，中日韩文字和全角字符占两列
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Mn, r), r == '\u200b':
+			// 组合符号和零宽字符不占宽度
+		case isWideRune(r):
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+// isWideRune 判断字符是否为东亚宽字符
+func isWideRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana) ||
+		(r >= 0x3000 && r <= 0x303F) || // 中日韩标点
+		(r >= 0xFF00 && r <= 0xFF60) || // 全角字符
+		(r >= 0xFFE0 && r <= 0xFFE6) ||
+		(r >= 0x1F300 && r <= 0x1FAFF) // 常见 emoji
+}
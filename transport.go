@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 邮件投递方式
+const (
+	transportSMTP     = "smtp"     // 默认，通过 SMTP 服务器投递
+	transportSES      = "ses"      // AWS SES v2 API
+	transportMailgun  = "mailgun"  // Mailgun messages API
+	transportHTTP     = "http"     // 通用的 JSON over HTTPS 接口
+	transportSendmail = "sendmail" // 交给本机的 sendmail 命令
+	transportMaildir  = "maildir"  // 写入本地的 Maildir 或 .eml 目录，不发送
+)
+
+// httpTransportTimeout 是 HTTP 投递方式的请求超时时间
+const httpTransportTimeout = time.Minute
+
+// EmailTransport 投递已构建的邮件，EmailSender（SMTP）和其他投递方式都实现了该接口
+// 返回的错误应能被 isPermanentSMTPError 区分临时和永久失败
+type EmailTransport interface {
+	Deliver(email *OutgoingEmail) error
+}
+
+// HTTPStatusError 表示 HTTP API 返回了非 2xx 状态码
+type HTTPStatusError struct {
+	Provider   string
+	StatusCode int
+	Body       string // 响应内容（截断），通常包含服务商的错误说明
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s API returned status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// httpTemporaryCodes 是可以重试的 4xx 状态码：认证失败（凭据可能会被修正）、超时和限流
+var httpTemporaryCodes = map[int]bool{
+	http.StatusUnauthorized:    true,
+	http.StatusForbidden:       true,
+	http.StatusRequestTimeout:  true,
+	http.StatusTooManyRequests: true,
+}
+
+// emailTransport 根据配置创建投递方式，使用 SMTP 时返回 nil
+func emailTransport(config *Config, client *http.Client) (EmailTransport, error) {
+	switch config.Email.Transport {
+	case "", transportSMTP:
+		return nil, nil
+	case transportSES:
+		ses := config.Email.SES
+		if ses.Region == "" && ses.Endpoint == "" {
+			return nil, fmt.Errorf("email.ses.region is required")
+		}
+		if ses.AccessKeyID == "" || ses.SecretAccessKey == "" {
+			return nil, fmt.Errorf("email.ses.access_key_id and email.ses.secret_access_key are required")
+		}
+		return NewSESTransport(SESConfig{
+			Region:           ses.Region,
+			AccessKeyID:      ses.AccessKeyID,
+			SecretAccessKey:  ses.SecretAccessKey,
+			SessionToken:     ses.SessionToken,
+			ConfigurationSet: ses.ConfigurationSet,
+			Endpoint:         ses.Endpoint,
+		}, client), nil
+	case transportMailgun:
+		mailgun := config.Email.Mailgun
+		if mailgun.Domain == "" || mailgun.APIKey == "" {
+			return nil, fmt.Errorf("email.mailgun.domain and email.mailgun.api_key are required")
+		}
+		transport, err := NewMailgunTransport(mailgun.Domain, mailgun.APIKey, firstNonEmpty(mailgun.Endpoint, mailgunEndpoints[mailgun.Region]), client)
+		if err != nil {
+			return nil, fmt.Errorf("email.mailgun: %w", err)
+		}
+		return transport, nil
+	case transportHTTP:
+		if !strings.HasPrefix(config.Email.HTTP.URL, "https://") && !strings.HasPrefix(config.Email.HTTP.URL, "http://") {
+			return nil, fmt.Errorf("email.http.url must be an http:// or https:// URL")
+		}
+		return NewHTTPTransport(config.Email.HTTP.URL, config.Email.HTTP.Headers, client), nil
+	case transportSendmail:
+		return NewSendmailTransport(config.Email.Sendmail.Path, config.Email.Sendmail.Args), nil
+	case transportMaildir:
+		dir := config.Email.Maildir.Path
+		if dir == "" {
+			dir = filepath.Join(config.DataDir, "maildir")
+		}
+		transport, err := NewMaildirTransport(dir, config.Email.Maildir.Format)
+		if err != nil {
+			return nil, fmt.Errorf("email.maildir: %w", err)
+		}
+		return transport, nil
+	default:
+		return nil, fmt.Errorf("email.transport must be one of smtp, ses, mailgun, http, sendmail, maildir")
+	}
+}
+
+// HTTPTransport 将邮件以 JSON 格式 POST 到任意 HTTPS 接口，适用于自建网关或其他服务商
+// 请求体为 {"from": ..., "to": [...], "subject": ..., "raw": <base64 编码的完整邮件>}
+type HTTPTransport struct {
+	url     string
+	headers map[string]string // 附加的请求头，如 Authorization
+	client  *http.Client
+}
+
+// NewHTTPTransport 创建通用 HTTP 投递方式
+func NewHTTPTransport(url string, headers map[string]string, client *http.Client) *HTTPTransport {
+	return &HTTPTransport{url: url, headers: headers, client: client}
+}
+
+// httpTransportRequest 是 HTTPTransport 的请求体
+type httpTransportRequest struct {
+	From    string   `json:"from"`
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Raw     string   `json:"raw"`
+}
+
+// Deliver 投递邮件，2xx 状态码表示成功
+func (t *HTTPTransport) Deliver(email *OutgoingEmail) error {
+	body, err := json.Marshal(httpTransportRequest{
+		From:    email.From,
+		To:      email.To,
+		Subject: email.Subject,
+		Raw:     base64.StdEncoding.EncodeToString(email.Message),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	_, err = doTransportRequest(t.client, req, "HTTP")
+	return err
+}
+
+// doTransportRequest 发送请求并返回响应内容，非 2xx 状态码返回 *HTTPStatusError
+func doTransportRequest(client *http.Client, req *http.Request, provider string) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send email via %s API: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s API response: %w", provider, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message := strings.TrimSpace(string(body))
+		if len(message) > 512 {
+			message = message[:512] + "..."
+		}
+		return nil, &HTTPStatusError{Provider: provider, StatusCode: resp.StatusCode, Body: message}
+	}
+	return body, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHTTPTransport 测试通用 HTTP 投递方式的请求头和 JSON 请求体
+func TestHTTPTransport(t *testing.T) {
+	var authorization, contentType string
+	var request httpTransportRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("请求体不是有效的 JSON: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	transport := NewHTTPTransport(server.URL, map[string]string{"Authorization": "Bearer token"}, server.Client())
+	email := &OutgoingEmail{From: "bot@example.com", To: []string{"a@test.com"}, Subject: "每日报表", Message: []byte("Subject: x\r\n\r\nbody\r\n")}
+	if err := transport.Deliver(email); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+
+	if authorization != "Bearer token" || contentType != "application/json" {
+		t.Errorf("请求头不正确: Authorization=%q Content-Type=%q", authorization, contentType)
+	}
+	raw, err := base64.StdEncoding.DecodeString(request.Raw)
+	if err != nil || string(raw) != string(email.Message) {
+		t.Errorf("raw 应该是 base64 编码的完整邮件: %q", request.Raw)
+	}
+	if request.From != "bot@example.com" || request.To[0] != "a@test.com" || request.Subject != "每日报表" {
+		t.Errorf("请求体不正确: %+v", request)
+	}
+}
+
+// TestHTTPTransportStatus 测试非 2xx 状态码返回 HTTPStatusError
+func TestHTTPTransportStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewHTTPTransport(server.URL, nil, server.Client()).Deliver(&OutgoingEmail{Message: []byte("x")})
+	statusErr, ok := err.(*HTTPStatusError)
+	if !ok {
+		t.Fatalf("应该返回 *HTTPStatusError，实际为 %T: %v", err, err)
+	}
+	if statusErr.StatusCode != http.StatusServiceUnavailable || statusErr.Body != "upstream unavailable" {
+		t.Errorf("错误内容不正确: %+v", statusErr)
+	}
+	if isPermanentSMTPError(err) {
+		t.Error("503 错误应该重试")
+	}
+}
+
+// TestEmailSenderTransport 测试配置了投递方式时 EmailSender 不连接 SMTP 服务器
+func TestEmailSenderTransport(t *testing.T) {
+	var request httpTransportRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+	}))
+	defer server.Close()
+
+	sender := NewEmailSender(EmailConfig{
+		From:      "bot@example.com",
+		To:        []string{"a@test.com"},
+		Transport: NewHTTPTransport(server.URL, nil, server.Client()),
+	})
+	if !sender.IsConfigured() {
+		t.Fatal("使用 HTTP 投递方式时不需要 SMTP 配置")
+	}
+	if err := sender.SendReportTo([]string{"Alice <a@test.com>"}, "Report", "<p>x</p>", "x"); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(request.Raw)
+	if request.To[0] != "a@test.com" || !strings.Contains(string(raw), `To: "Alice" <a@test.com>`) {
+		t.Errorf("应该投递 EmailSender 构建的邮件: %+v", request)
+	}
+}
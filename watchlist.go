@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// WatchlistStore 保存各 Discord 服务器通过 /watchlist 命令维护的关注列表
+// 文件内容为服务器 ID 到币种 ID 列表的映射，每次修改后立即写入磁盘
+type WatchlistStore struct {
+	path  string
+	mu    sync.Mutex
+	lists map[string][]string
+}
+
+// NewWatchlistStore 创建关注列表存储并读取已保存的列表，文件不存在时从空列表开始
+func NewWatchlistStore(path string) (*WatchlistStore, error) {
+	store := &WatchlistStore{path: path, lists: map[string][]string{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watchlist file: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.lists); err != nil {
+		return nil, fmt.Errorf("failed to parse watchlist file: %w", err)
+	}
+	return store, nil
+}
+
+// Coins 返回服务器的关注列表，未设置时返回空列表
+func (w *WatchlistStore) Coins(guildID string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.lists[guildID]...)
+}
+
+// Add 将币种加入服务器的关注列表，已存在时返回 false
+// 写入文件失败时撤销修改并返回错误
+func (w *WatchlistStore) Add(guildID, coinID string) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	old := w.lists[guildID]
+	if containsString(old, coinID) {
+		return false, nil
+	}
+	w.lists[guildID] = append(old[:len(old):len(old)], coinID)
+	return true, w.saveOrRestore(guildID, old)
+}
+
+// Remove 将币种移出服务器的关注列表，不存在时返回 false
+// 写入文件失败时撤销修改并返回错误
+func (w *WatchlistStore) Remove(guildID, coinID string) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	old := w.lists[guildID]
+	for i, id := range old {
+		if id == coinID {
+			list := append(old[:i:i], old[i+1:]...)
+			if len(list) == 0 {
+				delete(w.lists, guildID)
+			} else {
+				w.lists[guildID] = list
+			}
+			return true, w.saveOrRestore(guildID, old)
+		}
+	}
+	return false, nil
+}
+
+// saveOrRestore 写入关注列表文件，失败时将服务器的列表恢复为 old，调用方需要持有锁
+func (w *WatchlistStore) saveOrRestore(guildID string, old []string) error {
+	err := w.save()
+	if err != nil {
+		if len(old) == 0 {
+			delete(w.lists, guildID)
+		} else {
+			w.lists[guildID] = old
+		}
+	}
+	return err
+}
+
+// save 写入关注列表文件，调用方需要持有锁
+func (w *WatchlistStore) save() error {
+	data, err := json.MarshalIndent(w.lists, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(w.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write watchlist file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestWatchlistStore 测试按服务器添加、移除关注的币种，并在重新加载后保留
+func TestWatchlistStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchlists.json")
+	store, err := NewWatchlistStore(path)
+	if err != nil {
+		t.Fatalf("创建关注列表失败: %v", err)
+	}
+
+	if added, err := store.Add("guild-1", "bitcoin"); !added || err != nil {
+		t.Errorf("Add = %v, %v，期望成功添加", added, err)
+	}
+	store.Add("guild-1", "solana")
+	store.Add("guild-2", "ethereum")
+	if added, _ := store.Add("guild-1", "bitcoin"); added {
+		t.Error("重复添加应该返回 false")
+	}
+	if removed, _ := store.Remove("guild-2", "bitcoin"); removed {
+		t.Error("移除不存在的币种应该返回 false")
+	}
+	if removed, err := store.Remove("guild-2", "ethereum"); !removed || err != nil {
+		t.Errorf("Remove = %v, %v，期望成功移除", removed, err)
+	}
+
+	reloaded, err := NewWatchlistStore(path)
+	if err != nil {
+		t.Fatalf("重新加载关注列表失败: %v", err)
+	}
+	if got := reloaded.Coins("guild-1"); !reflect.DeepEqual(got, []string{"bitcoin", "solana"}) {
+		t.Errorf("guild-1 的关注列表错误: %v", got)
+	}
+	if got := reloaded.Coins("guild-2"); len(got) != 0 {
+		t.Errorf("guild-2 的关注列表应该为空: %v", got)
+	}
+}